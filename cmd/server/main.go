@@ -7,6 +7,7 @@ import (
 	"syscall"
 
 	"github.com/b0nbon1/stratal/internal/api"
+	"github.com/b0nbon1/stratal/internal/artifacts"
 	"github.com/b0nbon1/stratal/internal/config"
 	"github.com/b0nbon1/stratal/internal/queue"
 	"github.com/b0nbon1/stratal/internal/security"
@@ -30,6 +31,8 @@ func main() {
 	q := queue.NewRedisQueue(cfg, "job_runs", "workers", 3)
 
 	hs := api.NewHTTPServer(cfg.Server.Address(), store.(*db.SQLStore), q, secretManager)
+	hs.SetArtifactStore(artifacts.NewLocalStore(cfg.Artifacts.Dir))
+	hs.SetOutputStore(artifacts.NewLocalStore(cfg.Outputs.Dir))
 
 	if err := hs.Start(); err != nil {
 		panic(err)
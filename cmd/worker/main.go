@@ -7,8 +7,12 @@ import (
 	"os/signal"
 	"syscall"
 
+	"github.com/b0nbon1/stratal/internal/artifacts"
 	"github.com/b0nbon1/stratal/internal/config"
+	"github.com/b0nbon1/stratal/internal/processor"
 	"github.com/b0nbon1/stratal/internal/queue"
+	"github.com/b0nbon1/stratal/internal/runner"
+	"github.com/b0nbon1/stratal/internal/runner/tasks"
 	"github.com/b0nbon1/stratal/internal/scheduler"
 	"github.com/b0nbon1/stratal/internal/security"
 	psql "github.com/b0nbon1/stratal/internal/storage/db"
@@ -17,6 +21,9 @@ import (
 )
 
 func main() {
+	// sandboxed scripts start as a copy of the worker that sets up the sandbox
+	runner.SandboxInit()
+
 	ctx := context.Background()
 
 	// Load configuration
@@ -38,7 +45,29 @@ func main() {
 
 	go scheduler.StartScheduler(q, store.(*db.SQLStore), ctx)
 
-	go worker.StartWorker(ctx, q, store.(*db.SQLStore), secretManager)
+	sandbox, err := scriptSandbox(cfg.Sandbox)
+	if err != nil {
+		panic(fmt.Sprintf("Invalid script sandbox: %v", err))
+	}
+	if sandbox.ScrubEnv && !sandbox.Isolated() {
+		fmt.Println("Warning: scripts run as the worker user and can read the worker environment from /proc, set SANDBOX_UID or use the strict profile to hide it")
+	}
+
+	go worker.StartWorker(ctx, q, processor.Dependencies{
+		Store:         store.(*db.SQLStore),
+		SecretManager: secretManager,
+		Artifacts:     artifacts.NewLocalStore(cfg.Artifacts.Dir),
+		Outputs:       artifacts.NewLocalStore(cfg.Outputs.Dir),
+		OutputLimits: runner.OutputLimits{
+			MaxOutputSize: int64(cfg.Outputs.MaxInlineSize),
+			MaxLogSize:    int64(cfg.Outputs.MaxLogSize),
+			MaxEnvSize:    int64(cfg.Outputs.MaxEnvSize),
+			MaxEnvTotal:   int64(cfg.Outputs.MaxEnvTotal),
+		},
+		Sandbox:       sandbox,
+		Sensors:       tasks.SensorOptions{SQLConnections: cfg.Sensors.SQLConnections},
+		ScriptTimeout: cfg.Scripts.DefaultTimeout,
+	})
 	fmt.Println("Worker started successfully")
 
 	quitChannel := make(chan os.Signal, 1)
@@ -47,3 +76,27 @@ func main() {
 
 	fmt.Println("Stopped by signal, exiting gracefully...")
 }
+
+// scriptSandbox builds the sandbox of scripts from the profile and its overrides
+func scriptSandbox(cfg config.SandboxConfig) (runner.Sandbox, error) {
+	sandbox, err := runner.SandboxProfile(cfg.Profile)
+	if err != nil {
+		return sandbox, err
+	}
+	switch cfg.Network {
+	case "":
+	case "allow":
+		sandbox.DenyNetwork = false
+	case "deny":
+		sandbox.DenyNetwork = true
+	default:
+		return sandbox, fmt.Errorf("SANDBOX_NETWORK must be allow or deny, got '%s'", cfg.Network)
+	}
+	sandbox.UID = cfg.UID
+	sandbox.GID = cfg.GID
+	sandbox.CgroupParent = cfg.CgroupParent
+	if sandbox.ScrubEnv {
+		sandbox.AllowEnv = append(append([]string{}, sandbox.AllowEnv...), cfg.AllowEnv...)
+	}
+	return sandbox, sandbox.Validate()
+}
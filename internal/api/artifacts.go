@@ -0,0 +1,122 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/b0nbon1/stratal/internal/artifacts"
+	db "github.com/b0nbon1/stratal/internal/storage/db/sqlc"
+	"github.com/b0nbon1/stratal/pkg/router"
+	"github.com/b0nbon1/stratal/pkg/utils"
+)
+
+// ArtifactResponse describes a file uploaded by a task run
+type ArtifactResponse struct {
+	ID        string `json:"id"`
+	TaskRunID string `json:"task_run_id"`
+	TaskName  string `json:"task_name"`
+	Name      string `json:"name"`
+	SizeBytes int64  `json:"size_bytes"`
+	Sha256    string `json:"sha256"`
+	CreatedAt string `json:"created_at"`
+}
+
+func artifactResponse(artifact db.TaskArtifact) ArtifactResponse {
+	return ArtifactResponse{
+		ID:        artifact.ID.String(),
+		TaskRunID: artifact.TaskRunID.String(),
+		TaskName:  artifact.TaskName,
+		Name:      artifact.Name,
+		SizeBytes: artifact.SizeBytes,
+		Sha256:    artifact.Sha256,
+		CreatedAt: artifact.CreatedAt.Time.Format(time.RFC3339),
+	}
+}
+
+// ListJobRunArtifacts lists the artifacts uploaded by the tasks of a job run, optionally of
+// a single task (?task_name=)
+func (hs *HTTPServer) ListJobRunArtifacts(w http.ResponseWriter, r *http.Request) {
+	jobRunID := router.GetParam(r, "id")
+	if jobRunID == "" {
+		respondError(w, 400, "Job run ID is required")
+		return
+	}
+
+	jobRunUUID, err := utils.ParseUUID(jobRunID)
+	if err != nil {
+		respondError(w, 400, "Invalid job run UUID", err.Error())
+		return
+	}
+
+	params := db.ListJobRunArtifactsParams{JobRunID: jobRunUUID}
+	if taskName := r.URL.Query().Get("task_name"); taskName != "" {
+		params.TaskName = utils.ParseText(taskName)
+	}
+
+	stored, err := hs.store.ListJobRunArtifacts(hs.ctx, params)
+	if err != nil {
+		respondError(w, 500, "Failed to fetch artifacts", err.Error())
+		return
+	}
+
+	response := make([]ArtifactResponse, 0, len(stored))
+	for _, artifact := range stored {
+		response = append(response, artifactResponse(artifact))
+	}
+	respondJSON(w, 200, map[string]interface{}{
+		"job_run_id": jobRunID,
+		"artifacts":  response,
+	})
+}
+
+// DownloadJobRunArtifact streams the content of an artifact of a job run
+func (hs *HTTPServer) DownloadJobRunArtifact(w http.ResponseWriter, r *http.Request) {
+	jobRunUUID, err := utils.ParseUUID(router.GetParam(r, "id"))
+	if err != nil {
+		respondError(w, 400, "Invalid job run UUID", err.Error())
+		return
+	}
+	artifactUUID, err := utils.ParseUUID(router.GetParam(r, "artifact_id"))
+	if err != nil {
+		respondError(w, 400, "Invalid artifact UUID", err.Error())
+		return
+	}
+
+	artifact, err := hs.store.GetTaskArtifact(hs.ctx, db.GetTaskArtifactParams{
+		ID:       artifactUUID,
+		JobRunID: jobRunUUID,
+	})
+	if err != nil {
+		if utils.ContainsSubstring(err.Error(), "no rows") {
+			respondError(w, 404, "Artifact not found")
+		} else {
+			respondError(w, 500, "Failed to fetch artifact", err.Error())
+		}
+		return
+	}
+
+	content, err := hs.artifactStore.Open(r.Context(), artifact.Key)
+	if err != nil {
+		if errors.Is(err, artifacts.ErrNotFound) {
+			respondError(w, 404, "Artifact content not found", err.Error())
+		} else {
+			respondError(w, 500, "Failed to open artifact", err.Error())
+		}
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", path.Base(artifact.Name)))
+	w.Header().Set("Content-Length", strconv.FormatInt(artifact.SizeBytes, 10))
+	w.Header().Set("X-Artifact-Sha256", artifact.Sha256)
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, content); err != nil {
+		fmt.Printf("Failed to stream artifact %s: %v\n", artifact.Key, err)
+	}
+}
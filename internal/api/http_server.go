@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"sync"
 
+	"github.com/b0nbon1/stratal/internal/artifacts"
 	"github.com/b0nbon1/stratal/internal/logger"
 	"github.com/b0nbon1/stratal/internal/queue"
 	"github.com/b0nbon1/stratal/internal/security"
@@ -25,6 +26,8 @@ type HTTPServer struct {
 	queue         queue.TaskQueue
 	secretManager *security.SecretManager
 	logSystem     *logger.Logger
+	artifactStore artifacts.Store
+	outputStore   artifacts.Store
 }
 
 func NewHTTPServer(addr string, store *db.SQLStore, queue queue.TaskQueue, secretManager *security.SecretManager) *HTTPServer {
@@ -38,6 +41,8 @@ func NewHTTPServer(addr string, store *db.SQLStore, queue queue.TaskQueue, secre
 		queue:         queue,
 		secretManager: secretManager,
 		logSystem:     logger.NewLogger(store, "internal/storage/files/logs"),
+		artifactStore: artifacts.NewLocalStore("internal/storage/files/artifacts"),
+		outputStore:   artifacts.NewLocalStore("internal/storage/files/outputs"),
 	}
 
 	return s
@@ -47,6 +52,16 @@ func (httpServer *HTTPServer) GetLogSystem() *logger.Logger {
 	return httpServer.logSystem
 }
 
+// SetArtifactStore sets the store job run artifacts are downloaded from
+func (httpServer *HTTPServer) SetArtifactStore(store artifacts.Store) {
+	httpServer.artifactStore = store
+}
+
+// SetOutputStore sets the store complete task run outputs beyond the inline limit are read from
+func (httpServer *HTTPServer) SetOutputStore(store artifacts.Store) {
+	httpServer.outputStore = store
+}
+
 func (httpServer *HTTPServer) Start() error {
 	httpServer.mtx.Lock()
 	defer httpServer.mtx.Unlock()
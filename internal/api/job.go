@@ -1,10 +1,13 @@
 package api
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 
+	"github.com/b0nbon1/stratal/internal/processor"
 	"github.com/b0nbon1/stratal/internal/storage/db/dto"
 	db "github.com/b0nbon1/stratal/internal/storage/db/sqlc"
 	"github.com/b0nbon1/stratal/pkg/router"
@@ -24,6 +27,7 @@ type JobBodyParams struct {
 	Description    string        `json:"description"`
 	Source         string        `json:"source"`
 	RawPayload     []byte        `json:"raw_payload"`
+	Config         dto.JobConfig `json:"config"`
 	Tasks          []TaskJobBody `json:"tasks"`
 	RunImmediately bool          `json:"run_immediately"`
 }
@@ -47,6 +51,22 @@ func (hs *HTTPServer) CreateJob(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := processor.ValidateInputSpecs(reqBodyJob.Config.Inputs); err != nil {
+		respondJSON(w, 400, map[string]interface{}{
+			"error":   "Invalid job inputs",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := processor.ValidateConcurrency(reqBodyJob.Config.Concurrency); err != nil {
+		respondJSON(w, 400, map[string]interface{}{
+			"error":   "Invalid job concurrency",
+			"details": err.Error(),
+		})
+		return
+	}
+
 	if reqBodyJob.Source == "" {
 		reqBodyJob.Source = "api" // Default source
 	}
@@ -57,6 +77,7 @@ func (hs *HTTPServer) CreateJob(w http.ResponseWriter, r *http.Request) {
 		Description: pgtype.Text{String: reqBodyJob.Description, Valid: true},
 		Source:      reqBodyJob.Source,
 		RawPayload:  []byte(reqBodyJob.RawPayload),
+		Config:      reqBodyJob.Config,
 	}
 
 	// create Tasks that will be linked to the Job
@@ -87,7 +108,14 @@ func (hs *HTTPServer) CreateJob(w http.ResponseWriter, r *http.Request) {
 
 	// If requested, create and queue a job run immediately
 	if reqBodyJob.RunImmediately && data.Job != nil {
-		jobRunData, err := hs.store.CreateJobRunTx(hs.ctx, data.Job.ID, "api")
+		var jobRunData db.JobRunResult
+		inputs, err := processor.ResolveRunInputs(reqBodyJob.Config.Inputs, nil)
+		if err == nil {
+			var metadata []byte
+			if metadata, err = runMetadata(inputs); err == nil {
+				jobRunData, err = hs.store.CreateJobRunTx(hs.ctx, data.Job.ID, "api", metadata)
+			}
+		}
 		if err != nil {
 			response["warning"] = fmt.Sprintf("Job created but failed to create job run: %v", err)
 		} else {
@@ -139,6 +167,57 @@ func (hs *HTTPServer) GetJob(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, 200, job)
 }
 
+// PlanJobBody holds the inputs a job is planned with
+type PlanJobBody struct {
+	Inputs map[string]interface{} `json:"inputs"`
+}
+
+// PlanJob reports how a run of the job would execute and every problem that would make
+// it fail, without executing anything. Secret values are masked.
+func (hs *HTTPServer) PlanJob(w http.ResponseWriter, r *http.Request) {
+	jobID := router.GetParam(r, "id")
+	if jobID == "" {
+		respondError(w, 400, "Job ID is required in URL path")
+		return
+	}
+
+	jobUUID, err := utils.ParseUUID(jobID)
+	if err != nil {
+		respondError(w, 400, "Invalid job UUID", err.Error())
+		return
+	}
+
+	// the body is optional, a job without required inputs can be planned without one
+	var reqBody PlanJobBody
+	if err := parseJSON(r, &reqBody); err != nil && !errors.Is(err, io.EOF) {
+		respondError(w, 400, "Invalid request body", err.Error())
+		return
+	}
+
+	hs.respondPlan(w, jobUUID, reqBody.Inputs)
+}
+
+// respondPlan computes the plan of a job run and writes it as the response
+func (hs *HTTPServer) respondPlan(w http.ResponseWriter, jobID pgtype.UUID, inputs map[string]interface{}) {
+	job, err := hs.store.GetJobWithTasks(hs.ctx, jobID)
+	if err != nil {
+		if utils.ContainsSubstring(err.Error(), "no rows") {
+			respondError(w, 404, "Job not found")
+		} else {
+			respondError(w, 500, "Failed to fetch job", err.Error())
+		}
+		return
+	}
+
+	plan, err := processor.PlanJob(hs.ctx, hs.store, hs.secretManager, job, inputs)
+	if err != nil {
+		respondError(w, 500, "Failed to plan job", err.Error())
+		return
+	}
+
+	respondJSON(w, 200, plan)
+}
+
 func (hs *HTTPServer) ListJobs(w http.ResponseWriter, r *http.Request) {
 	if jobID := r.URL.Query().Get("id"); jobID != "" {
 		hs.GetJob(w, r)
@@ -1,17 +1,23 @@
 package api
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
 
+	"github.com/b0nbon1/stratal/internal/processor"
 	db "github.com/b0nbon1/stratal/internal/storage/db/sqlc"
+	"github.com/b0nbon1/stratal/pkg/router"
 	"github.com/b0nbon1/stratal/pkg/utils"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type JobRunBody struct {
-	JobID       string `json:"job_id"`
-	TriggeredBy string `json:"triggered_by"`
+	JobID       string                 `json:"job_id"`
+	TriggeredBy string                 `json:"triggered_by"`
+	Inputs      map[string]interface{} `json:"inputs"`
+	DryRun      bool                   `json:"dry_run"` // only plan the run, nothing is created or executed
 }
 
 func (hs *HTTPServer) CreateJobRun(w http.ResponseWriter, r *http.Request) {
@@ -28,8 +34,35 @@ func (hs *HTTPServer) CreateJobRun(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if reqBodyJobRun.DryRun {
+		hs.respondPlan(w, parsedJobID, reqBodyJobRun.Inputs)
+		return
+	}
+
+	job, err := hs.store.GetJob(hs.ctx, parsedJobID)
+	if err != nil {
+		if utils.ContainsSubstring(err.Error(), "no rows") {
+			respondError(w, 404, "Job not found")
+		} else {
+			respondError(w, 500, "Failed to fetch job", err.Error())
+		}
+		return
+	}
+
+	// inputs are validated against the job's declarations before the run is created
+	inputs, err := processor.ResolveRunInputs(job.Config.Inputs, reqBodyJobRun.Inputs)
+	if err != nil {
+		respondError(w, 400, "Invalid job run inputs", err.Error())
+		return
+	}
+	metadata, err := runMetadata(inputs)
+	if err != nil {
+		respondError(w, 500, "Failed to encode job run inputs", err.Error())
+		return
+	}
+
 	// execute the transaction to create the jobs and also tasks, if fails rollback everthing
-	data, err := hs.store.CreateJobRunTx(hs.ctx, parsedJobID, reqBodyJobRun.TriggeredBy)
+	data, err := hs.store.CreateJobRunTx(hs.ctx, parsedJobID, reqBodyJobRun.TriggeredBy, metadata)
 	if err != nil {
 		respondJSON(w, 500, fmt.Errorf("job Run failed, %w", err))
 		return
@@ -58,6 +91,14 @@ func (hs *HTTPServer) CreateJobRun(w http.ResponseWriter, r *http.Request) {
 
 }
 
+// runMetadata builds the job run metadata holding the inputs of the run
+func runMetadata(inputs map[string]interface{}) ([]byte, error) {
+	if len(inputs) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(map[string]interface{}{"inputs": inputs})
+}
+
 func (hs *HTTPServer) GetJobRun(w http.ResponseWriter, r *http.Request) {
 	jobRunID := r.URL.Query().Get("id")
 	if jobRunID == "" {
@@ -83,3 +124,89 @@ func (hs *HTTPServer) GetJobRun(w http.ResponseWriter, r *http.Request) {
 
 	respondJSON(w, 200, jobRun)
 }
+
+// maxJobRunTreeDepth bounds how deep GetJobRunTree follows sub-job runs
+const maxJobRunTreeDepth = 10
+
+// JobRunTreeNode is a job run with the runs started by its sub-job tasks
+type JobRunTreeNode struct {
+	ID              string           `json:"id"`
+	JobID           string           `json:"job_id"`
+	Status          string           `json:"status"`
+	ParentTaskRunID *string          `json:"parent_task_run_id,omitempty"`
+	StartedAt       pgtype.Timestamp `json:"started_at"`
+	FinishedAt      pgtype.Timestamp `json:"finished_at"`
+	ErrorMessage    string           `json:"error_message,omitempty"`
+	Children        []JobRunTreeNode `json:"children"`
+}
+
+// GetJobRunTree returns a job run together with all child runs started by sub-job tasks
+func (hs *HTTPServer) GetJobRunTree(w http.ResponseWriter, r *http.Request) {
+	jobRunID := router.GetParam(r, "id")
+	if jobRunID == "" {
+		respondError(w, 400, "Job run ID is required")
+		return
+	}
+
+	jobRunUUID, err := utils.ParseUUID(jobRunID)
+	if err != nil {
+		respondError(w, 400, "Invalid job run UUID", err.Error())
+		return
+	}
+
+	jobRun, err := hs.store.GetJobRun(hs.ctx, jobRunUUID)
+	if err != nil {
+		if utils.ContainsSubstring(err.Error(), "no rows") {
+			respondError(w, 404, "Job run not found")
+		} else {
+			respondError(w, 500, "Failed to fetch job run", err.Error())
+		}
+		return
+	}
+
+	root := JobRunTreeNode{
+		ID:           jobRun.ID.String(),
+		JobID:        jobRun.JobID.String(),
+		Status:       jobRun.Status.String,
+		StartedAt:    jobRun.StartedAt,
+		FinishedAt:   jobRun.FinishedAt,
+		ErrorMessage: jobRun.ErrorMessage.String,
+	}
+	root.Children, err = hs.childJobRunNodes(jobRun.ID, 1)
+	if err != nil {
+		respondError(w, 500, "Failed to fetch child job runs", err.Error())
+		return
+	}
+
+	respondJSON(w, 200, root)
+}
+
+func (hs *HTTPServer) childJobRunNodes(jobRunID pgtype.UUID, depth int) ([]JobRunTreeNode, error) {
+	nodes := []JobRunTreeNode{}
+	if depth > maxJobRunTreeDepth {
+		return nodes, nil
+	}
+
+	children, err := hs.store.ListChildJobRuns(hs.ctx, jobRunID)
+	if err != nil {
+		return nil, err
+	}
+	for _, child := range children {
+		parentTaskRunID := child.ParentTaskRunID.String()
+		node := JobRunTreeNode{
+			ID:              child.ID.String(),
+			JobID:           child.JobID.String(),
+			Status:          child.Status.String,
+			ParentTaskRunID: &parentTaskRunID,
+			StartedAt:       child.StartedAt,
+			FinishedAt:      child.FinishedAt,
+			ErrorMessage:    child.ErrorMessage.String,
+		}
+		node.Children, err = hs.childJobRunNodes(child.ID, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
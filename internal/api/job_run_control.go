@@ -2,8 +2,10 @@ package api
 
 import (
 	"fmt"
+	"log"
 	"net/http"
 
+	db "github.com/b0nbon1/stratal/internal/storage/db/sqlc"
 	"github.com/b0nbon1/stratal/pkg/router"
 	"github.com/b0nbon1/stratal/pkg/utils"
 )
@@ -96,20 +98,14 @@ func (hs *HTTPServer) ResumeJobRun(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// If job was previously running, we need to re-queue it
-	var newStatus string
-	if jobRun.StartedAt.Valid {
-		// Job was running when paused, queue it to continue
-		err = hs.queue.Enqueue(jobRunID)
-		if err != nil {
-			respondError(w, 500, "Failed to re-queue resumed job run", err.Error())
-			return
-		}
-		newStatus = "queued"
-	} else {
-		// Job was queued when paused, just change status back to queued
-		newStatus = "queued"
+	// Re-queue the job run, the worker skips tasks that already completed.
+	// Its previous queue message was consumed while it was paused.
+	err = hs.queue.Enqueue(jobRunID)
+	if err != nil {
+		respondError(w, 500, "Failed to re-queue resumed job run", err.Error())
+		return
 	}
+	newStatus := "queued"
 
 	respondJSON(w, 200, map[string]interface{}{
 		"message":         "Job run resumed successfully",
@@ -120,6 +116,70 @@ func (hs *HTTPServer) ResumeJobRun(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// CancelJobRun cancels an unfinished job run. The worker running it is signalled to kill
+// its in-flight tasks, task runs that did not finish are marked cancelled.
+func (hs *HTTPServer) CancelJobRun(w http.ResponseWriter, r *http.Request) {
+	jobRunID := router.GetParam(r, "id")
+
+	if jobRunID == "" {
+		respondError(w, 400, "Job run ID is required")
+		return
+	}
+
+	jobRunUUID, err := utils.ParseUUID(jobRunID)
+	if err != nil {
+		respondError(w, 400, "Invalid job run UUID", err.Error())
+		return
+	}
+
+	jobRun, err := hs.store.GetJobRunWithPauseInfo(hs.ctx, jobRunUUID)
+	if err != nil {
+		if utils.ContainsSubstring(err.Error(), "no rows") {
+			respondError(w, 404, "Job run not found")
+		} else {
+			respondError(w, 500, "Failed to fetch job run", err.Error())
+		}
+		return
+	}
+
+	// Check if job run is in a cancellable state
+	status := jobRun.Status.String
+	switch status {
+	case "pending", "queued", "running", "paused", "waiting_approval", "up_for_reschedule":
+	default:
+		respondError(w, 400, fmt.Sprintf("Cannot cancel job run in '%s' status. Only unfinished jobs can be cancelled.", status))
+		return
+	}
+
+	err = hs.store.CancelJobRun(hs.ctx, db.CancelJobRunParams{
+		ID:           jobRunUUID,
+		ErrorMessage: utils.ParseText("Job run cancelled by user"),
+	})
+	if err != nil {
+		respondError(w, 500, "Failed to cancel job run", err.Error())
+		return
+	}
+	if err := hs.store.CancelUnfinishedTaskRuns(hs.ctx, jobRunUUID); err != nil {
+		respondError(w, 500, "Failed to cancel task runs", err.Error())
+		return
+	}
+
+	// A worker still executing tasks of the run stops them, whatever status the run was in:
+	// a paused or parked run can have tasks of its current level in flight. Workers ignore
+	// runs they do not execute, queued messages are skipped once dequeued.
+	if err := hs.queue.PublishCancel(jobRunID); err != nil {
+		log.Printf("unable to signal cancellation of job run %s: %v", jobRunID, err)
+	}
+
+	respondJSON(w, 200, map[string]interface{}{
+		"message":         "Job run cancelled successfully",
+		"job_run_id":      jobRunID,
+		"job_name":        jobRun.JobName,
+		"previous_status": status,
+		"new_status":      "cancelled",
+	})
+}
+
 // GetPausedJobRuns returns all paused job runs
 func (hs *HTTPServer) GetPausedJobRuns(w http.ResponseWriter, r *http.Request) {
 	pausedJobRuns, err := hs.store.GetPausedJobRuns(hs.ctx)
@@ -32,16 +32,33 @@ func (hs *HTTPServer) registerRoutes() *router.Router {
 	v1.Post("/jobs", hs.CreateJob)
 	v1.Get("/jobs", hs.ListJobs)
 	v1.Get("/jobs/:id", hs.GetJob)
+	v1.Post("/jobs/:id/plan", hs.PlanJob)
 
 	v1.Post("/job-runs", hs.CreateJobRun)
 	v1.Get("/job-runs", hs.GetJobRun)
 	v1.Get("/job-runs/:id", hs.GetJobRun)
+	v1.Get("/job-runs/:id/tree", hs.GetJobRunTree)
+	v1.Get("/job-runs/:id/artifacts", hs.ListJobRunArtifacts)
+	v1.Get("/job-runs/:id/artifacts/:artifact_id", hs.DownloadJobRunArtifact)
 
 	// Job run control endpoints
 	v1.Post("/job-runs/:id/pause", hs.PauseJobRun)
 	v1.Post("/job-runs/:id/resume", hs.ResumeJobRun)
+	v1.Post("/job-runs/:id/cancel", hs.CancelJobRun)
 	v1.Get("/job-runs/paused", hs.GetPausedJobRuns)
 
+	v1.Get("/task-runs/:id/output", hs.GetTaskRunOutput)
+
+	// Approval task endpoints
+	v1.Post("/task-runs/:id/approve", hs.ApproveTaskRun)
+	v1.Post("/task-runs/:id/reject", hs.RejectTaskRun)
+
+	// Task cache endpoints
+	v1.Get("/cache", hs.ListTaskCache)
+	v1.Delete("/cache", hs.InvalidateTaskCache)
+	v1.Get("/cache/:key", hs.GetTaskCacheEntry)
+	v1.Delete("/cache/:key", hs.InvalidateTaskCacheEntry)
+
 	v1.Post("/secrets", hs.CreateSecret)
 	v1.Get("/secrets", hs.ListSecrets)
 
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	db "github.com/b0nbon1/stratal/internal/storage/db/sqlc"
+	"github.com/b0nbon1/stratal/pkg/router"
+	"github.com/b0nbon1/stratal/pkg/utils"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// TaskCacheEntryResponse is a cached task output
+type TaskCacheEntryResponse struct {
+	Key       string `json:"key"`
+	JobID     string `json:"job_id"`
+	TaskName  string `json:"task_name"`
+	Output    string `json:"output"`
+	TaskRunID string `json:"task_run_id,omitempty"`
+	Hits      int32  `json:"hits"`
+	CreatedAt string `json:"created_at"`
+	LastHitAt string `json:"last_hit_at,omitempty"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+func taskCacheEntryResponse(entry db.TaskCache) TaskCacheEntryResponse {
+	response := TaskCacheEntryResponse{
+		Key:       entry.Key,
+		JobID:     entry.JobID.String(),
+		TaskName:  entry.TaskName,
+		Output:    entry.Output,
+		Hits:      entry.Hits,
+		CreatedAt: entry.CreatedAt.Time.Format(time.RFC3339),
+		ExpiresAt: entry.ExpiresAt.Time.Format(time.RFC3339),
+	}
+	if entry.TaskRunID.Valid {
+		response.TaskRunID = entry.TaskRunID.String()
+	}
+	if entry.LastHitAt.Valid {
+		response.LastHitAt = entry.LastHitAt.Time.Format(time.RFC3339)
+	}
+	return response
+}
+
+// ListTaskCache lists cached task outputs, optionally of a single job (?job_id=) and task (?task_name=)
+func (hs *HTTPServer) ListTaskCache(w http.ResponseWriter, r *http.Request) {
+	var params db.ListTaskCacheEntriesParams
+	if jobID := r.URL.Query().Get("job_id"); jobID != "" {
+		jobUUID, err := utils.ParseUUID(jobID)
+		if err != nil {
+			respondError(w, 400, "Invalid job UUID", err.Error())
+			return
+		}
+		params.JobID = jobUUID
+	}
+	if taskName := r.URL.Query().Get("task_name"); taskName != "" {
+		params.TaskName = utils.ParseText(taskName)
+	}
+
+	entries, err := hs.store.ListTaskCacheEntries(hs.ctx, params)
+	if err != nil {
+		respondError(w, 500, "Failed to fetch cache entries", err.Error())
+		return
+	}
+
+	response := make([]TaskCacheEntryResponse, 0, len(entries))
+	for _, entry := range entries {
+		response = append(response, taskCacheEntryResponse(entry))
+	}
+	respondJSON(w, 200, map[string]interface{}{
+		"entries": response,
+	})
+}
+
+// GetTaskCacheEntry returns a single cached task output by its key
+func (hs *HTTPServer) GetTaskCacheEntry(w http.ResponseWriter, r *http.Request) {
+	key := router.GetParam(r, "key")
+	if key == "" {
+		respondError(w, 400, "Cache key is required in URL path")
+		return
+	}
+
+	entry, err := hs.store.GetTaskCacheEntry(hs.ctx, key)
+	if err != nil {
+		if utils.ContainsSubstring(err.Error(), "no rows") {
+			respondError(w, 404, "Cache entry not found")
+		} else {
+			respondError(w, 500, "Failed to fetch cache entry", err.Error())
+		}
+		return
+	}
+	respondJSON(w, 200, taskCacheEntryResponse(entry))
+}
+
+// InvalidateTaskCacheEntry deletes a single cached task output, the task runs again next time
+func (hs *HTTPServer) InvalidateTaskCacheEntry(w http.ResponseWriter, r *http.Request) {
+	key := router.GetParam(r, "key")
+	if key == "" {
+		respondError(w, 400, "Cache key is required in URL path")
+		return
+	}
+
+	deleted, err := hs.store.DeleteTaskCacheEntry(hs.ctx, key)
+	if err != nil {
+		respondError(w, 500, "Failed to invalidate cache entry", err.Error())
+		return
+	}
+	if deleted == 0 {
+		respondError(w, 404, "Cache entry not found")
+		return
+	}
+	respondJSON(w, 200, map[string]interface{}{
+		"message": "Cache entry invalidated",
+		"deleted": deleted,
+	})
+}
+
+// InvalidateTaskCache deletes the cached outputs of a job (?job_id=), or of one of its tasks
+// when ?task_name= is given as well
+func (hs *HTTPServer) InvalidateTaskCache(w http.ResponseWriter, r *http.Request) {
+	jobID := r.URL.Query().Get("job_id")
+	if jobID == "" {
+		respondError(w, 400, "job_id query parameter is required")
+		return
+	}
+	jobUUID, err := utils.ParseUUID(jobID)
+	if err != nil {
+		respondError(w, 400, "Invalid job UUID", err.Error())
+		return
+	}
+
+	var taskName pgtype.Text
+	if name := r.URL.Query().Get("task_name"); name != "" {
+		taskName = utils.ParseText(name)
+	}
+
+	deleted, err := hs.store.DeleteTaskCacheEntries(hs.ctx, db.DeleteTaskCacheEntriesParams{
+		JobID:    jobUUID,
+		TaskName: taskName,
+	})
+	if err != nil {
+		respondError(w, 500, "Failed to invalidate cache entries", err.Error())
+		return
+	}
+	respondJSON(w, 200, map[string]interface{}{
+		"message": "Cache entries invalidated",
+		"deleted": deleted,
+	})
+}
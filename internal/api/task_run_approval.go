@@ -0,0 +1,112 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/b0nbon1/stratal/internal/processor"
+	"github.com/b0nbon1/stratal/pkg/router"
+	"github.com/b0nbon1/stratal/pkg/utils"
+)
+
+// ApprovalBody identifies who decides an approval task run
+type ApprovalBody struct {
+	Approver string `json:"approver"`
+	Comment  string `json:"comment"`
+}
+
+// ApproveTaskRun approves a task run waiting for approval and continues its job run
+func (hs *HTTPServer) ApproveTaskRun(w http.ResponseWriter, r *http.Request) {
+	hs.decideApproval(w, r, true)
+}
+
+// RejectTaskRun rejects a task run waiting for approval, the approval task fails
+func (hs *HTTPServer) RejectTaskRun(w http.ResponseWriter, r *http.Request) {
+	hs.decideApproval(w, r, false)
+}
+
+func (hs *HTTPServer) decideApproval(w http.ResponseWriter, r *http.Request, approved bool) {
+	taskRunID := router.GetParam(r, "id")
+	if taskRunID == "" {
+		respondError(w, 400, "Task run ID is required")
+		return
+	}
+
+	taskRunUUID, err := utils.ParseUUID(taskRunID)
+	if err != nil {
+		respondError(w, 400, "Invalid task run UUID", err.Error())
+		return
+	}
+
+	var reqBody ApprovalBody
+	if err := parseJSON(r, &reqBody); err != nil && !errors.Is(err, io.EOF) {
+		respondError(w, 400, "Invalid request body", err.Error())
+		return
+	}
+	reqBody.Approver = strings.TrimSpace(reqBody.Approver)
+	if reqBody.Approver == "" {
+		respondError(w, 400, "Approver is required")
+		return
+	}
+
+	approval, err := hs.store.GetTaskRunApproval(hs.ctx, taskRunUUID)
+	if err != nil {
+		if utils.ContainsSubstring(err.Error(), "no rows") {
+			respondError(w, 404, "Task run not found")
+		} else {
+			respondError(w, 500, "Failed to fetch task run", err.Error())
+		}
+		return
+	}
+
+	if approval.Status.String != "waiting_approval" {
+		respondError(w, 409, fmt.Sprintf("Cannot decide task run in '%s' status. Only task runs waiting for approval can be approved or rejected.", approval.Status.String))
+		return
+	}
+
+	jobRunID, err := processor.DecideApproval(hs.ctx, hs.store, taskRunUUID, processor.ApprovalDecision{
+		Approved: approved,
+		Approver: reqBody.Approver,
+		Comment:  reqBody.Comment,
+	})
+	if err != nil {
+		if utils.ContainsSubstring(err.Error(), "no rows") {
+			respondError(w, 409, "Task run was decided or expired in the meantime")
+		} else {
+			respondError(w, 500, "Failed to record approval decision", err.Error())
+		}
+		return
+	}
+
+	// A parked run is re-queued, a run still being processed picks up the decision itself
+	requeued, err := hs.store.RequeueApprovedJobRun(hs.ctx, jobRunID)
+	if err != nil {
+		respondError(w, 500, "Failed to re-queue job run", err.Error())
+		return
+	}
+	if requeued > 0 {
+		if err := hs.queue.Enqueue(jobRunID.String()); err != nil {
+			log.Printf("unable to re-queue job run %s after approval decision: %v", jobRunID.String(), err)
+		}
+	}
+
+	newStatus := "completed"
+	message := "Task run approved"
+	if !approved {
+		newStatus = "failed"
+		message = "Task run rejected"
+	}
+	respondJSON(w, 200, map[string]interface{}{
+		"message":     message,
+		"task_run_id": taskRunID,
+		"task_name":   approval.TaskName,
+		"job_run_id":  jobRunID.String(),
+		"approver":    reqBody.Approver,
+		"comment":     reqBody.Comment,
+		"new_status":  newStatus,
+	})
+}
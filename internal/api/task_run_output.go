@@ -0,0 +1,64 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/b0nbon1/stratal/internal/artifacts"
+	"github.com/b0nbon1/stratal/pkg/router"
+	"github.com/b0nbon1/stratal/pkg/utils"
+)
+
+// GetTaskRunOutput streams the complete output of a task run. Outputs beyond the inline
+// limit come from the output store, task_runs.output only holds their truncated start.
+func (hs *HTTPServer) GetTaskRunOutput(w http.ResponseWriter, r *http.Request) {
+	taskRunID := router.GetParam(r, "id")
+	if taskRunID == "" {
+		respondError(w, 400, "Task run ID is required")
+		return
+	}
+
+	taskRunUUID, err := utils.ParseUUID(taskRunID)
+	if err != nil {
+		respondError(w, 400, "Invalid task run UUID", err.Error())
+		return
+	}
+
+	taskRun, err := hs.store.GetTaskRunOutput(hs.ctx, taskRunUUID)
+	if err != nil {
+		if utils.ContainsSubstring(err.Error(), "no rows") {
+			respondError(w, 404, "Task run not found")
+		} else {
+			respondError(w, 500, "Failed to fetch task run", err.Error())
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if !taskRun.OutputKey.Valid {
+		w.Header().Set("Content-Length", strconv.Itoa(len(taskRun.Output.String)))
+		w.WriteHeader(http.StatusOK)
+		io.WriteString(w, taskRun.Output.String)
+		return
+	}
+
+	content, err := hs.outputStore.Open(r.Context(), taskRun.OutputKey.String)
+	if err != nil {
+		if errors.Is(err, artifacts.ErrNotFound) {
+			respondError(w, 404, "Task run output not found", err.Error())
+		} else {
+			respondError(w, 500, "Failed to open task run output", err.Error())
+		}
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("Content-Length", strconv.FormatInt(taskRun.OutputSize.Int64, 10))
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, content); err != nil {
+		fmt.Printf("Failed to stream output of task run %s: %v\n", taskRunID, err)
+	}
+}
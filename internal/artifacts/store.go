@@ -0,0 +1,43 @@
+package artifacts
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path"
+	"strings"
+)
+
+// ErrNotFound is returned when no artifact is stored under a key
+var ErrNotFound = errors.New("artifact not found")
+
+// Store keeps the files tasks pass to each other. Keys are slash separated paths such as
+// <job run id>/<task run id>/<artifact name>.
+type Store interface {
+	// Put stores the content of r under key, replacing an earlier artifact with the same key
+	Put(ctx context.Context, key string, r io.Reader) (int64, error)
+	// Open returns the content stored under key
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the artifact stored under key, removing a missing artifact is not an error
+	Delete(ctx context.Context, key string) error
+}
+
+// Key builds the key of an artifact produced by a task run of a job run
+func Key(jobRunID, taskRunID, name string) string {
+	return path.Join(jobRunID, taskRunID, name)
+}
+
+// ValidateName checks that an artifact name is a relative slash separated path that stays
+// within the directory it is resolved against
+func ValidateName(name string) error {
+	if name == "" {
+		return errors.New("artifact name is empty")
+	}
+	if path.IsAbs(name) || strings.HasPrefix(name, "\\") {
+		return errors.New("artifact name must be a relative path")
+	}
+	if clean := path.Clean(name); clean != name || clean == "." || clean == ".." || strings.HasPrefix(clean, "../") {
+		return errors.New("artifact name must be a clean path within the artifacts directory")
+	}
+	return nil
+}
@@ -5,15 +5,22 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	Database DatabaseConfig
-	Redis    RedisConfig
-	Server   ServerConfig
-	Security SecurityConfig
+	Database  DatabaseConfig
+	Redis     RedisConfig
+	Server    ServerConfig
+	Security  SecurityConfig
+	Artifacts ArtifactsConfig
+	Outputs   OutputsConfig
+	Sandbox   SandboxConfig
+	Scripts   ScriptsConfig
+	Sensors   SensorsConfig
 }
 
 type DatabaseConfig struct {
@@ -40,6 +47,44 @@ type SecurityConfig struct {
 	EncryptionKey string
 }
 
+type ArtifactsConfig struct {
+	Dir string // root of the local artifact store, shared by the workers and the API server
+}
+
+// OutputsConfig bounds the size of task outputs, limits are in bytes and 0 disables them
+type OutputsConfig struct {
+	Dir           string // root of the local store for outputs beyond the inline limit
+	MaxInlineSize int    // output kept on the task run and passed to later tasks, jobs and tasks may set their own
+	MaxLogSize    int    // stdout and stderr of a script each written to the logs
+	MaxEnvSize    int    // a single TASK_OUTPUT_ variable of a script
+	MaxEnvTotal   int    // all TASK_OUTPUT_ variables of a script together
+}
+
+// SandboxConfig selects how workers isolate scripts
+type SandboxConfig struct {
+	Profile  string   // none, standard or strict
+	Network  string   // allow or deny, empty keeps the default of the profile
+	UID      int      // run scripts as this user, 0 keeps the user of the worker
+	GID      int      // run scripts with this group, set together with UID
+	AllowEnv []string // worker variables passed to scripts besides the defaults
+	// CgroupParent is the cgroup v2 directory task resource limits are enforced below,
+	// empty enforces them through rlimits only
+	CgroupParent string
+}
+
+// ScriptsConfig holds the defaults of custom script tasks
+type ScriptsConfig struct {
+	// DefaultTimeout is the deadline of scripts whose task and job set no timeout, 0 sets none
+	DefaultTimeout time.Duration
+}
+
+// SensorsConfig holds what sensors may reach besides their task parameters
+type SensorsConfig struct {
+	// SQLConnections are the databases wait_sql sensors query by name, set as
+	// SQL_CONNECTION_<NAME>=<connection string> with the lower-cased name
+	SQLConnections map[string]string
+}
+
 func Load() *Config {
 	// Load .env file if it exists
 	if err := godotenv.Load(); err != nil {
@@ -67,6 +112,30 @@ func Load() *Config {
 		Security: SecurityConfig{
 			EncryptionKey: getEnv("ENCRYPTION_KEY", ""),
 		},
+		Artifacts: ArtifactsConfig{
+			Dir: getEnv("ARTIFACTS_DIR", "internal/storage/files/artifacts"),
+		},
+		Outputs: OutputsConfig{
+			Dir:           getEnv("OUTPUTS_DIR", "internal/storage/files/outputs"),
+			MaxInlineSize: getEnvInt("OUTPUT_MAX_INLINE_SIZE", 1<<20),
+			MaxLogSize:    getEnvInt("OUTPUT_MAX_LOG_SIZE", 10<<20),
+			MaxEnvSize:    getEnvInt("OUTPUT_MAX_ENV_SIZE", 64<<10),
+			MaxEnvTotal:   getEnvInt("OUTPUT_MAX_ENV_TOTAL", 1<<20),
+		},
+		Sandbox: SandboxConfig{
+			Profile:      getEnv("SANDBOX_PROFILE", "none"),
+			Network:      getEnv("SANDBOX_NETWORK", ""),
+			UID:          getEnvInt("SANDBOX_UID", 0),
+			GID:          getEnvInt("SANDBOX_GID", 0),
+			AllowEnv:     getEnvList("SANDBOX_ALLOW_ENV"),
+			CgroupParent: getEnv("SANDBOX_CGROUP_PARENT", ""),
+		},
+		Scripts: ScriptsConfig{
+			DefaultTimeout: getEnvDuration("SCRIPT_DEFAULT_TIMEOUT", 5*time.Minute),
+		},
+		Sensors: SensorsConfig{
+			SQLConnections: getEnvPrefixed("SQL_CONNECTION_"),
+		},
 	}
 
 	if cfg.Security.EncryptionKey == "" {
@@ -95,6 +164,41 @@ func getEnv(key, defaultVal string) string {
 	return defaultVal
 }
 
+// getEnvList splits a comma separated variable
+func getEnvList(key string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// getEnvPrefixed collects the variables starting with prefix by the lower-cased rest of
+// their name
+func getEnvPrefixed(prefix string) map[string]string {
+	values := make(map[string]string)
+	for _, kv := range os.Environ() {
+		key, val, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(key, prefix) || key == prefix || val == "" {
+			continue
+		}
+		values[strings.ToLower(strings.TrimPrefix(key, prefix))] = val
+	}
+	return values
+}
+
+// getEnvDuration parses a Go duration such as 10m
+func getEnvDuration(key string, defaultVal time.Duration) time.Duration {
+	if val := os.Getenv(key); val != "" {
+		if d, err := time.ParseDuration(val); err == nil && d >= 0 {
+			return d
+		}
+	}
+	return defaultVal
+}
+
 func getEnvInt(key string, defaultVal int) int {
 	if val := os.Getenv(key); val != "" {
 		if i, err := strconv.Atoi(val); err == nil {
@@ -119,6 +119,16 @@ func (l *Logger) Close() {
 	}
 }
 
+// ForJobRun returns the logger of another job run from the same log system, used for
+// child runs started by a sub-job task. The returned func closes it.
+func (jrl *JobRunLogger) ForJobRun(jobRunID string) (*JobRunLogger, func(), error) {
+	logger, err := jrl.logger.GetJobRunLogger(jobRunID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return logger, func() { jrl.logger.CloseJobRunLogger(jobRunID) }, nil
+}
+
 // LogJob logs a job-level message
 func (jrl *JobRunLogger) LogJob(level LogLevel, message string, metadata map[string]interface{}) {
 	jrl.logEntry(JobLogType, "", level, message, "system", metadata)
@@ -0,0 +1,130 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/b0nbon1/stratal/internal/logger"
+	db "github.com/b0nbon1/stratal/internal/storage/db/sqlc"
+	"github.com/b0nbon1/stratal/pkg/utils"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// errWaitingApproval is returned by a taskExecFunc when an approval task has no decision yet
+var errWaitingApproval = errors.New("waiting for approval")
+
+// awaitApproval handles an approval task. The first time it is reached its task run moves to
+// waiting_approval and the run is parked without holding a worker. Once decided, through the
+// API or by expiring, the re-queued run sees the decision here: an approved task run is
+// already completed and never executed again, a rejected one fails the task.
+func awaitApproval(ctx context.Context, store *db.SQLStore, jobRunID pgtype.UUID, task db.Task, jobLogger *logger.JobRunLogger) (string, error) {
+	taskRun, err := store.GetTaskRunByJobRunAndTaskID(ctx, db.GetTaskRunByJobRunAndTaskIDParams{
+		JobRunID: jobRunID,
+		TaskID:   task.ID,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to find task run for task %s: %w", task.Name, err)
+	}
+	approval, err := store.GetTaskRunApproval(ctx, taskRun.ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to load approval of task %s: %w", task.Name, err)
+	}
+
+	switch approval.Status.String {
+	case "waiting_approval":
+		return "", errWaitingApproval
+	case "completed":
+		// approved while the rest of its level was still running
+		return taskRun.Output.String, nil
+	case "failed":
+		if approval.ApprovalDecidedAt.Valid {
+			return "", fmt.Errorf("approval task %s: %s", task.Name, approval.ErrorMessage.String)
+		}
+	}
+
+	var expiresAt pgtype.Timestamp
+	if cfg := task.Config.Approval; cfg != nil && cfg.Expiry != "" {
+		expiry, err := time.ParseDuration(cfg.Expiry)
+		if err != nil || expiry <= 0 {
+			return "", fmt.Errorf("approval task %s has an invalid expiry '%s'", task.Name, cfg.Expiry)
+		}
+		expiresAt = pgtype.Timestamp{Time: time.Now().Add(expiry), Valid: true}
+	}
+
+	if err := store.WaitForApproval(ctx, db.WaitForApprovalParams{
+		ID:                taskRun.ID,
+		ApprovalExpiresAt: expiresAt,
+	}); err != nil {
+		return "", fmt.Errorf("failed to mark task %s as waiting for approval: %w", task.Name, err)
+	}
+
+	message := fmt.Sprintf("Task %s is waiting for approval", task.Name)
+	if task.Config.Approval != nil && task.Config.Approval.Message != "" {
+		message += ": " + task.Config.Approval.Message
+	}
+	fmt.Println(message)
+	if jobLogger != nil {
+		jobLogger.InfoWithTaskRun(taskRun.ID.String(), message)
+	}
+	return "", errWaitingApproval
+}
+
+// parkJobRun moves a job run whose tasks wait for approval or for their next sensor check
+// out of the worker. It reports false when nothing waits any more, e.g. every approval was
+// decided in the meantime, the run then carries on directly.
+func parkJobRun(ctx context.Context, store *db.SQLStore, jobRunID pgtype.UUID, jobLogger *logger.JobRunLogger) (bool, error) {
+	parked, err := store.ParkJobRun(ctx, jobRunID)
+	if err != nil {
+		return false, fmt.Errorf("failed to park job run: %w", err)
+	}
+	if parked == 0 {
+		return false, nil
+	}
+
+	fmt.Printf("Job run %s is waiting for approval or a sensor\n", jobRunID.String())
+	if jobLogger != nil {
+		jobLogger.Info("Job run is waiting, it continues once every pending approval is decided and every sensor is due")
+	}
+	return true, nil
+}
+
+// ApprovalDecision is an approval or rejection of an approval task run
+type ApprovalDecision struct {
+	Approved bool
+	Approver string
+	Comment  string
+}
+
+// DecideApproval records the decision on a task run waiting for approval and returns its
+// job run, which the caller re-queues so the run continues
+func DecideApproval(ctx context.Context, store *db.SQLStore, taskRunID pgtype.UUID, decision ApprovalDecision) (pgtype.UUID, error) {
+	params := db.DecideApprovalParams{
+		ID:              taskRunID,
+		Approver:        utils.ParseText(decision.Approver),
+		ApprovalComment: pgtype.Text{String: decision.Comment, Valid: decision.Comment != ""},
+	}
+	if decision.Approved {
+		output, err := json.Marshal(map[string]interface{}{
+			"approved": true,
+			"approver": decision.Approver,
+			"comment":  decision.Comment,
+		})
+		if err != nil {
+			return pgtype.UUID{}, err
+		}
+		params.Status = utils.ParseText("completed")
+		params.Output = utils.ParseText(string(output))
+	} else {
+		message := fmt.Sprintf("Rejected by %s", decision.Approver)
+		if decision.Comment != "" {
+			message += ": " + decision.Comment
+		}
+		params.Status = utils.ParseText("failed")
+		params.ErrorMessage = utils.ParseText(message)
+	}
+
+	return store.DecideApproval(ctx, params)
+}
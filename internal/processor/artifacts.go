@@ -0,0 +1,297 @@
+package processor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/b0nbon1/stratal/internal/artifacts"
+	"github.com/b0nbon1/stratal/internal/logger"
+	db "github.com/b0nbon1/stratal/internal/storage/db/sqlc"
+	"github.com/b0nbon1/stratal/pkg/utils"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// validateArtifacts checks the artifacts of each task: only custom tasks exchange them,
+// outputs stay within the artifacts directory and inputs name upstream tasks with outputs
+func validateArtifacts(tasks []db.Task) error {
+	tasksByName := make(map[string]db.Task, len(tasks))
+	for _, task := range tasks {
+		tasksByName[task.Name] = task
+	}
+
+	for _, task := range tasks {
+		cfg := task.Config.Artifacts
+		if cfg == nil {
+			continue
+		}
+		if task.Type != "custom" {
+			return fmt.Errorf("task %s: only custom tasks can have artifacts", task.Name)
+		}
+		if len(cfg.Outputs) > 0 && task.Config.Cache != nil {
+			return fmt.Errorf("task %s: tasks with output artifacts cannot be cached", task.Name)
+		}
+		for _, pattern := range cfg.Outputs {
+			if err := artifacts.ValidateName(pattern); err != nil {
+				return fmt.Errorf("task %s: artifact output '%s': %w", task.Name, pattern, err)
+			}
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("task %s: artifact output '%s': %w", task.Name, pattern, err)
+			}
+		}
+		for _, input := range cfg.Inputs {
+			upstream, ok := tasksByName[input]
+			if !ok {
+				return fmt.Errorf("task %s: artifact input '%s' is not a task of this job", task.Name, input)
+			}
+			if upstream.Config.Artifacts == nil || len(upstream.Config.Artifacts.Outputs) == 0 {
+				return fmt.Errorf("task %s: artifact input '%s' declares no artifact outputs", task.Name, input)
+			}
+			if !dependsOn(tasksByName, task.Name, input) {
+				return fmt.Errorf("task %s: artifact input '%s' must run before it, add it to depends_on", task.Name, input)
+			}
+		}
+	}
+	return nil
+}
+
+// dependsOn reports whether the task named name depends on upstream, directly or through
+// other tasks
+func dependsOn(tasksByName map[string]db.Task, name, upstream string) bool {
+	seen := make(map[string]bool)
+	pending := []string{name}
+	for len(pending) > 0 {
+		current := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		for _, dep := range tasksByName[current].Config.DependsOn {
+			if dep == upstream {
+				return true
+			}
+			if !seen[dep] {
+				seen[dep] = true
+				pending = append(pending, dep)
+			}
+		}
+	}
+	return false
+}
+
+// taskArtifacts exchanges the artifacts of a single task run with the artifact store
+type taskArtifacts struct {
+	store     *db.SQLStore
+	files     artifacts.Store
+	jobRunID  pgtype.UUID
+	taskRunID pgtype.UUID
+	task      db.Task
+	jobLogger *logger.JobRunLogger
+}
+
+// newTaskArtifacts returns the artifact exchange of a script task, nil when it has no artifacts
+func newTaskArtifacts(ctx context.Context, store *db.SQLStore, files artifacts.Store, task db.Task, taskRunID pgtype.UUID, jobLogger *logger.JobRunLogger) (*taskArtifacts, error) {
+	if task.Config.Artifacts == nil {
+		return nil, nil
+	}
+	if files == nil {
+		return nil, fmt.Errorf("task %s declares artifacts but the worker has no artifact store", task.Name)
+	}
+	taskRun, err := store.GetTaskRun(ctx, taskRunID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load task run of task %s: %w", task.Name, err)
+	}
+	return &taskArtifacts{
+		store:     store,
+		files:     files,
+		jobRunID:  taskRun.JobRunID,
+		taskRunID: taskRunID,
+		task:      task,
+		jobLogger: jobLogger,
+	}, nil
+}
+
+// Download places the artifacts of each input task in <task name>/ within dir. When an
+// input task ran as several instances their artifacts share the directory, an instance
+// that uploaded later overrides files of the same name.
+func (a *taskArtifacts) Download(ctx context.Context, dir string) error {
+	for _, input := range a.task.Config.Artifacts.Inputs {
+		stored, err := a.store.ListJobRunArtifacts(ctx, db.ListJobRunArtifactsParams{
+			JobRunID: a.jobRunID,
+			TaskName: utils.ParseText(input),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to list artifacts of task %s: %w", input, err)
+		}
+		if len(stored) == 0 {
+			return fmt.Errorf("task %s produced no artifacts in this job run", input)
+		}
+		for _, artifact := range stored {
+			target := filepath.Join(dir, input, filepath.FromSlash(artifact.Name))
+			if err := a.download(ctx, artifact.Key, target); err != nil {
+				return err
+			}
+		}
+		if a.jobLogger != nil {
+			a.jobLogger.InfoWithTaskRun(a.taskRunID.String(), fmt.Sprintf("Downloaded %d artifact(s) of task %s", len(stored), input))
+		}
+	}
+	return nil
+}
+
+// Upload stores every file matched by the output patterns, a directory matched by a pattern
+// is uploaded with everything below it. A pattern that matches nothing fails the task.
+func (a *taskArtifacts) Upload(ctx context.Context, dir string) error {
+	ordered, err := artifactFiles(dir, a.task.Config.Artifacts.Outputs)
+	if err != nil {
+		return err
+	}
+	for _, name := range ordered {
+		if err := a.upload(ctx, dir, name); err != nil {
+			return err
+		}
+	}
+	if a.jobLogger != nil {
+		a.jobLogger.InfoWithTaskRun(a.taskRunID.String(), fmt.Sprintf("Uploaded %d artifact(s) of task %s", len(ordered), a.task.Name))
+	}
+	return nil
+}
+
+// artifactFiles returns the names of the regular files within dir matched by the output
+// patterns, relative to dir. Symbolic links are not followed, a match resolving outside of
+// dir through a linked directory is rejected so that a script cannot upload files of the
+// worker host.
+func artifactFiles(dir string, patterns []string) ([]string, error) {
+	root, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve artifacts directory: %w", err)
+	}
+
+	names := make(map[string]bool)
+	var ordered []string
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(filepath.Join(dir, filepath.FromSlash(pattern)))
+		if err != nil {
+			return nil, fmt.Errorf("artifact output '%s': %w", pattern, err)
+		}
+		found := 0
+		for _, match := range matches {
+			if err := checkWithinDir(root, match); err != nil {
+				return nil, fmt.Errorf("artifact output '%s': %w", pattern, err)
+			}
+			// WalkDir does not follow links, neither the match itself nor entries below it
+			err := filepath.WalkDir(match, func(file string, entry fs.DirEntry, err error) error {
+				if err != nil || !entry.Type().IsRegular() {
+					return err
+				}
+				rel, err := filepath.Rel(dir, file)
+				if err != nil {
+					return err
+				}
+				found++
+				if name := filepath.ToSlash(rel); !names[name] {
+					names[name] = true
+					ordered = append(ordered, name)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, fmt.Errorf("artifact output '%s': %w", pattern, err)
+			}
+		}
+		if found == 0 {
+			return nil, fmt.Errorf("artifact output '%s' matched no files", pattern)
+		}
+	}
+	return ordered, nil
+}
+
+// checkWithinDir fails when file resolves to a location outside of root, a resolved directory
+func checkWithinDir(root, file string) error {
+	resolved, err := filepath.EvalSymlinks(file)
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(root, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("%s resolves outside of the artifacts directory", filepath.Base(file))
+	}
+	return nil
+}
+
+// upload stores a single file of dir and records it on the task run. The file is checked
+// again when it is opened, a script that left processes behind may have replaced it.
+func (a *taskArtifacts) upload(ctx context.Context, dir, name string) error {
+	root, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve artifacts directory: %w", err)
+	}
+	filePath := filepath.Join(dir, filepath.FromSlash(name))
+	if err := checkWithinDir(root, filePath); err != nil {
+		return fmt.Errorf("artifact %s: %w", name, err)
+	}
+	info, err := os.Lstat(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open artifact %s: %w", name, err)
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("artifact %s is not a regular file", name)
+	}
+	file, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open artifact %s: %w", name, err)
+	}
+	defer file.Close()
+	if opened, err := file.Stat(); err != nil || !os.SameFile(info, opened) {
+		return fmt.Errorf("artifact %s changed while it was uploaded", name)
+	}
+
+	key := artifacts.Key(a.jobRunID.String(), a.taskRunID.String(), name)
+	hash := sha256.New()
+	size, err := a.files.Put(ctx, key, io.TeeReader(file, hash))
+	if err != nil {
+		return err
+	}
+
+	if _, err := a.store.CreateTaskArtifact(ctx, db.CreateTaskArtifactParams{
+		JobRunID:  a.jobRunID,
+		TaskRunID: a.taskRunID,
+		TaskName:  a.task.Name,
+		Name:      name,
+		Key:       key,
+		SizeBytes: size,
+		Sha256:    hex.EncodeToString(hash.Sum(nil)),
+	}); err != nil {
+		return fmt.Errorf("failed to record artifact %s: %w", name, err)
+	}
+	return nil
+}
+
+// download copies the artifact stored under key to target
+func (a *taskArtifacts) download(ctx context.Context, key, target string) error {
+	content, err := a.files.Open(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+
+	if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+		return fmt.Errorf("failed to create directory for artifact %s: %w", key, err)
+	}
+	file, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create artifact file %s: %w", target, err)
+	}
+	_, err = io.Copy(file, content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to download artifact %s: %w", key, err)
+	}
+	return nil
+}
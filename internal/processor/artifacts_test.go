@@ -0,0 +1,83 @@
+package processor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArtifactFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"report.txt", "dist/app.bin", "dist/lib/util.so", "logs/run.log"} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+		require.NoError(t, os.WriteFile(path, []byte(name), 0600))
+	}
+
+	tests := []struct {
+		name     string
+		patterns []string
+		expected []string
+	}{
+		{"single file", []string{"report.txt"}, []string{"report.txt"}},
+		{"directory", []string{"dist"}, []string{"dist/app.bin", "dist/lib/util.so"}},
+		{"glob", []string{"*/*.log"}, []string{"logs/run.log"}},
+		{"overlapping patterns", []string{"dist/app.bin", "dist"}, []string{"dist/app.bin", "dist/lib/util.so"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			names, err := artifactFiles(dir, tt.patterns)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, names)
+		})
+	}
+
+	_, err := artifactFiles(dir, []string{"missing/*"})
+	assert.ErrorContains(t, err, "matched no files")
+}
+
+func TestArtifactFiles_Symlinks(t *testing.T) {
+	outside := t.TempDir()
+	secret := filepath.Join(outside, "shadow")
+	require.NoError(t, os.WriteFile(secret, []byte("secret"), 0600))
+
+	tests := []struct {
+		name    string
+		link    string
+		target  string
+		pattern string
+		wantErr string
+	}{
+		{"link to a file outside", "x", secret, "x", "resolves outside of the artifacts directory"},
+		{"link to a directory outside", "out", outside, "out", "resolves outside of the artifacts directory"},
+		{"glob through a linked directory", "out", outside, "out/*", "resolves outside of the artifacts directory"},
+		{"link to the root", "root", "/", "root/etc/*", "resolves outside of the artifacts directory"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			require.NoError(t, os.Symlink(tt.target, filepath.Join(dir, tt.link)))
+
+			names, err := artifactFiles(dir, []string{tt.pattern})
+			assert.Empty(t, names)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestArtifactFiles_SkipsLinksInDirectories(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "dist"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "dist", "app.bin"), []byte("app"), 0600))
+	require.NoError(t, os.Symlink("/etc", filepath.Join(dir, "dist", "etc")))
+	require.NoError(t, os.Symlink("/etc/hostname", filepath.Join(dir, "dist", "hostname")))
+
+	names, err := artifactFiles(dir, []string{"dist"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"dist/app.bin"}, names)
+}
@@ -0,0 +1,191 @@
+package processor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/b0nbon1/stratal/internal/logger"
+	"github.com/b0nbon1/stratal/internal/storage/db/dto"
+	db "github.com/b0nbon1/stratal/internal/storage/db/sqlc"
+	"github.com/b0nbon1/stratal/pkg/tmpl"
+	"github.com/b0nbon1/stratal/pkg/utils"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// defaultCacheTTL is how long a cached task output is reused when the task sets no TTL
+const defaultCacheTTL = 24 * time.Hour
+
+// cacheTTL reads the TTL of a task's cache configuration
+func cacheTTL(task db.Task) (time.Duration, error) {
+	if task.Config.Cache == nil || task.Config.Cache.TTL == "" {
+		return defaultCacheTTL, nil
+	}
+	ttl, err := time.ParseDuration(task.Config.Cache.TTL)
+	if err != nil || ttl <= 0 {
+		return 0, fmt.Errorf("task %s has an invalid cache ttl '%s'", task.Name, task.Config.Cache.TTL)
+	}
+	return ttl, nil
+}
+
+// validateCache checks the cache configuration of tasks. Only tasks that run once and
+// finish in a single pass can reuse an output: fan-out tasks are cached per item neither,
+// approvals wait for a person and sensors park the run until their condition holds.
+func validateCache(tasks []db.Task) error {
+	for _, task := range tasks {
+		if task.Config.Cache == nil {
+			continue
+		}
+		switch {
+		case task.Type == "approval":
+			return fmt.Errorf("task %s: approval tasks cannot be cached", task.Name)
+		case isSensorTask(task):
+			return fmt.Errorf("task %s: sensor tasks cannot be cached", task.Name)
+		case task.Config.ForEach != nil:
+			return fmt.Errorf("task %s: fan-out tasks cannot be cached", task.Name)
+		}
+		if _, err := cacheTTL(task); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cacheParameters renders the parameters of a task for its cache key. Secrets are rendered
+// as their reference, the key depends on which secrets a task uses and not on their values.
+func cacheParameters(ctx context.Context, parameters, outputs map[string]string) (map[string]string, error) {
+	data, opts := outputData(ctx, nil, nil, pgtype.UUID{}, outputs)
+	data["secrets"] = tmpl.LookupFunc(func(name string) (interface{}, error) {
+		return "${secrets." + name + "}", nil
+	})
+	return renderParameters(parameters, data, opts)
+}
+
+// taskCacheKey hashes everything that determines the output of a task: its job, name, type
+// and configuration, its resolved parameters and the outputs of the tasks it depends on.
+// Secret values are not part of the key, only which secrets the task uses.
+func taskCacheKey(task db.Task, params, outputs map[string]string) (string, error) {
+	config := task.Config
+	config.Cache = nil // changing the TTL does not change the output
+
+	upstream := make(map[string]string, len(config.DependsOn))
+	for _, name := range config.DependsOn {
+		upstream[name] = outputs[name]
+	}
+
+	encoded, err := json.Marshal(struct {
+		JobID      string            `json:"job_id"`
+		Name       string            `json:"name"`
+		Type       string            `json:"type"`
+		Config     dto.TaskConfig    `json:"config"`
+		Parameters map[string]string `json:"parameters"`
+		Upstream   map[string]string `json:"upstream"`
+	}{task.JobID.String(), task.Name, task.Type, config, params, upstream})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// runCached reuses the cached output of a task when an entry for its cache key has not
+// expired yet, the task run is then marked cached instead of executing the task and takes
+// over the variables the cached run exported and its complete output in the output store.
+// Otherwise run executes the task and a successful output is stored for later runs.
+func runCached(ctx context.Context, store *db.SQLStore, jobRunID pgtype.UUID, task db.Task, outputs map[string]string, jobLogger *logger.JobRunLogger, run func() (string, error)) (string, error) {
+	ttl, err := cacheTTL(task)
+	if err != nil {
+		return "", err
+	}
+
+	runUncached := func(err error) (string, error) {
+		message := fmt.Sprintf("Task %s runs without its cache, no cache key could be computed: %v", task.Name, err)
+		fmt.Println(message)
+		if jobLogger != nil {
+			jobLogger.Info(message)
+		}
+		return run()
+	}
+	// unresolvable parameters fail the task when it runs, there is nothing to cache
+	params, err := cacheParameters(ctx, task.Config.Parameters, outputs)
+	if err != nil {
+		return runUncached(err)
+	}
+	key, err := taskCacheKey(task, params, outputs)
+	if err != nil {
+		return runUncached(err)
+	}
+
+	taskRun, err := store.GetTaskRunByJobRunAndTaskID(ctx, db.GetTaskRunByJobRunAndTaskIDParams{
+		JobRunID: jobRunID,
+		TaskID:   task.ID,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to find task run for task %s: %w", task.Name, err)
+	}
+
+	hit, err := store.HitTaskCache(ctx, key)
+	switch {
+	case err == nil:
+		if len(hit.ExportedEnv) > 0 {
+			if err := store.SetTaskRunExportedEnv(ctx, db.SetTaskRunExportedEnvParams{
+				ID:          taskRun.ID,
+				ExportedEnv: hit.ExportedEnv,
+			}); err != nil {
+				return "", fmt.Errorf("failed to restore exported variables of task %s: %w", task.Name, err)
+			}
+		}
+		if hit.OutputKey.Valid {
+			if err := store.SetTaskRunOutputSpill(ctx, db.SetTaskRunOutputSpillParams{
+				ID:         taskRun.ID,
+				OutputKey:  hit.OutputKey,
+				OutputSize: hit.OutputSize,
+			}); err != nil {
+				return "", fmt.Errorf("failed to restore output of task %s: %w", task.Name, err)
+			}
+		}
+		if err := store.FinishTaskRun(ctx, db.FinishTaskRunParams{
+			ID:     taskRun.ID,
+			Status: utils.ParseText("cached"),
+			Output: utils.ParseText(hit.Output),
+		}); err != nil {
+			return "", fmt.Errorf("failed to mark task %s as cached: %w", task.Name, err)
+		}
+		message := fmt.Sprintf("Task %s reused the cached output of task run %s", task.Name, hit.TaskRunID.String())
+		fmt.Println(message)
+		if jobLogger != nil {
+			jobLogger.InfoWithTaskRun(taskRun.ID.String(), message)
+		}
+		return hit.Output, nil
+	case !errors.Is(err, pgx.ErrNoRows):
+		fmt.Printf("Failed to look up cached output of task %s: %v\n", task.Name, err)
+		if jobLogger != nil {
+			jobLogger.ErrorWithTaskRun(taskRun.ID.String(), fmt.Sprintf("Failed to look up cached output: %v", err))
+		}
+	}
+
+	output, err := run()
+	if err != nil {
+		return output, err
+	}
+
+	if err := store.PutTaskCacheEntry(ctx, db.PutTaskCacheEntryParams{
+		Key:        key,
+		JobID:      task.JobID,
+		TaskName:   task.Name,
+		Output:     output,
+		TaskRunID:  taskRun.ID,
+		TtlSeconds: ttl.Seconds(),
+	}); err != nil {
+		fmt.Printf("Failed to cache output of task %s: %v\n", task.Name, err)
+		if jobLogger != nil {
+			jobLogger.ErrorWithTaskRun(taskRun.ID.String(), fmt.Sprintf("Failed to cache output: %v", err))
+		}
+	}
+	return output, nil
+}
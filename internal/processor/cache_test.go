@@ -0,0 +1,124 @@
+package processor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/b0nbon1/stratal/internal/storage/db/dto"
+	db "github.com/b0nbon1/stratal/internal/storage/db/sqlc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateCache(t *testing.T) {
+	cache := &dto.CacheConfig{TTL: "6h"}
+
+	tests := []struct {
+		name    string
+		task    db.Task
+		wantErr string
+	}{
+		{
+			name: "script",
+			task: db.Task{Name: "build", Type: "custom", Config: dto.TaskConfig{Cache: cache}},
+		},
+		{
+			name: "builtin task",
+			task: db.Task{Name: "notify", Type: "builtin", Config: dto.TaskConfig{Cache: &dto.CacheConfig{}, Parameters: map[string]string{"task_name": "send_email"}}},
+		},
+		{
+			name:    "approval",
+			task:    db.Task{Name: "sign_off", Type: "approval", Config: dto.TaskConfig{Cache: cache}},
+			wantErr: "approval tasks cannot be cached",
+		},
+		{
+			name:    "sensor",
+			task:    db.Task{Name: "wait", Type: "builtin", Config: dto.TaskConfig{Cache: cache, Parameters: map[string]string{"task_name": "wait_file"}}},
+			wantErr: "sensor tasks cannot be cached",
+		},
+		{
+			name:    "fan-out",
+			task:    db.Task{Name: "test", Type: "custom", Config: dto.TaskConfig{Cache: cache, ForEach: &dto.ForEachConfig{Matrix: map[string][]string{"go": {"1.24", "1.25"}}}}},
+			wantErr: "fan-out tasks cannot be cached",
+		},
+		{
+			name:    "invalid ttl",
+			task:    db.Task{Name: "build", Type: "custom", Config: dto.TaskConfig{Cache: &dto.CacheConfig{TTL: "-1h"}}},
+			wantErr: "invalid cache ttl",
+		},
+		{
+			name: "not cached",
+			task: db.Task{Name: "sign_off", Type: "approval"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateCache([]db.Task{tt.task})
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestTaskCacheKey(t *testing.T) {
+	task := db.Task{Name: "build", Type: "custom", Config: dto.TaskConfig{DependsOn: []string{"fetch"}, Cache: &dto.CacheConfig{TTL: "1h"}}}
+	key, err := taskCacheKey(task, map[string]string{"ref": "main"}, map[string]string{"fetch": "abc", "other": "x"})
+	require.NoError(t, err)
+
+	sameTTLChanged := task
+	sameTTLChanged.Config.Cache = &dto.CacheConfig{TTL: "12h"}
+	other, err := taskCacheKey(sameTTLChanged, map[string]string{"ref": "main"}, map[string]string{"fetch": "abc", "other": "y"})
+	require.NoError(t, err)
+	assert.Equal(t, key, other, "the ttl and outputs of unrelated tasks do not change the key")
+
+	other, err = taskCacheKey(task, map[string]string{"ref": "main"}, map[string]string{"fetch": "def"})
+	require.NoError(t, err)
+	assert.NotEqual(t, key, other, "upstream outputs change the key")
+
+	other, err = taskCacheKey(task, map[string]string{"ref": "dev"}, map[string]string{"fetch": "abc"})
+	require.NoError(t, err)
+	assert.NotEqual(t, key, other, "parameters change the key")
+}
+
+func TestCacheParameters(t *testing.T) {
+	tests := []struct {
+		name       string
+		parameters map[string]string
+		expected   map[string]string
+		wantErr    string
+	}{
+		{
+			name:       "outputs resolved",
+			parameters: map[string]string{"REF": "${outputs.fetch}", "MODE": "fast"},
+			expected:   map[string]string{"REF": "abc", "MODE": "fast"},
+		},
+		{
+			name:       "secrets by reference",
+			parameters: map[string]string{"AUTH": "Bearer ${secrets.TOKEN}"},
+			expected:   map[string]string{"AUTH": "Bearer ${secrets.TOKEN}"},
+		},
+		{
+			name:       "missing output",
+			parameters: map[string]string{"REF": "${outputs.missing}"},
+			wantErr:    "task 'missing' has no output",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params, err := cacheParameters(context.Background(), tt.parameters, map[string]string{"fetch": "abc"})
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, params)
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/b0nbon1/stratal/internal/logger"
+	db "github.com/b0nbon1/stratal/internal/storage/db/sqlc"
+	"github.com/b0nbon1/stratal/pkg/utils"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ErrJobRunCancelled is the cause the worker cancels a job run's context with when a
+// cancellation was requested through the API
+var ErrJobRunCancelled = errors.New("job run cancelled")
+
+// isCancelled reports whether ctx ended because its job run was cancelled
+func isCancelled(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), ErrJobRunCancelled)
+}
+
+// cancelJobRun marks a job run and all of its unfinished task runs as cancelled
+func cancelJobRun(ctx context.Context, store *db.SQLStore, jobRunID pgtype.UUID, message string, jobLogger *logger.JobRunLogger) {
+	fmt.Println(message)
+	if jobLogger != nil {
+		jobLogger.Info(message)
+	}
+
+	// the run context is cancelled at this point, the final state still has to be stored
+	ctx = context.WithoutCancel(ctx)
+	if err := store.CancelJobRun(ctx, db.CancelJobRunParams{
+		ID:           jobRunID,
+		ErrorMessage: utils.ParseText(message),
+	}); err != nil {
+		fmt.Printf("Failed to mark job run as cancelled: %v\n", err)
+		if jobLogger != nil {
+			jobLogger.Error(fmt.Sprintf("Failed to mark job run as cancelled: %v", err))
+		}
+	}
+	if err := store.CancelUnfinishedTaskRuns(ctx, jobRunID); err != nil {
+		fmt.Printf("Failed to mark task runs as cancelled: %v\n", err)
+		if jobLogger != nil {
+			jobLogger.Error(fmt.Sprintf("Failed to mark task runs as cancelled: %v", err))
+		}
+	}
+}
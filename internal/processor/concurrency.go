@@ -0,0 +1,23 @@
+package processor
+
+import (
+	"fmt"
+
+	"github.com/b0nbon1/stratal/internal/storage/db/dto"
+)
+
+// ValidateConcurrency checks the concurrency policy of a job, a job without one is unlimited
+func ValidateConcurrency(cfg *dto.ConcurrencyConfig) error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.MaxRuns < 1 {
+		return fmt.Errorf("concurrency max_runs must be at least 1, got %d", cfg.MaxRuns)
+	}
+	switch cfg.OnConflict {
+	case "", dto.ConcurrencyQueue, dto.ConcurrencySkip, dto.ConcurrencyCancelPrevious:
+	default:
+		return fmt.Errorf("unknown concurrency on_conflict '%s', use queue, skip or cancel_previous", cfg.OnConflict)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/b0nbon1/stratal/internal/logger"
+	"github.com/b0nbon1/stratal/internal/runner"
+	"github.com/b0nbon1/stratal/internal/storage/db/dto"
+	db "github.com/b0nbon1/stratal/internal/storage/db/sqlc"
+	"github.com/b0nbon1/stratal/pkg/expr"
+	"github.com/b0nbon1/stratal/pkg/utils"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// runInputs extracts the inputs a job run was triggered with from its metadata
+func runInputs(metadata []byte) map[string]interface{} {
+	var meta struct {
+		Inputs map[string]interface{} `json:"inputs"`
+	}
+	if len(metadata) > 0 {
+		_ = json.Unmarshal(metadata, &meta)
+	}
+	if meta.Inputs == nil {
+		return map[string]interface{}{}
+	}
+	return meta.Inputs
+}
+
+// conditionVars builds the variables a `when` expression can reference:
+// outputs.<task>, inputs.<name> and env.<NAME>, the worker variables the script sandbox
+// passes to scripts
+func conditionVars(outputs map[string]string, inputs map[string]interface{}, sandbox runner.Sandbox) map[string]interface{} {
+	env := make(map[string]string)
+	for _, kv := range os.Environ() {
+		key, _, ok := strings.Cut(kv, "=")
+		if !ok {
+			continue
+		}
+		if value, visible := sandbox.LookupEnv(key); visible {
+			env[key] = value
+		}
+	}
+
+	return map[string]interface{}{
+		"outputs": outputs,
+		"inputs":  inputs,
+		"env":     env,
+	}
+}
+
+// skipReason decides whether a task has to be skipped. A task is skipped when the trigger
+// rule over its upstream tasks is not met, or when its `when` condition is false.
+// An empty reason means the task runs.
+func skipReason(task db.Task, tasksByName map[string]db.Task, outputs *taskOutputStore, vars map[string]interface{}) (string, error) {
+	if reason := triggerRuleReason(task, tasksByName, outputs); reason != "" {
+		return reason, nil
+	}
+
+	if task.Config.When == "" {
+		return "", nil
+	}
+	ok, err := expr.EvalBool(task.Config.When, vars)
+	if err != nil {
+		return "", fmt.Errorf("task %s has an invalid when condition: %w", task.Name, err)
+	}
+	if !ok {
+		return fmt.Sprintf("condition '%s' evaluated to false", task.Config.When), nil
+	}
+	return "", nil
+}
+
+// validateTaskConditions checks the trigger rules and compiles every `when` condition of a
+// job so that configuration errors fail the run before any task has been executed
+func validateTaskConditions(tasks []db.Task) error {
+	for _, task := range tasks {
+		if !validTriggerRule(task.Config.TriggerRule) {
+			return fmt.Errorf("task %s has an invalid trigger_rule '%s'", task.Name, task.Config.TriggerRule)
+		}
+		switch task.Config.SkipPropagation {
+		case "", dto.SkipPropagationSkip, dto.SkipPropagationNone:
+		default:
+			return fmt.Errorf("task %s has an invalid skip_propagation '%s'", task.Name, task.Config.SkipPropagation)
+		}
+		if task.Config.When == "" {
+			continue
+		}
+		if _, err := expr.Compile(task.Config.When); err != nil {
+			return fmt.Errorf("task %s: %w", task.Name, err)
+		}
+	}
+	return nil
+}
+
+// skipTaskRun marks the task run of a skipped task and records why it was skipped
+func skipTaskRun(ctx context.Context, store *db.SQLStore, jobRunID pgtype.UUID, task db.Task, reason string, jobLogger *logger.JobRunLogger) error {
+	taskRun, err := store.GetTaskRunByJobRunAndTaskID(ctx, db.GetTaskRunByJobRunAndTaskIDParams{
+		JobRunID: jobRunID,
+		TaskID:   task.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to find task run for task %s: %w", task.Name, err)
+	}
+
+	fmt.Printf("Skipping task %s: %s\n", task.Name, reason)
+	if jobLogger != nil {
+		jobLogger.InfoWithTaskRun(taskRun.ID.String(), fmt.Sprintf("Skipping task %s: %s", task.Name, reason))
+	}
+
+	err = store.FinishTaskRun(ctx, db.FinishTaskRunParams{
+		ID:           taskRun.ID,
+		Status:       utils.ParseText("skipped"),
+		ErrorMessage: utils.ParseText(reason),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to mark task %s as skipped: %w", task.Name, err)
+	}
+	return nil
+}
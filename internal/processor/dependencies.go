@@ -0,0 +1,33 @@
+package processor
+
+import (
+	"time"
+
+	"github.com/b0nbon1/stratal/internal/artifacts"
+	"github.com/b0nbon1/stratal/internal/runner"
+	"github.com/b0nbon1/stratal/internal/runner/tasks"
+	"github.com/b0nbon1/stratal/internal/security"
+	db "github.com/b0nbon1/stratal/internal/storage/db/sqlc"
+)
+
+// Dependencies are the stores and settings a job run is processed with, the worker builds
+// them from its configuration
+type Dependencies struct {
+	Store         *db.SQLStore
+	SecretManager *security.SecretManager // nil runs tasks without secrets
+	// Artifacts keeps the artifacts of script tasks, tasks declaring artifacts fail without it
+	Artifacts artifacts.Store
+	// Outputs keeps the complete outputs of task runs beyond their inline limit, without it
+	// the rest of such outputs is discarded
+	Outputs artifacts.Store
+	// OutputLimits bounds task outputs, MaxOutputSize is the default of tasks that set no
+	// max_output_size. Zero limits leave outputs unbounded.
+	OutputLimits runner.OutputLimits
+	// Sandbox isolates the scripts of custom tasks, the env namespace of templates and `when`
+	// conditions sees the worker variables it passes to scripts
+	Sandbox runner.Sandbox
+	// Sensors holds the connections sensors may use, wait_sql only queries these
+	Sensors tasks.SensorOptions
+	// ScriptTimeout is the deadline of scripts whose task and job set no timeout, 0 sets none
+	ScriptTimeout time.Duration
+}
@@ -6,13 +6,12 @@ import (
 
 	"github.com/b0nbon1/stratal/internal/logger"
 	"github.com/b0nbon1/stratal/internal/runner"
-	"github.com/b0nbon1/stratal/internal/security"
 	db "github.com/b0nbon1/stratal/internal/storage/db/sqlc"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
-func ExecuteTaskWithOutputs(ctx context.Context, task db.Task, outputs map[string]string, taskNameToID map[string]string, jobRunID pgtype.UUID, store *db.SQLStore, jobLogger *logger.JobRunLogger) (string, error) {
-	taskRun, err := store.GetTaskRunByJobRunAndTaskID(ctx, db.GetTaskRunByJobRunAndTaskIDParams{
+func ExecuteTaskWithOutputs(ctx context.Context, task db.Task, outputs map[string]string, taskNameToID map[string]string, jobRunID pgtype.UUID, deps Dependencies, jobLogger *logger.JobRunLogger) (string, error) {
+	taskRun, err := deps.Store.GetTaskRunByJobRunAndTaskID(ctx, db.GetTaskRunByJobRunAndTaskIDParams{
 		JobRunID: jobRunID,
 		TaskID:   task.ID,
 	})
@@ -23,47 +22,59 @@ func ExecuteTaskWithOutputs(ctx context.Context, task db.Task, outputs map[strin
 		return "", fmt.Errorf("failed to find task run for task %s: %w", task.Name, err)
 	}
 
-	taskRunID := taskRun.ID.String()
+	return executeTaskRunWithOutputs(ctx, task, taskRun.ID, outputs, deps, jobLogger)
+}
+
+// executeTaskRunWithOutputs runs a task and records the result on the given task run
+func executeTaskRunWithOutputs(ctx context.Context, task db.Task, taskRunUUID pgtype.UUID, outputs map[string]string, deps Dependencies, jobLogger *logger.JobRunLogger) (string, error) {
+	taskRunID := taskRunUUID.String()
+	store := deps.Store
 
 	if jobLogger != nil {
 		jobLogger.InfoWithTaskRun(taskRunID, fmt.Sprintf("Starting execution of task %s (type: %s)", task.Name, task.Type))
 	}
 
-	switch task.Type {
-	case "builtin":
-		output, err := runner.RunBuiltinTask(ctx, task.Name, task.Config.Parameters, outputs)
-		if err != nil && jobLogger != nil {
-			jobLogger.ErrorWithTaskRun(taskRunID, fmt.Sprintf("Builtin task %s failed: %v", task.Name, err))
-		} else if jobLogger != nil {
-			jobLogger.InfoWithTaskRun(taskRunID, fmt.Sprintf("Builtin task %s completed successfully", task.Name))
+	output, err := runWithRetry(ctx, store, task, taskRunUUID, jobLogger, func() (string, error) {
+		ctx, cancel, err := withTimeout(ctx, task.Config.Timeout)
+		if err != nil {
+			return "", fmt.Errorf("task %s: %w", task.Name, err)
 		}
-		return output, err
-	case "custom":
-		if task.Config.Script == nil {
-			err := fmt.Errorf("custom task %s has no script configuration", task.Name)
+		defer cancel()
+
+		params, err := (&ParameterResolver{}).resolveParameters(ctx, pgtype.UUID{}, task.Config.Parameters, outputs)
+		if err != nil {
 			if jobLogger != nil {
-				jobLogger.ErrorWithTaskRun(taskRunID, err.Error())
+				jobLogger.ErrorWithTaskRun(taskRunID, fmt.Sprintf("Failed to resolve parameters for task %s: %v", task.Name, err))
 			}
-			return "", err
-		}
-		output, err := runner.RunCustomScriptWithOutputs(ctx, task.Config.Script, outputs)
-		if err != nil && jobLogger != nil {
-			jobLogger.ErrorWithTaskRun(taskRunID, fmt.Sprintf("Custom script task %s failed: %v", task.Name, err))
-		} else if jobLogger != nil {
-			jobLogger.InfoWithTaskRun(taskRunID, fmt.Sprintf("Custom script task %s completed successfully", task.Name))
+			return "", fmt.Errorf("failed to resolve parameters for task %s: %w", task.Name, err)
 		}
-		return output, err
-	default:
-		err := fmt.Errorf("unsupported task type: %s", task.Type)
-		if jobLogger != nil {
-			jobLogger.ErrorWithTaskRun(taskRunID, err.Error())
-		}
-		return "", err
-	}
+
+		return runTask(task, taskRunID, jobLogger, func() (string, error) {
+			switch task.Type {
+			case "builtin":
+				output, err := runner.RunBuiltinTask(ctx, task.Name, params, outputs)
+				if err != nil {
+					return output, err
+				}
+				return limitOutput(ctx, deps, task, taskRunUUID, output, jobLogger)
+			case "job":
+				output, err := runSubJob(ctx, deps, task, taskRunUUID, outputs, jobLogger)
+				if err != nil {
+					return output, err
+				}
+				return limitOutput(ctx, deps, task, taskRunUUID, output, jobLogger)
+			default:
+				return runScriptTask(ctx, deps, task, taskRunUUID, params, nil, outputs, jobLogger)
+			}
+		})
+	})
+
+	finishTaskRun(ctx, store, taskRunUUID, output, exitCodeFromError(task.Type, err), err, jobLogger)
+	return output, err
 }
 
-func ExecuteTaskWithSecrets(ctx context.Context, task db.Task, store *db.SQLStore, secretManager *security.SecretManager, userID pgtype.UUID, outputs map[string]string, jobRunID pgtype.UUID, jobLogger *logger.JobRunLogger) (string, error) {
-	taskRun, err := store.GetTaskRunByJobRunAndTaskID(ctx, db.GetTaskRunByJobRunAndTaskIDParams{
+func ExecuteTaskWithSecrets(ctx context.Context, task db.Task, deps Dependencies, userID pgtype.UUID, outputs map[string]string, jobRunID pgtype.UUID, jobLogger *logger.JobRunLogger) (string, error) {
+	taskRun, err := deps.Store.GetTaskRunByJobRunAndTaskID(ctx, db.GetTaskRunByJobRunAndTaskIDParams{
 		JobRunID: jobRunID,
 		TaskID:   task.ID,
 	})
@@ -74,37 +85,72 @@ func ExecuteTaskWithSecrets(ctx context.Context, task db.Task, store *db.SQLStor
 		return "", fmt.Errorf("failed to find task run for task %s: %w", task.Name, err)
 	}
 
-	taskRunID := taskRun.ID.String()
+	return executeTaskRunWithSecrets(ctx, task, taskRun.ID, deps, userID, outputs, jobLogger)
+}
+
+// executeTaskRunWithSecrets resolves parameters and secrets, runs a task and records the result on the given task run
+func executeTaskRunWithSecrets(ctx context.Context, task db.Task, taskRunUUID pgtype.UUID, deps Dependencies, userID pgtype.UUID, outputs map[string]string, jobLogger *logger.JobRunLogger) (string, error) {
+	taskRunID := taskRunUUID.String()
+	store := deps.Store
 
 	if jobLogger != nil {
 		jobLogger.InfoWithTaskRun(taskRunID, fmt.Sprintf("Starting execution of task %s with secrets (type: %s)", task.Name, task.Type))
 	}
 
-	resolver := NewParameterResolver(store, secretManager)
-	resolvedParams, secretEnvVars, err := resolver.ResolveParameters(ctx, task, userID, outputs)
-	if err != nil {
-		if jobLogger != nil {
-			jobLogger.ErrorWithTaskRun(taskRunID, fmt.Sprintf("Failed to resolve parameters for task %s: %v", task.Name, err))
+	resolver := NewParameterResolver(store, deps.SecretManager)
+
+	output, err := runWithRetry(ctx, store, task, taskRunUUID, jobLogger, func() (string, error) {
+		ctx, cancel, err := withTimeout(ctx, task.Config.Timeout)
+		if err != nil {
+			return "", fmt.Errorf("task %s: %w", task.Name, err)
 		}
-		return "", fmt.Errorf("failed to resolve parameters for task %s: %w", task.Name, err)
-	}
+		defer cancel()
+
+		resolvedParams, secretEnvVars, err := resolver.ResolveParameters(ctx, task, userID, outputs)
+		if err != nil {
+			if jobLogger != nil {
+				jobLogger.ErrorWithTaskRun(taskRunID, fmt.Sprintf("Failed to resolve parameters for task %s: %v", task.Name, err))
+			}
+			return "", fmt.Errorf("failed to resolve parameters for task %s: %w", task.Name, err)
+		}
+
+		return runTask(task, taskRunID, jobLogger, func() (string, error) {
+			switch task.Type {
+			case "builtin":
+				allParams := make(map[string]string)
+				for k, v := range resolvedParams {
+					allParams[k] = v
+				}
+				for k, v := range secretEnvVars {
+					allParams[k] = v
+				}
+				output, err := runner.RunBuiltinTask(ctx, task.Name, allParams, outputs)
+				if err != nil {
+					return output, err
+				}
+				return limitOutput(ctx, deps, task, taskRunUUID, output, jobLogger)
+			case "job":
+				output, err := runSubJob(ctx, deps, task, taskRunUUID, outputs, jobLogger)
+				if err != nil {
+					return output, err
+				}
+				return limitOutput(ctx, deps, task, taskRunUUID, output, jobLogger)
+			default:
+				return runScriptTask(ctx, deps, task, taskRunUUID, resolvedParams, secretEnvVars, outputs, jobLogger)
+			}
+		})
+	})
 
+	finishTaskRun(ctx, store, taskRunUUID, output, exitCodeFromError(task.Type, err), err, jobLogger)
+	return output, err
+}
+
+// runTask validates the task type, invokes run and logs the outcome for the task run
+func runTask(task db.Task, taskRunID string, jobLogger *logger.JobRunLogger, run func() (string, error)) (string, error) {
+	var kind string
 	switch task.Type {
 	case "builtin":
-		allParams := make(map[string]string)
-		for k, v := range resolvedParams {
-			allParams[k] = v
-		}
-		for k, v := range secretEnvVars {
-			allParams[k] = v
-		}
-		output, err := runner.RunBuiltinTask(ctx, task.Name, allParams, outputs)
-		if err != nil && jobLogger != nil {
-			jobLogger.ErrorWithTaskRun(taskRunID, fmt.Sprintf("Builtin task %s failed: %v", task.Name, err))
-		} else if jobLogger != nil {
-			jobLogger.InfoWithTaskRun(taskRunID, fmt.Sprintf("Builtin task %s completed successfully", task.Name))
-		}
-		return output, err
+		kind = "Builtin task"
 	case "custom":
 		if task.Config.Script == nil {
 			err := fmt.Errorf("custom task %s has no script configuration", task.Name)
@@ -113,13 +159,16 @@ func ExecuteTaskWithSecrets(ctx context.Context, task db.Task, store *db.SQLStor
 			}
 			return "", err
 		}
-		output, err := runner.RunCustomScriptWithSecrets(ctx, task.Config.Script, resolvedParams, secretEnvVars, outputs)
-		if err != nil && jobLogger != nil {
-			jobLogger.ErrorWithTaskRun(taskRunID, fmt.Sprintf("Custom script task %s failed: %v", task.Name, err))
-		} else if jobLogger != nil {
-			jobLogger.InfoWithTaskRun(taskRunID, fmt.Sprintf("Custom script task %s completed successfully", task.Name))
+		kind = "Custom script task"
+	case "job":
+		if task.Config.Job == nil || (task.Config.Job.JobID == "" && task.Config.Job.JobName == "") {
+			err := fmt.Errorf("job task %s has no job_id or job_name configured", task.Name)
+			if jobLogger != nil {
+				jobLogger.ErrorWithTaskRun(taskRunID, err.Error())
+			}
+			return "", err
 		}
-		return output, err
+		kind = "Sub-job task"
 	default:
 		err := fmt.Errorf("unsupported task type: %s", task.Type)
 		if jobLogger != nil {
@@ -127,4 +176,12 @@ func ExecuteTaskWithSecrets(ctx context.Context, task db.Task, store *db.SQLStor
 		}
 		return "", err
 	}
+
+	output, err := run()
+	if err != nil && jobLogger != nil {
+		jobLogger.ErrorWithTaskRun(taskRunID, fmt.Sprintf("%s %s failed: %v", kind, task.Name, err))
+	} else if jobLogger != nil {
+		jobLogger.InfoWithTaskRun(taskRunID, fmt.Sprintf("%s %s completed successfully", kind, task.Name))
+	}
+	return output, err
 }
@@ -0,0 +1,176 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	db "github.com/b0nbon1/stratal/internal/storage/db/sqlc"
+	"github.com/b0nbon1/stratal/pkg/utils"
+)
+
+// maxParallelTasks bounds how many tasks of a single level run at the same time
+const maxParallelTasks = 8
+
+// taskExecFunc runs a single task with a read-only view of the outputs of earlier levels
+type taskExecFunc func(ctx context.Context, task db.Task, outputs map[string]string) (string, error)
+
+// errTaskSkipped is returned by a taskExecFunc when the task was skipped instead of run
+var errTaskSkipped = errors.New("task skipped")
+
+// taskOutputStore holds task outputs and final statuses and is safe for concurrent use
+type taskOutputStore struct {
+	mu       sync.RWMutex
+	outputs  map[string]string
+	statuses map[string]string
+}
+
+func newTaskOutputStore() *taskOutputStore {
+	return &taskOutputStore{
+		outputs:  make(map[string]string),
+		statuses: make(map[string]string),
+	}
+}
+
+// SetStatus records the final status of a task
+func (s *taskOutputStore) SetStatus(taskName, status string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.statuses[taskName] = status
+}
+
+// Status returns the final status of a task, empty if it has not finished
+func (s *taskOutputStore) Status(taskName string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.statuses[taskName]
+}
+
+// Set stores the output of a task
+func (s *taskOutputStore) Set(taskName, output string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.outputs[taskName] = output
+}
+
+// Snapshot returns a copy of all outputs stored so far
+func (s *taskOutputStore) Snapshot() map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snapshot := make(map[string]string, len(s.outputs))
+	for name, output := range s.outputs {
+		snapshot[name] = output
+	}
+	return snapshot
+}
+
+// buildTaskLevels groups tasks into levels that can be executed in parallel
+func buildTaskLevels(tasks []db.Task) ([]TaskLevel, error) {
+	grouped, err := utils.TopoLevels(tasks)
+	if err != nil {
+		return nil, err
+	}
+
+	levels := make([]TaskLevel, 0, len(grouped))
+	for i, levelTasks := range grouped {
+		levels = append(levels, TaskLevel{
+			Level: i + 1,
+			Tasks: levelTasks,
+		})
+	}
+	return levels, nil
+}
+
+// executeLevel runs all tasks of a level concurrently, bounded by maxParallelTasks, and
+// returns the tasks that failed and the approval and sensor tasks that wait. A failure
+// does not stop its siblings, the trigger rules of the dependents decide how the run continues.
+func executeLevel(ctx context.Context, level TaskLevel, exec taskExecFunc, outputs *taskOutputStore) ([]TaskOutput, []db.Task) {
+	// every task in the level only depends on earlier levels, so one snapshot serves all of them
+	snapshot := outputs.Snapshot()
+	sem := make(chan struct{}, maxParallelTasks)
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		failures []TaskOutput
+		waiting  []db.Task
+	)
+
+	for _, task := range level.Tasks {
+		wg.Add(1)
+		go func(task db.Task) {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				return
+			}
+
+			// the run may have been cancelled while we were waiting for a slot
+			if ctx.Err() != nil {
+				return
+			}
+
+			output, err := exec(ctx, task, snapshot)
+			if errors.Is(err, errTaskSkipped) {
+				outputs.SetStatus(task.Name, "skipped")
+				return
+			}
+			if errors.Is(err, errWaitingApproval) || errors.Is(err, errSensorRescheduled) {
+				status := "waiting_approval"
+				if errors.Is(err, errSensorRescheduled) {
+					status = "up_for_reschedule"
+				}
+				outputs.SetStatus(task.Name, status)
+				mu.Lock()
+				waiting = append(waiting, task)
+				mu.Unlock()
+				return
+			}
+			if err != nil {
+				status := "failed"
+				if isTimeout(err) {
+					status = "timed_out"
+				}
+				outputs.SetStatus(task.Name, status)
+
+				mu.Lock()
+				failures = append(failures, TaskOutput{
+					TaskID:   task.ID.String(),
+					TaskName: task.Name,
+					Output:   output,
+					Error:    err,
+				})
+				mu.Unlock()
+				return
+			}
+
+			outputs.Set(task.Name, output)
+			outputs.SetStatus(task.Name, "completed")
+		}(task)
+	}
+
+	wg.Wait()
+
+	// keep the failures in level order so the reported error is deterministic
+	sort.SliceStable(failures, func(i, j int) bool {
+		return taskIndex(level.Tasks, failures[i].TaskName) < taskIndex(level.Tasks, failures[j].TaskName)
+	})
+	sort.SliceStable(waiting, func(i, j int) bool {
+		return taskIndex(level.Tasks, waiting[i].Name) < taskIndex(level.Tasks, waiting[j].Name)
+	})
+	return failures, waiting
+}
+
+func taskIndex(tasks []db.Task, name string) int {
+	for i, task := range tasks {
+		if task.Name == name {
+			return i
+		}
+	}
+	return len(tasks)
+}
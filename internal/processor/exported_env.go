@@ -0,0 +1,109 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/b0nbon1/stratal/internal/logger"
+	"github.com/b0nbon1/stratal/internal/runner"
+	db "github.com/b0nbon1/stratal/internal/storage/db/sqlc"
+	"github.com/b0nbon1/stratal/pkg/tmpl"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// runScriptTask runs the script of a custom task. Its stdout and stderr are streamed to the
+// task logs while it runs, variables it exports are stored on the task run for the tasks
+// that run after it. Declared artifacts are exchanged with the artifact store, an output
+// beyond the inline limit is truncated and spilled to the output store.
+func runScriptTask(ctx context.Context, deps Dependencies, task db.Task, taskRunID pgtype.UUID, params, secrets, outputs map[string]string, jobLogger *logger.JobRunLogger) (string, error) {
+	store := deps.Store
+	exchange, err := newTaskArtifacts(ctx, store, deps.Artifacts, task, taskRunID, jobLogger)
+	if err != nil {
+		return "", err
+	}
+	limits, err := taskOutputLimits(task, deps.OutputLimits)
+	if err != nil {
+		return "", err
+	}
+	resources, err := taskResources(task)
+	if err != nil {
+		return "", err
+	}
+	opts := runner.ScriptOptions{
+		Limits:    limits,
+		Spill:     newOutputSpill(deps, taskRunID, jobLogger),
+		Sandbox:   deps.Sandbox,
+		Resources: resources,
+	}
+	if exchange != nil {
+		opts.Artifacts = exchange
+	}
+	if jobLogger != nil {
+		opts.Stdout = jobLogger.GetWriterForTaskRun(taskRunID.String(), "stdout")
+		opts.Stderr = jobLogger.GetWriterForTaskRun(taskRunID.String(), "stderr")
+	}
+	result, err := runner.RunScript(ctx, task.Config.Script, params, secrets, outputs, opts)
+	if err != nil {
+		return "", err
+	}
+
+	if len(result.Env) > 0 {
+		exported, err := json.Marshal(result.Env)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode exported env of task %s: %w", task.Name, err)
+		}
+		if err := store.SetTaskRunExportedEnv(ctx, db.SetTaskRunExportedEnvParams{
+			ID:          taskRunID,
+			ExportedEnv: exported,
+		}); err != nil {
+			return "", fmt.Errorf("failed to store exported env of task %s: %w", task.Name, err)
+		}
+	}
+
+	// stdout is limited by the runner, named outputs are limited here
+	if len(result.Outputs) > 0 {
+		return limitOutput(ctx, deps, task, taskRunID, result.Output(), jobLogger)
+	}
+	return result.Output(), nil
+}
+
+// loadExportedEnv merges the variables exported by the completed task runs of a job run,
+// a variable exported by a task that finished later overrides an earlier one
+func loadExportedEnv(ctx context.Context, store *db.SQLStore, jobRunID pgtype.UUID) (map[string]string, error) {
+	rows, err := store.ListTaskRunExportedEnv(ctx, jobRunID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load exported env: %w", err)
+	}
+
+	env := make(map[string]string)
+	for _, row := range rows {
+		var exported map[string]string
+		if err := json.Unmarshal(row, &exported); err != nil {
+			return nil, fmt.Errorf("failed to decode exported env: %w", err)
+		}
+		for name, value := range exported {
+			env[name] = value
+		}
+	}
+	return env, nil
+}
+
+// withExportedEnv passes exported variables to a script task as parameters, parameters
+// configured on the task take precedence. Builtin tasks read their parameters by name, an
+// exported variable must not fill in one they leave unset, they use ${vars.NAME} instead.
+func withExportedEnv(task db.Task, env map[string]string) db.Task {
+	if len(env) == 0 || task.Type != "custom" {
+		return task
+	}
+
+	params := make(map[string]string, len(env)+len(task.Config.Parameters))
+	for k, v := range env {
+		params[k] = tmpl.Escape(v)
+	}
+	for k, v := range task.Config.Parameters {
+		params[k] = v
+	}
+	task.Config.Parameters = params
+	return task
+}
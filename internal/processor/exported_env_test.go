@@ -0,0 +1,44 @@
+package processor
+
+import (
+	"testing"
+
+	"github.com/b0nbon1/stratal/internal/storage/db/dto"
+	db "github.com/b0nbon1/stratal/internal/storage/db/sqlc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithExportedEnv(t *testing.T) {
+	env := map[string]string{"VERSION": "1.2.0", "url": "https://evil.example", "NOTE": "${secrets.TOKEN}"}
+
+	tests := []struct {
+		name     string
+		task     db.Task
+		expected map[string]string
+	}{
+		{
+			name: "script task",
+			task: db.Task{Name: "deploy", Type: "custom", Config: dto.TaskConfig{Parameters: map[string]string{"VERSION": "pinned"}}},
+			expected: map[string]string{
+				"VERSION": "pinned",
+				"url":     "https://evil.example",
+				"NOTE":    "$${secrets.TOKEN}",
+			},
+		},
+		{
+			name:     "builtin task",
+			task:     db.Task{Name: "notify", Type: "builtin", Config: dto.TaskConfig{Parameters: map[string]string{"task_name": "http_request"}}},
+			expected: map[string]string{"task_name": "http_request"},
+		},
+		{
+			name: "job task",
+			task: db.Task{Name: "release", Type: "job"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, withExportedEnv(tt.task, env).Config.Parameters)
+		})
+	}
+}
@@ -0,0 +1,314 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/b0nbon1/stratal/internal/logger"
+	db "github.com/b0nbon1/stratal/internal/storage/db/sqlc"
+	"github.com/b0nbon1/stratal/pkg/tmpl"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// maxFanOutInstances bounds how many instances a single fan-out task expands into, each
+// instance is a task run of its own
+const maxFanOutInstances = 1000
+
+// taskRunExecFunc runs a task and records its result on the given task run
+type taskRunExecFunc func(ctx context.Context, task db.Task, taskRunID pgtype.UUID, outputs map[string]string) (string, error)
+
+// fanOutItem is a single instance of a fan-out task and the parameters injected into it
+type fanOutItem struct {
+	params map[string]string
+}
+
+// validateFanOut checks the for_each configuration of every task of a job
+func validateFanOut(tasks []db.Task) error {
+	for _, task := range tasks {
+		forEach := task.Config.ForEach
+		if forEach == nil {
+			continue
+		}
+		if task.Type == "approval" {
+			return fmt.Errorf("task %s: approval tasks cannot fan out", task.Name)
+		}
+		if (len(forEach.Matrix) > 0) == (forEach.FromTask != "") {
+			return fmt.Errorf("task %s: for_each needs either a matrix or from_task", task.Name)
+		}
+		if forEach.FromTask != "" && !containsString(task.Config.DependsOn, forEach.FromTask) {
+			return fmt.Errorf("task %s: for_each from_task %s must be listed in depends_on", task.Name, forEach.FromTask)
+		}
+		instances := 1
+		for key, values := range forEach.Matrix {
+			if len(values) == 0 {
+				return fmt.Errorf("task %s: matrix key %s has no values", task.Name, key)
+			}
+			// a product that would overflow saturates, it is far beyond the limit anyway
+			if instances > math.MaxInt/len(values) {
+				instances = math.MaxInt
+			} else {
+				instances *= len(values)
+			}
+		}
+		if instances > maxFanOutInstances {
+			return fmt.Errorf("task %s: matrix expands to %d instances, at most %d are allowed", task.Name, instances, maxFanOutInstances)
+		}
+	}
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// expandFanOut computes the instances of a fan-out task from its matrix or upstream output
+func expandFanOut(task db.Task, outputs map[string]string) ([]fanOutItem, error) {
+	forEach := task.Config.ForEach
+	if len(forEach.Matrix) > 0 {
+		return expandMatrix(forEach.Matrix), nil
+	}
+
+	output, ok := outputs[forEach.FromTask]
+	if !ok {
+		return nil, fmt.Errorf("task %s: no output from task %s to fan out over", task.Name, forEach.FromTask)
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal([]byte(strings.TrimSpace(output)), &items); err != nil {
+		return nil, fmt.Errorf("task %s: output of task %s is not a JSON array: %w", task.Name, forEach.FromTask, err)
+	}
+	if len(items) > maxFanOutInstances {
+		return nil, fmt.Errorf("task %s: output of task %s has %d items, at most %d instances are allowed", task.Name, forEach.FromTask, len(items), maxFanOutInstances)
+	}
+
+	instances := make([]fanOutItem, 0, len(items))
+	for i, raw := range items {
+		item := string(raw)
+		var s string
+		if err := json.Unmarshal(raw, &s); err == nil {
+			item = s
+		}
+		instances = append(instances, fanOutItem{params: map[string]string{
+			"ITEM":       item,
+			"ITEM_INDEX": strconv.Itoa(i),
+		}})
+	}
+	return instances, nil
+}
+
+// expandMatrix builds the cross product of all matrix values, keys in sorted order
+func expandMatrix(matrix map[string][]string) []fanOutItem {
+	keys := make([]string, 0, len(matrix))
+	for key := range matrix {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	combinations := []map[string]string{{}}
+	for _, key := range keys {
+		var next []map[string]string
+		for _, combination := range combinations {
+			for _, value := range matrix[key] {
+				c := make(map[string]string, len(combination)+1)
+				for k, v := range combination {
+					c[k] = v
+				}
+				c[key] = value
+				next = append(next, c)
+			}
+		}
+		combinations = next
+	}
+
+	instances := make([]fanOutItem, 0, len(combinations))
+	for i, combination := range combinations {
+		item, _ := json.Marshal(combination)
+		params := map[string]string{
+			"ITEM":       string(item),
+			"ITEM_INDEX": strconv.Itoa(i),
+		}
+		for k, v := range combination {
+			params[k] = v
+		}
+		instances = append(instances, fanOutItem{params: params})
+	}
+	return instances
+}
+
+// instanceTask returns a copy of the task with the parameters of one instance merged in
+func instanceTask(task db.Task, item fanOutItem) db.Task {
+	params := make(map[string]string, len(task.Config.Parameters)+len(item.params))
+	for k, v := range task.Config.Parameters {
+		params[k] = v
+	}
+	// item values are data, they are not rendered as templates
+	for k, v := range item.params {
+		params[k] = tmpl.Escape(v)
+	}
+	task.Config.Parameters = params
+	task.Config.ForEach = nil
+	return task
+}
+
+// executeFanOut expands a task into parallel instances, each with its own task run under
+// the task run of the task. The task output is a JSON array of the instance outputs in
+// instance order. Instances completed by an earlier attempt of the run are not executed again.
+func executeFanOut(ctx context.Context, deps Dependencies, jobRunID pgtype.UUID, task db.Task, outputs map[string]string, exec taskRunExecFunc, jobLogger *logger.JobRunLogger) (string, error) {
+	store := deps.Store
+	parent, err := store.GetTaskRunByJobRunAndTaskID(ctx, db.GetTaskRunByJobRunAndTaskIDParams{
+		JobRunID: jobRunID,
+		TaskID:   task.ID,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to find task run for task %s: %w", task.Name, err)
+	}
+	markTaskRunRunning(ctx, store, parent.ID, 1, jobLogger)
+
+	items, err := expandFanOut(task, outputs)
+	if err != nil {
+		finishTaskRun(ctx, store, parent.ID, "", pgtype.Int4{}, err, jobLogger)
+		return "", err
+	}
+
+	fmt.Printf("Fanning out task %s into %d instance(s)\n", task.Name, len(items))
+	if jobLogger != nil {
+		jobLogger.InfoWithTaskRun(parent.ID.String(), fmt.Sprintf("Fanning out task %s into %d instance(s)", task.Name, len(items)))
+	}
+
+	existing, err := store.ListTaskRunInstances(ctx, parent.ID)
+	if err != nil {
+		err = fmt.Errorf("failed to load instances of task %s: %w", task.Name, err)
+		finishTaskRun(ctx, store, parent.ID, "", pgtype.Int4{}, err, jobLogger)
+		return "", err
+	}
+	instanceRuns := make(map[int]db.ListTaskRunInstancesRow, len(existing))
+	for _, instance := range existing {
+		instanceRuns[int(instance.InstanceIndex.Int32)] = instance
+	}
+
+	limit := task.Config.ForEach.MaxParallel
+	if limit <= 0 {
+		limit = maxParallelTasks
+	}
+	sem := make(chan struct{}, limit)
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		failures []int
+		firstErr error
+	)
+	results := make([]string, len(items))
+
+	for i, item := range items {
+		instance, found := instanceRuns[i]
+		if found && instance.Status.String == "completed" {
+			results[i] = instance.Output.String
+			continue
+		}
+		instanceRunID := instance.ID
+		if !found {
+			instanceRunID, err = store.CreateTaskRunInstance(ctx, db.CreateTaskRunInstanceParams{
+				JobRunID:        jobRunID,
+				TaskID:          task.ID,
+				ParentTaskRunID: parent.ID,
+				InstanceIndex:   pgtype.Int4{Int32: int32(i), Valid: true},
+			})
+			if err != nil {
+				err = fmt.Errorf("failed to create task run for instance %d of task %s: %w", i, task.Name, err)
+				mu.Lock()
+				failures = append(failures, i)
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+				continue
+			}
+		}
+
+		wg.Add(1)
+		go func(i int, item fanOutItem, instanceRunID pgtype.UUID) {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				return
+			}
+
+			output, err := exec(ctx, instanceTask(task, item), instanceRunID, outputs)
+			mu.Lock()
+			defer mu.Unlock()
+			results[i] = output
+			if err != nil {
+				failures = append(failures, i)
+				if firstErr == nil {
+					firstErr = fmt.Errorf("instance %d: %w", i, err)
+				}
+			}
+		}(i, item, instanceRunID)
+	}
+
+	wg.Wait()
+
+	aggregated, err := aggregateOutputs(results)
+	if err != nil {
+		aggregated = ""
+	}
+	if firstErr == nil && ctx.Err() != nil {
+		firstErr = ctx.Err()
+	}
+	if firstErr != nil {
+		if len(failures) > 0 {
+			sort.Ints(failures)
+			indexes := make([]string, 0, len(failures))
+			for _, i := range failures {
+				indexes = append(indexes, strconv.Itoa(i))
+			}
+			firstErr = fmt.Errorf("%d of %d instances of task %s failed (%s): %w", len(failures), len(items), task.Name, strings.Join(indexes, ", "), firstErr)
+		}
+		finishTaskRun(ctx, store, parent.ID, aggregated, pgtype.Int4{}, firstErr, jobLogger)
+		return aggregated, firstErr
+	}
+
+	if jobLogger != nil {
+		jobLogger.InfoWithTaskRun(parent.ID.String(), fmt.Sprintf("All %d instance(s) of task %s completed", len(items), task.Name))
+	}
+	aggregated, err = limitOutput(ctx, deps, task, parent.ID, aggregated, jobLogger)
+	finishTaskRun(ctx, store, parent.ID, aggregated, pgtype.Int4{}, err, jobLogger)
+	return aggregated, err
+}
+
+// aggregateOutputs builds the JSON array of instance outputs. Outputs that are valid JSON
+// are embedded as is, everything else as a string.
+func aggregateOutputs(results []string) (string, error) {
+	values := make([]json.RawMessage, 0, len(results))
+	for _, result := range results {
+		trimmed := strings.TrimSpace(result)
+		if trimmed != "" && json.Valid([]byte(trimmed)) {
+			values = append(values, json.RawMessage(trimmed))
+			continue
+		}
+		encoded, err := json.Marshal(trimmed)
+		if err != nil {
+			return "", err
+		}
+		values = append(values, encoded)
+	}
+	aggregated, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+	return string(aggregated), nil
+}
@@ -0,0 +1,171 @@
+package processor
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/b0nbon1/stratal/internal/storage/db/dto"
+	db "github.com/b0nbon1/stratal/internal/storage/db/sqlc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func itemParams(items []fanOutItem) []map[string]string {
+	params := make([]map[string]string, 0, len(items))
+	for _, item := range items {
+		params = append(params, item.params)
+	}
+	return params
+}
+
+func TestExpandMatrix(t *testing.T) {
+	tests := []struct {
+		name     string
+		matrix   map[string][]string
+		expected []map[string]string
+	}{
+		{
+			name:   "single key",
+			matrix: map[string][]string{"GO": {"1.24", "1.25"}},
+			expected: []map[string]string{
+				{"ITEM": `{"GO":"1.24"}`, "ITEM_INDEX": "0", "GO": "1.24"},
+				{"ITEM": `{"GO":"1.25"}`, "ITEM_INDEX": "1", "GO": "1.25"},
+			},
+		},
+		{
+			name:   "cross product in sorted key order",
+			matrix: map[string][]string{"OS": {"linux", "darwin"}, "ARCH": {"amd64", "arm64"}},
+			expected: []map[string]string{
+				{"ITEM": `{"ARCH":"amd64","OS":"linux"}`, "ITEM_INDEX": "0", "ARCH": "amd64", "OS": "linux"},
+				{"ITEM": `{"ARCH":"amd64","OS":"darwin"}`, "ITEM_INDEX": "1", "ARCH": "amd64", "OS": "darwin"},
+				{"ITEM": `{"ARCH":"arm64","OS":"linux"}`, "ITEM_INDEX": "2", "ARCH": "arm64", "OS": "linux"},
+				{"ITEM": `{"ARCH":"arm64","OS":"darwin"}`, "ITEM_INDEX": "3", "ARCH": "arm64", "OS": "darwin"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, itemParams(expandMatrix(tt.matrix)))
+		})
+	}
+}
+
+func TestExpandFanOut(t *testing.T) {
+	fromTask := db.Task{Name: "deploy", Config: dto.TaskConfig{
+		DependsOn: []string{"regions"},
+		ForEach:   &dto.ForEachConfig{FromTask: "regions"},
+	}}
+
+	tests := []struct {
+		name     string
+		task     db.Task
+		outputs  map[string]string
+		expected []map[string]string
+		wantErr  string
+	}{
+		{
+			name:    "strings and other values",
+			task:    fromTask,
+			outputs: map[string]string{"regions": " [\"eu-west-1\", 42, {\"name\": \"us\"}]\n"},
+			expected: []map[string]string{
+				{"ITEM": "eu-west-1", "ITEM_INDEX": "0"},
+				{"ITEM": "42", "ITEM_INDEX": "1"},
+				{"ITEM": `{"name": "us"}`, "ITEM_INDEX": "2"},
+			},
+		},
+		{
+			name:     "empty array",
+			task:     fromTask,
+			outputs:  map[string]string{"regions": "[]"},
+			expected: []map[string]string{},
+		},
+		{
+			name: "matrix",
+			task: db.Task{Name: "test", Config: dto.TaskConfig{ForEach: &dto.ForEachConfig{Matrix: map[string][]string{"GO": {"1.25"}}}}},
+			expected: []map[string]string{
+				{"ITEM": `{"GO":"1.25"}`, "ITEM_INDEX": "0", "GO": "1.25"},
+			},
+		},
+		{
+			name:    "missing upstream output",
+			task:    fromTask,
+			outputs: map[string]string{},
+			wantErr: "no output from task regions to fan out over",
+		},
+		{
+			name:    "not an array",
+			task:    fromTask,
+			outputs: map[string]string{"regions": `{"region": "eu-west-1"}`},
+			wantErr: "output of task regions is not a JSON array",
+		},
+		{
+			name:    "too many items",
+			task:    fromTask,
+			outputs: map[string]string{"regions": "[" + strings.Repeat("1,", maxFanOutInstances) + "1]"},
+			wantErr: fmt.Sprintf("output of task regions has %d items, at most %d instances are allowed", maxFanOutInstances+1, maxFanOutInstances),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := expandFanOut(tt.task, tt.outputs)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, itemParams(items))
+		})
+	}
+}
+
+func matrixTask(matrix map[string][]string) db.Task {
+	return db.Task{Name: "test", Type: "custom", Config: dto.TaskConfig{ForEach: &dto.ForEachConfig{Matrix: matrix}}}
+}
+
+func values(n int) []string {
+	values := make([]string, n)
+	for i := range values {
+		values[i] = fmt.Sprint(i)
+	}
+	return values
+}
+
+func TestValidateFanOut_MaxInstances(t *testing.T) {
+	assert.NoError(t, validateFanOut([]db.Task{matrixTask(map[string][]string{"A": values(10), "B": values(100)})}))
+
+	err := validateFanOut([]db.Task{matrixTask(map[string][]string{"A": values(10), "B": values(101)})})
+	assert.ErrorContains(t, err, fmt.Sprintf("task test: matrix expands to 1010 instances, at most %d are allowed", maxFanOutInstances))
+
+	// the count saturates instead of overflowing
+	huge := make(map[string][]string)
+	for i := 0; i < 70; i++ {
+		huge[fmt.Sprintf("K%d", i)] = values(2)
+	}
+	assert.ErrorContains(t, validateFanOut([]db.Task{matrixTask(huge)}), "at most")
+}
+
+func TestAggregateOutputs(t *testing.T) {
+	tests := []struct {
+		name     string
+		results  []string
+		expected string
+	}{
+		{"no instances", nil, `[]`},
+		{"plain text", []string{"ok\n", " done "}, `["ok","done"]`},
+		{"json values", []string{`{"a": 1}`, "42", "true", `"quoted"`}, `[{"a":1},42,true,"quoted"]`},
+		{"empty output", []string{"", "  "}, `["",""]`},
+		{"mixed", []string{"[1,2]", "not json {"}, `[[1,2],"not json {"]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			aggregated, err := aggregateOutputs(tt.results)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, aggregated)
+		})
+	}
+}
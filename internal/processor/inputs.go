@@ -0,0 +1,217 @@
+package processor
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/b0nbon1/stratal/internal/storage/db/dto"
+	db "github.com/b0nbon1/stratal/internal/storage/db/sqlc"
+	"github.com/b0nbon1/stratal/pkg/expr"
+	"github.com/b0nbon1/stratal/pkg/tmpl"
+)
+
+// inputNamePattern keeps input names usable in ${inputs.name} references and env var names
+var inputNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// ValidateInputSpecs checks the input declarations of a job: names are valid and unique,
+// types are known and defaults match their type and allowed values
+func ValidateInputSpecs(specs []dto.InputSpec) error {
+	seen := make(map[string]bool, len(specs))
+	for _, spec := range specs {
+		if !inputNamePattern.MatchString(spec.Name) {
+			return fmt.Errorf("invalid input name '%s'", spec.Name)
+		}
+		if seen[spec.Name] {
+			return fmt.Errorf("input '%s' is declared more than once", spec.Name)
+		}
+		seen[spec.Name] = true
+
+		switch inputType(spec) {
+		case dto.InputTypeString, dto.InputTypeNumber, dto.InputTypeInteger, dto.InputTypeBoolean:
+		default:
+			return fmt.Errorf("input '%s' has unknown type '%s'", spec.Name, spec.Type)
+		}
+		for _, allowed := range spec.Enum {
+			if _, err := coerceInput(spec, allowed); err != nil {
+				return fmt.Errorf("input '%s' has an invalid enum value: %w", spec.Name, err)
+			}
+		}
+		if spec.Default != nil {
+			if _, err := checkInput(spec, spec.Default); err != nil {
+				return fmt.Errorf("input '%s' has an invalid default: %w", spec.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ResolveRunInputs validates the inputs supplied for a run against the job's declarations
+// and returns them converted to their declared types, with defaults filled in. Without
+// declarations the supplied inputs are accepted as they are.
+func ResolveRunInputs(specs []dto.InputSpec, supplied map[string]interface{}) (map[string]interface{}, error) {
+	resolved := make(map[string]interface{}, len(specs))
+	if len(specs) == 0 {
+		for name, value := range supplied {
+			resolved[name] = value
+		}
+		return resolved, nil
+	}
+
+	declared := make(map[string]bool, len(specs))
+	var problems []string
+	for _, spec := range specs {
+		declared[spec.Name] = true
+
+		value, ok := supplied[spec.Name]
+		if !ok || value == nil {
+			switch {
+			case spec.Default != nil:
+				value = spec.Default
+			case spec.Required:
+				problems = append(problems, fmt.Sprintf("input '%s' is required", spec.Name))
+				continue
+			default:
+				continue
+			}
+		}
+
+		checked, err := checkInput(spec, value)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("input '%s': %v", spec.Name, err))
+			continue
+		}
+		resolved[spec.Name] = checked
+	}
+
+	var unknown []string
+	for name := range supplied {
+		if !declared[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		problems = append(problems, fmt.Sprintf("input '%s' is not declared by the job", name))
+	}
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid inputs: %s", strings.Join(problems, "; "))
+	}
+	return resolved, nil
+}
+
+// withRunInputs renders the inputs, vars, run and env namespaces in the parameters and
+// sub-job inputs of a task and passes every input as an INPUT_NAME parameter, parameters
+// configured on the task take precedence
+func withRunInputs(task db.Task, scope runScope) (db.Task, error) {
+	params := make(map[string]string, len(task.Config.Parameters)+len(scope.inputs))
+	for name, value := range scope.inputs {
+		params[inputEnvName(name)] = tmpl.Escape(expr.Format(value))
+	}
+	for key, value := range task.Config.Parameters {
+		resolved, err := renderRunScope(value, task, scope)
+		if err != nil {
+			return task, fmt.Errorf("parameter '%s' of task %s: %w", key, task.Name, err)
+		}
+		params[key] = resolved
+	}
+
+	if task.Config.Job != nil && len(task.Config.Job.Inputs) > 0 {
+		subJob := *task.Config.Job
+		subJob.Inputs = make(map[string]string, len(task.Config.Job.Inputs))
+		for key, value := range task.Config.Job.Inputs {
+			resolved, err := renderRunScope(value, task, scope)
+			if err != nil {
+				return task, fmt.Errorf("input '%s' of task %s: %w", key, task.Name, err)
+			}
+			subJob.Inputs[key] = resolved
+		}
+		task.Config.Job = &subJob
+	}
+	task.Config.Parameters = params
+	return task, nil
+}
+
+// inputEnvName is the environment variable an input is passed to scripts as
+func inputEnvName(name string) string {
+	return "INPUT_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+func inputType(spec dto.InputSpec) string {
+	if spec.Type == "" {
+		return dto.InputTypeString
+	}
+	return spec.Type
+}
+
+// checkInput converts a value to the type of the input and checks it against the allowed values
+func checkInput(spec dto.InputSpec, value interface{}) (interface{}, error) {
+	converted, err := coerceInput(spec, value)
+	if err != nil {
+		return nil, err
+	}
+	if len(spec.Enum) == 0 {
+		return converted, nil
+	}
+
+	allowed := make([]string, 0, len(spec.Enum))
+	for _, option := range spec.Enum {
+		option, err := coerceInput(spec, option)
+		if err == nil && option == converted {
+			return converted, nil
+		}
+		allowed = append(allowed, expr.Format(option))
+	}
+	return nil, fmt.Errorf("value '%s' is not one of %s", expr.Format(converted), strings.Join(allowed, ", "))
+}
+
+// coerceInput converts a value to the type of the input. Strings are accepted for every
+// type so that inputs passed by sub-job tasks or query strings can be typed.
+func coerceInput(spec dto.InputSpec, value interface{}) (interface{}, error) {
+	switch inputType(spec) {
+	case dto.InputTypeString:
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case float64, bool:
+			return expr.Format(v), nil
+		}
+	case dto.InputTypeNumber, dto.InputTypeInteger:
+		var number float64
+		switch v := value.(type) {
+		case float64:
+			number = v
+		case int:
+			number = float64(v)
+		case string:
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return nil, fmt.Errorf("expected a %s, got '%s'", inputType(spec), v)
+			}
+			number = parsed
+		default:
+			return nil, fmt.Errorf("expected a %s, got %v", inputType(spec), value)
+		}
+		if inputType(spec) == dto.InputTypeInteger && number != math.Trunc(number) {
+			return nil, fmt.Errorf("expected an integer, got %s", expr.Format(number))
+		}
+		return number, nil
+	case dto.InputTypeBoolean:
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			parsed, err := strconv.ParseBool(strings.TrimSpace(v))
+			if err != nil {
+				return nil, fmt.Errorf("expected a boolean, got '%s'", v)
+			}
+			return parsed, nil
+		}
+		return nil, fmt.Errorf("expected a boolean, got %v", value)
+	}
+	return nil, fmt.Errorf("expected a %s, got %v", inputType(spec), value)
+}
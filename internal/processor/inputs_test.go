@@ -0,0 +1,126 @@
+package processor
+
+import (
+	"testing"
+
+	"github.com/b0nbon1/stratal/internal/storage/db/dto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoerceInput(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     dto.InputSpec
+		value    interface{}
+		expected interface{}
+		wantErr  string
+	}{
+		{"string", dto.InputSpec{Name: "ref"}, "main", "main", ""},
+		{"string from number", dto.InputSpec{Name: "ref", Type: dto.InputTypeString}, float64(42), "42", ""},
+		{"string from boolean", dto.InputSpec{Name: "ref"}, true, "true", ""},
+		{"string from list", dto.InputSpec{Name: "ref"}, []interface{}{"a"}, nil, "expected a string"},
+		{"number", dto.InputSpec{Name: "ratio", Type: dto.InputTypeNumber}, 0.5, 0.5, ""},
+		{"number from string", dto.InputSpec{Name: "ratio", Type: dto.InputTypeNumber}, " 2.5 ", 2.5, ""},
+		{"number from int", dto.InputSpec{Name: "ratio", Type: dto.InputTypeNumber}, 3, float64(3), ""},
+		{"invalid number", dto.InputSpec{Name: "ratio", Type: dto.InputTypeNumber}, "half", nil, "expected a number, got 'half'"},
+		{"number from boolean", dto.InputSpec{Name: "ratio", Type: dto.InputTypeNumber}, true, nil, "expected a number, got true"},
+		{"integer", dto.InputSpec{Name: "replicas", Type: dto.InputTypeInteger}, "3", float64(3), ""},
+		{"fractional integer", dto.InputSpec{Name: "replicas", Type: dto.InputTypeInteger}, 2.5, nil, "expected an integer, got 2.5"},
+		{"boolean", dto.InputSpec{Name: "dry_run", Type: dto.InputTypeBoolean}, false, false, ""},
+		{"boolean from string", dto.InputSpec{Name: "dry_run", Type: dto.InputTypeBoolean}, "true", true, ""},
+		{"invalid boolean", dto.InputSpec{Name: "dry_run", Type: dto.InputTypeBoolean}, "maybe", nil, "expected a boolean, got 'maybe'"},
+		{"boolean from number", dto.InputSpec{Name: "dry_run", Type: dto.InputTypeBoolean}, float64(1), nil, "expected a boolean, got 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := coerceInput(tt.spec, tt.value)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, value)
+		})
+	}
+}
+
+func TestResolveRunInputs(t *testing.T) {
+	specs := []dto.InputSpec{
+		{Name: "environment", Required: true, Enum: []interface{}{"staging", "production"}},
+		{Name: "replicas", Type: dto.InputTypeInteger, Default: float64(2)},
+		{Name: "dry_run", Type: dto.InputTypeBoolean},
+	}
+
+	tests := []struct {
+		name     string
+		specs    []dto.InputSpec
+		supplied map[string]interface{}
+		expected map[string]interface{}
+		wantErr  string
+	}{
+		{
+			name:     "defaults filled in",
+			specs:    specs,
+			supplied: map[string]interface{}{"environment": "staging"},
+			expected: map[string]interface{}{"environment": "staging", "replicas": float64(2)},
+		},
+		{
+			name:     "supplied values converted",
+			specs:    specs,
+			supplied: map[string]interface{}{"environment": "production", "replicas": "5", "dry_run": "true"},
+			expected: map[string]interface{}{"environment": "production", "replicas": float64(5), "dry_run": true},
+		},
+		{
+			name:     "null takes the default",
+			specs:    specs,
+			supplied: map[string]interface{}{"environment": "staging", "replicas": nil},
+			expected: map[string]interface{}{"environment": "staging", "replicas": float64(2)},
+		},
+		{
+			name:     "no declarations",
+			supplied: map[string]interface{}{"anything": "goes", "count": float64(1)},
+			expected: map[string]interface{}{"anything": "goes", "count": float64(1)},
+		},
+		{
+			name:     "missing required input",
+			specs:    specs,
+			supplied: map[string]interface{}{},
+			wantErr:  "input 'environment' is required",
+		},
+		{
+			name:     "value not allowed",
+			specs:    specs,
+			supplied: map[string]interface{}{"environment": "dev"},
+			wantErr:  "input 'environment': value 'dev' is not one of staging, production",
+		},
+		{
+			name:     "undeclared inputs",
+			specs:    specs,
+			supplied: map[string]interface{}{"environment": "staging", "zone": "b", "region": "eu"},
+			wantErr:  "input 'region' is not declared by the job; input 'zone' is not declared by the job",
+		},
+		{
+			name:     "every problem reported",
+			specs:    specs,
+			supplied: map[string]interface{}{"replicas": 1.5},
+			wantErr:  "invalid inputs: input 'environment' is required; input 'replicas': expected an integer, got 1.5",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inputs, err := ResolveRunInputs(tt.specs, tt.supplied)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				assert.Nil(t, inputs)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, inputs)
+		})
+	}
+}
@@ -0,0 +1,255 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/b0nbon1/stratal/internal/logger"
+	"github.com/b0nbon1/stratal/internal/runner"
+	"github.com/b0nbon1/stratal/internal/storage/db/dto"
+	db "github.com/b0nbon1/stratal/internal/storage/db/sqlc"
+	"github.com/b0nbon1/stratal/pkg/tmpl"
+	"github.com/b0nbon1/stratal/pkg/utils"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	handlerOnFailure  = "on_failure"
+	handlerCompensate = "compensate"
+)
+
+// validateFailureHandlers checks the on_failure handlers of a job and its tasks and the
+// compensation steps of its tasks
+func validateFailureHandlers(cfg dto.JobConfig, tasks []db.Task) error {
+	for _, handler := range cfg.OnFailure {
+		if err := validateHandler("job", handler); err != nil {
+			return err
+		}
+	}
+	for _, task := range tasks {
+		for _, handler := range task.Config.OnFailure {
+			if err := validateHandler("task "+task.Name, handler); err != nil {
+				return err
+			}
+		}
+		if task.Config.Compensate != nil {
+			if err := validateHandler("task "+task.Name, *task.Config.Compensate); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateHandler checks a single handler, handlers run once and cannot wait or fan out
+func validateHandler(owner string, handler dto.HandlerTask) error {
+	if handler.Name == "" {
+		return fmt.Errorf("%s: handler has no name", owner)
+	}
+	switch handler.Type {
+	case "builtin":
+		name := handler.Config.Parameters["task_name"]
+		if !runner.IsBuiltinTask(name) || runner.IsSensor(name) {
+			return fmt.Errorf("%s: handler %s: unsupported builtin task '%s'", owner, handler.Name, name)
+		}
+	case "custom":
+		if err := runner.ValidateScript(handler.Config.Script); err != nil {
+			return fmt.Errorf("%s: handler %s: %w", owner, handler.Name, err)
+		}
+	case "job":
+	default:
+		return fmt.Errorf("%s: handler %s has unsupported type '%s', use builtin, custom or job", owner, handler.Name, handler.Type)
+	}
+	if handler.Config.ForEach != nil || handler.Config.Approval != nil {
+		return fmt.Errorf("%s: handler %s cannot fan out or wait for approval", owner, handler.Name)
+	}
+	if len(handler.Config.OnFailure) > 0 || handler.Config.Compensate != nil {
+		return fmt.Errorf("%s: handler %s cannot declare handlers of its own", owner, handler.Name)
+	}
+	return nil
+}
+
+// failureHandling runs the on_failure handlers and compensations of a job run. Each handler
+// is recorded as a task run of its own. A failing handler is logged and does not stop the
+// remaining ones, the job run fails with the original error either way.
+type failureHandling struct {
+	deps      Dependencies
+	jobRunID  pgtype.UUID
+	scope     runScope
+	jobLogger *logger.JobRunLogger
+}
+
+// failureDetails are the parameters describing a failure that every handler receives
+func failureDetails(taskName string, err error, outputs map[string]string) map[string]string {
+	encoded, _ := json.Marshal(outputs)
+	details := map[string]string{
+		"FAILED_TASK":     taskName,
+		"FAILURE_OUTPUTS": string(encoded),
+	}
+	if err != nil {
+		details["FAILURE_ERROR"] = err.Error()
+	}
+	return details
+}
+
+// onTaskFailure runs the on_failure handlers of a task that failed
+func (f *failureHandling) onTaskFailure(ctx context.Context, task db.Task, taskErr error, outputs map[string]string) {
+	if len(task.Config.OnFailure) == 0 || isCancelled(ctx) {
+		return
+	}
+	// an expired deadline must not keep the handlers from running
+	ctx = context.WithoutCancel(ctx)
+
+	var failedRunID pgtype.UUID
+	taskRun, err := f.deps.Store.GetTaskRunByJobRunAndTaskID(ctx, db.GetTaskRunByJobRunAndTaskIDParams{
+		JobRunID: f.jobRunID,
+		TaskID:   task.ID,
+	})
+	if err == nil {
+		failedRunID = taskRun.ID
+	}
+
+	details := failureDetails(task.Name, taskErr, outputs)
+	for _, handler := range task.Config.OnFailure {
+		f.runHandler(ctx, handlerOnFailure, handler, failedRunID, details, outputs)
+	}
+}
+
+// onJobFailure compensates the completed tasks of a failed job run, the task that completed
+// last first, and then runs the on_failure handlers of the job
+func (f *failureHandling) onJobFailure(ctx context.Context, cfg dto.JobConfig, tasks []db.Task, failedTask string, jobErr error, outputs map[string]string) {
+	if isCancelled(ctx) {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	details := failureDetails(failedTask, jobErr, outputs)
+
+	if hasCompensations(tasks) {
+		completed, err := f.deps.Store.ListCompensableTaskRuns(ctx, f.jobRunID)
+		if err != nil {
+			fmt.Printf("Failed to load completed task runs to compensate: %v\n", err)
+			if f.jobLogger != nil {
+				f.jobLogger.Error(fmt.Sprintf("Failed to load completed task runs to compensate: %v", err))
+			}
+		}
+		for _, step := range compensationSteps(tasks, completed, details) {
+			f.runHandler(ctx, handlerCompensate, step.handler, step.taskRunID, step.params, outputs)
+		}
+	}
+
+	for _, handler := range cfg.OnFailure {
+		f.runHandler(ctx, handlerOnFailure, handler, pgtype.UUID{}, details, outputs)
+	}
+}
+
+// compensationStep is the compensation of one completed task run
+type compensationStep struct {
+	handler   dto.HandlerTask
+	taskRunID pgtype.UUID
+	params    map[string]string
+}
+
+func hasCompensations(tasks []db.Task) bool {
+	for _, task := range tasks {
+		if task.Config.Compensate != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// compensationSteps returns the compensations of the completed task runs in the order
+// they are listed, the task run that completed last first. Tasks without a compensation
+// are passed over.
+func compensationSteps(tasks []db.Task, completed []db.ListCompensableTaskRunsRow, details map[string]string) []compensationStep {
+	tasksByID := make(map[string]db.Task, len(tasks))
+	for _, task := range tasks {
+		if task.Config.Compensate != nil {
+			tasksByID[task.ID.String()] = task
+		}
+	}
+
+	var steps []compensationStep
+	for _, taskRun := range completed {
+		task, ok := tasksByID[taskRun.TaskID.String()]
+		if !ok {
+			continue
+		}
+		params := make(map[string]string, len(details)+2)
+		for k, v := range details {
+			params[k] = v
+		}
+		params["COMPENSATED_TASK"] = task.Name
+		params["COMPENSATED_OUTPUT"] = taskRun.Output.String
+		steps = append(steps, compensationStep{handler: *task.Config.Compensate, taskRunID: taskRun.ID, params: params})
+	}
+	return steps
+}
+
+// runHandler records a handler as a new task run and executes it with the failure details
+// as parameters, parameters configured on the handler take precedence
+func (f *failureHandling) runHandler(ctx context.Context, kind string, handler dto.HandlerTask, handledRunID pgtype.UUID, details, outputs map[string]string) {
+	taskRunID, err := f.deps.Store.CreateHandlerTaskRun(ctx, db.CreateHandlerTaskRunParams{
+		JobRunID:           f.jobRunID,
+		Handler:            utils.ParseText(kind),
+		HandlerName:        utils.ParseText(handler.Name),
+		HandlerOfTaskRunID: handledRunID,
+	})
+	if err != nil {
+		fmt.Printf("Failed to create task run for %s handler %s: %v\n", kind, handler.Name, err)
+		if f.jobLogger != nil {
+			f.jobLogger.Error(fmt.Sprintf("Failed to create task run for %s handler %s: %v", kind, handler.Name, err))
+		}
+		return
+	}
+
+	// the job timeout does not bound handlers, their scripts always get the default one
+	task := withScriptTimeout(db.Task{Name: handler.Name, Type: handler.Type, Config: handler.Config}, dto.JobConfig{}, f.deps.ScriptTimeout)
+	params := make(map[string]string, len(details)+len(task.Config.Parameters))
+	for k, v := range details {
+		params[k] = tmpl.Escape(v)
+	}
+	for k, v := range task.Config.Parameters {
+		params[k] = v
+	}
+	task.Config.Parameters = params
+
+	fmt.Printf("Running %s handler %s\n", kind, handler.Name)
+	if f.jobLogger != nil {
+		f.jobLogger.InfoWithTaskRun(taskRunID.String(), fmt.Sprintf("Running %s handler %s", kind, handler.Name))
+	}
+
+	scope := f.scope
+	if env, err := loadExportedEnv(ctx, f.deps.Store, f.jobRunID); err == nil {
+		scope.vars = env
+	}
+	task, err = withRunInputs(task, scope)
+	if err == nil {
+		if f.deps.SecretManager != nil {
+			_, err = executeTaskRunWithSecrets(ctx, task, taskRunID, f.deps, secretOwnerID(), outputs, f.jobLogger)
+		} else {
+			_, err = executeTaskRunWithOutputs(ctx, task, taskRunID, outputs, f.deps, f.jobLogger)
+		}
+	} else {
+		finishTaskRun(ctx, f.deps.Store, taskRunID, "", pgtype.Int4{}, err, f.jobLogger)
+	}
+	if err != nil {
+		fmt.Printf("%s handler %s failed: %v\n", kind, handler.Name, err)
+		if f.jobLogger != nil {
+			f.jobLogger.ErrorWithTaskRun(taskRunID.String(), fmt.Sprintf("%s handler %s failed: %v", kind, handler.Name, err))
+		}
+	}
+}
+
+// withTaskHandlers runs the on_failure handlers of a task after exec failed it
+func (f *failureHandling) withTaskHandlers(exec taskExecFunc) taskExecFunc {
+	return func(ctx context.Context, task db.Task, outputs map[string]string) (string, error) {
+		output, err := exec(ctx, task, outputs)
+		if err != nil && !errors.Is(err, errTaskSkipped) && !errors.Is(err, errWaitingApproval) && !errors.Is(err, errSensorRescheduled) {
+			f.onTaskFailure(ctx, task, err, outputs)
+		}
+		return output, err
+	}
+}
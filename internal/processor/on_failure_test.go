@@ -0,0 +1,139 @@
+package processor
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/b0nbon1/stratal/internal/storage/db/dto"
+	db "github.com/b0nbon1/stratal/internal/storage/db/sqlc"
+	"github.com/b0nbon1/stratal/pkg/utils"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func notifyHandler(name string) dto.HandlerTask {
+	return dto.HandlerTask{Name: name, Type: "builtin", Config: dto.TaskConfig{Parameters: map[string]string{"task_name": "send_email"}}}
+}
+
+func TestValidateFailureHandlers(t *testing.T) {
+	script := &dto.ScriptConfig{Language: "bash", Code: "echo rollback"}
+
+	tests := []struct {
+		name    string
+		config  dto.JobConfig
+		tasks   []db.Task
+		wantErr string
+	}{
+		{
+			name:   "valid handlers",
+			config: dto.JobConfig{OnFailure: []dto.HandlerTask{notifyHandler("page")}},
+			tasks: []db.Task{{Name: "deploy", Config: dto.TaskConfig{
+				OnFailure:  []dto.HandlerTask{notifyHandler("notify")},
+				Compensate: &dto.HandlerTask{Name: "rollback", Type: "custom", Config: dto.TaskConfig{Script: script}},
+			}}},
+		},
+		{
+			name:    "handler without name",
+			config:  dto.JobConfig{OnFailure: []dto.HandlerTask{{Type: "job"}}},
+			wantErr: "job: handler has no name",
+		},
+		{
+			name:    "unknown builtin",
+			tasks:   []db.Task{{Name: "deploy", Config: dto.TaskConfig{OnFailure: []dto.HandlerTask{{Name: "x", Type: "builtin", Config: dto.TaskConfig{Parameters: map[string]string{"task_name": "nope"}}}}}}},
+			wantErr: "task deploy: handler x: unsupported builtin task 'nope'",
+		},
+		{
+			name:    "sensor handler",
+			tasks:   []db.Task{{Name: "deploy", Config: dto.TaskConfig{OnFailure: []dto.HandlerTask{{Name: "x", Type: "builtin", Config: dto.TaskConfig{Parameters: map[string]string{"task_name": "wait_file"}}}}}}},
+			wantErr: "unsupported builtin task 'wait_file'",
+		},
+		{
+			name:    "compensation without script",
+			tasks:   []db.Task{{Name: "deploy", Config: dto.TaskConfig{Compensate: &dto.HandlerTask{Name: "rollback", Type: "custom"}}}},
+			wantErr: "task deploy: handler rollback: script configuration is nil",
+		},
+		{
+			name:    "unsupported type",
+			config:  dto.JobConfig{OnFailure: []dto.HandlerTask{{Name: "gate", Type: "approval"}}},
+			wantErr: "handler gate has unsupported type 'approval'",
+		},
+		{
+			name: "fan-out handler",
+			config: dto.JobConfig{OnFailure: []dto.HandlerTask{{Name: "fan", Type: "job", Config: dto.TaskConfig{
+				ForEach: &dto.ForEachConfig{Matrix: map[string][]string{"A": {"1"}}},
+			}}}},
+			wantErr: "handler fan cannot fan out or wait for approval",
+		},
+		{
+			name: "nested handlers",
+			config: dto.JobConfig{OnFailure: []dto.HandlerTask{{Name: "outer", Type: "job", Config: dto.TaskConfig{
+				OnFailure: []dto.HandlerTask{notifyHandler("inner")},
+			}}}},
+			wantErr: "handler outer cannot declare handlers of its own",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateFailureHandlers(tt.config, tt.tasks)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestFailureDetails(t *testing.T) {
+	details := failureDetails("deploy", errors.New("exit status 1"), map[string]string{"build": "ok"})
+	assert.Equal(t, map[string]string{
+		"FAILED_TASK":     "deploy",
+		"FAILURE_ERROR":   "exit status 1",
+		"FAILURE_OUTPUTS": `{"build":"ok"}`,
+	}, details)
+
+	assert.NotContains(t, failureDetails("", nil, nil), "FAILURE_ERROR")
+}
+
+func TestCompensationSteps(t *testing.T) {
+	id := func(s string) pgtype.UUID {
+		uuid, err := utils.ParseUUID(s)
+		require.NoError(t, err)
+		return uuid
+	}
+	task := func(uuid, name string, compensate bool) db.Task {
+		task := db.Task{ID: id(uuid), Name: name}
+		if compensate {
+			task.Config.Compensate = &dto.HandlerTask{Name: "undo_" + name, Type: "job"}
+		}
+		return task
+	}
+	tasks := []db.Task{
+		task("00000000-0000-0000-0000-000000000001", "create_db", true),
+		task("00000000-0000-0000-0000-000000000002", "notify", false),
+		task("00000000-0000-0000-0000-000000000003", "deploy", true),
+	}
+	// listed the way ListCompensableTaskRuns returns them, the task run that finished last first
+	completed := []db.ListCompensableTaskRunsRow{
+		{ID: id("00000000-0000-0000-0000-00000000000c"), TaskID: tasks[2].ID, Output: utils.ParseText("v2")},
+		{ID: id("00000000-0000-0000-0000-00000000000b"), TaskID: tasks[1].ID, Output: utils.ParseText("sent")},
+		{ID: id("00000000-0000-0000-0000-00000000000a"), TaskID: tasks[0].ID, Output: utils.ParseText("db-1")},
+	}
+
+	steps := compensationSteps(tasks, completed, map[string]string{"FAILED_TASK": "smoke_test"})
+	require.Len(t, steps, 2)
+
+	assert.Equal(t, "undo_deploy", steps[0].handler.Name)
+	assert.Equal(t, completed[0].ID, steps[0].taskRunID)
+	assert.Equal(t, map[string]string{"FAILED_TASK": "smoke_test", "COMPENSATED_TASK": "deploy", "COMPENSATED_OUTPUT": "v2"}, steps[0].params)
+
+	assert.Equal(t, "undo_create_db", steps[1].handler.Name)
+	assert.Equal(t, completed[2].ID, steps[1].taskRunID)
+	assert.Equal(t, "db-1", steps[1].params["COMPENSATED_OUTPUT"])
+
+	assert.True(t, hasCompensations(tasks))
+	assert.False(t, hasCompensations(tasks[1:2]))
+}
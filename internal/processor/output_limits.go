@@ -0,0 +1,113 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/b0nbon1/stratal/internal/artifacts"
+	"github.com/b0nbon1/stratal/internal/logger"
+	"github.com/b0nbon1/stratal/internal/runner"
+	"github.com/b0nbon1/stratal/internal/storage/db/dto"
+	db "github.com/b0nbon1/stratal/internal/storage/db/sqlc"
+	"github.com/b0nbon1/stratal/pkg/utils"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// validateOutputLimits checks the max_output_size of a job and of its tasks
+func validateOutputLimits(config dto.JobConfig, tasks []db.Task) error {
+	if config.MaxOutputSize != "" {
+		if _, err := utils.ParseSize(config.MaxOutputSize); err != nil {
+			return fmt.Errorf("job max_output_size: %w", err)
+		}
+	}
+	for _, task := range tasks {
+		if task.Config.MaxOutputSize == "" {
+			continue
+		}
+		if _, err := utils.ParseSize(task.Config.MaxOutputSize); err != nil {
+			return fmt.Errorf("task %s max_output_size: %w", task.Name, err)
+		}
+	}
+	return nil
+}
+
+// withOutputLimit applies the max_output_size of the job to a task that sets none
+func withOutputLimit(task db.Task, config dto.JobConfig) db.Task {
+	if task.Config.MaxOutputSize == "" {
+		task.Config.MaxOutputSize = config.MaxOutputSize
+	}
+	return task
+}
+
+// taskOutputLimits returns the output limits of a task, the defaults with its max_output_size
+func taskOutputLimits(task db.Task, defaults runner.OutputLimits) (runner.OutputLimits, error) {
+	limits := defaults
+	if task.Config.MaxOutputSize != "" {
+		size, err := utils.ParseSize(task.Config.MaxOutputSize)
+		if err != nil {
+			return limits, fmt.Errorf("task %s max_output_size: %w", task.Name, err)
+		}
+		limits.MaxOutputSize = size
+	}
+	return limits, nil
+}
+
+// outputSpill stores the complete output of a task run in the output store and references
+// it from the task run
+type outputSpill struct {
+	store     *db.SQLStore
+	files     artifacts.Store
+	taskRunID pgtype.UUID
+	jobLogger *logger.JobRunLogger
+}
+
+// newOutputSpill returns nil when there is no output store
+func newOutputSpill(deps Dependencies, taskRunID pgtype.UUID, jobLogger *logger.JobRunLogger) runner.OutputSpill {
+	if deps.Outputs == nil {
+		return nil
+	}
+	return &outputSpill{store: deps.Store, files: deps.Outputs, taskRunID: taskRunID, jobLogger: jobLogger}
+}
+
+func (s *outputSpill) Spill(ctx context.Context, r io.Reader) error {
+	key := s.taskRunID.String()
+	size, err := s.files.Put(ctx, key, r)
+	if err != nil {
+		return err
+	}
+	if err := s.store.SetTaskRunOutputSpill(ctx, db.SetTaskRunOutputSpillParams{
+		ID:         s.taskRunID,
+		OutputKey:  utils.ParseText(key),
+		OutputSize: pgtype.Int8{Int64: size, Valid: true},
+	}); err != nil {
+		return fmt.Errorf("failed to reference complete output: %w", err)
+	}
+
+	if s.jobLogger != nil {
+		s.jobLogger.InfoWithTaskRun(s.taskRunID.String(), fmt.Sprintf("Output of %d bytes exceeds the inline limit, the complete output was stored", size))
+	}
+	return nil
+}
+
+// limitOutput truncates an output beyond the inline limit of its task. The complete output
+// is spilled to the output store when there is one.
+func limitOutput(ctx context.Context, deps Dependencies, task db.Task, taskRunID pgtype.UUID, output string, jobLogger *logger.JobRunLogger) (string, error) {
+	limits, err := taskOutputLimits(task, deps.OutputLimits)
+	if err != nil {
+		return "", err
+	}
+	if limits.MaxOutputSize <= 0 || int64(len(output)) <= limits.MaxOutputSize {
+		return output, nil
+	}
+
+	note := "the rest was discarded"
+	if spill := newOutputSpill(deps, taskRunID, jobLogger); spill != nil {
+		if err := spill.Spill(ctx, strings.NewReader(output)); err != nil {
+			return "", fmt.Errorf("failed to store complete output of task %s: %w", task.Name, err)
+		}
+		note = "the complete output is stored with the task run"
+	}
+	return runner.TruncateOutput(output, limits.MaxOutputSize, int64(len(output)), note), nil
+}
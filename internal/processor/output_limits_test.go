@@ -0,0 +1,48 @@
+package processor
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/b0nbon1/stratal/internal/runner"
+	"github.com/b0nbon1/stratal/internal/storage/db/dto"
+	db "github.com/b0nbon1/stratal/internal/storage/db/sqlc"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskOutputLimits(t *testing.T) {
+	defaults := runner.OutputLimits{MaxOutputSize: 1 << 20, MaxLogSize: 10 << 20}
+
+	limits, err := taskOutputLimits(db.Task{Name: "build"}, defaults)
+	require.NoError(t, err)
+	assert.Equal(t, defaults, limits)
+
+	limits, err = taskOutputLimits(db.Task{Name: "build", Config: dto.TaskConfig{MaxOutputSize: "2KB"}}, defaults)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2048), limits.MaxOutputSize)
+	assert.Equal(t, defaults.MaxLogSize, limits.MaxLogSize)
+
+	_, err = taskOutputLimits(db.Task{Name: "build", Config: dto.TaskConfig{MaxOutputSize: "lots"}}, defaults)
+	assert.ErrorContains(t, err, "task build max_output_size")
+}
+
+func TestLimitOutput_WithoutOutputStore(t *testing.T) {
+	deps := Dependencies{OutputLimits: runner.OutputLimits{MaxOutputSize: 10}}
+	task := db.Task{Name: "build"}
+
+	output, err := limitOutput(context.Background(), deps, task, pgtype.UUID{}, "short", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "short", output)
+
+	output, err = limitOutput(context.Background(), deps, task, pgtype.UUID{}, strings.Repeat("x", 25), nil)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(output, strings.Repeat("x", 10)+"\n"))
+	assert.Contains(t, output, "10 of 25 bytes shown, the rest was discarded")
+
+	output, err = limitOutput(context.Background(), Dependencies{}, task, pgtype.UUID{}, strings.Repeat("x", 25), nil)
+	require.NoError(t, err)
+	assert.Equal(t, strings.Repeat("x", 25), output, "zero limits leave outputs unbounded")
+}
@@ -3,11 +3,11 @@ package processor
 import (
 	"context"
 	"fmt"
-	"regexp"
-	"strings"
+	"sort"
 
 	"github.com/b0nbon1/stratal/internal/security"
 	db "github.com/b0nbon1/stratal/internal/storage/db/sqlc"
+	"github.com/b0nbon1/stratal/pkg/tmpl"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
@@ -30,13 +30,12 @@ func (pr *ParameterResolver) ResolveParameters(
 	taskOutputs map[string]string,
 ) (map[string]string, map[string]string, error) {
 
-	resolvedParams := make(map[string]string)
 	secretEnvVars := make(map[string]string)
 
-	// 1. Copy regular parameters and resolve ${TASK_OUTPUT.task_name} references
-	for key, value := range task.Config.Parameters {
-		resolvedValue := pr.resolveTaskOutputReferences(value, taskOutputs)
-		resolvedParams[key] = resolvedValue
+	// 1. Copy regular parameters and render ${outputs.task_name} and ${secrets.name} references
+	resolvedParams, err := pr.resolveParameters(ctx, userID, task.Config.Parameters, taskOutputs)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	// 2. Resolve secrets
@@ -60,27 +59,86 @@ func (pr *ParameterResolver) ResolveParameters(
 	return resolvedParams, secretEnvVars, nil
 }
 
-// resolveTaskOutputReferences replaces ${TASK_OUTPUT.task_name} and ${task_name.output} with actual values
-func (pr *ParameterResolver) resolveTaskOutputReferences(value string, taskOutputs map[string]string) string {
-	// Handle ${TASK_OUTPUT.task_name} pattern
-	re1 := regexp.MustCompile(`\$\{TASK_OUTPUT\.([^}]+)\}`)
-	value = re1.ReplaceAllStringFunc(value, func(match string) string {
-		taskName := strings.TrimPrefix(strings.TrimSuffix(match, "}"), "${TASK_OUTPUT.")
-		if output, exists := taskOutputs[taskName]; exists {
-			return output
+// maskedSecret replaces secret values in plans
+const maskedSecret = "********"
+
+// PlanParameters resolves the parameters and secrets of a task without running it. Task
+// output references are only checked against the tasks that run before it, since outputs
+// are known at run time. Secrets are looked up and decrypted but their values are masked.
+// Every problem found is returned instead of stopping at the first one.
+func (pr *ParameterResolver) PlanParameters(
+	ctx context.Context,
+	task db.Task,
+	userID pgtype.UUID,
+	earlierTasks map[string]bool,
+) (map[string]string, map[string]string, []string) {
+	var problems []string
+
+	params := make(map[string]string, len(task.Config.Parameters))
+	referencedSecrets := make(map[string]bool)
+	for key, value := range task.Config.Parameters {
+		params[key] = value
+		t := tmpl.Parse(value)
+		for _, taskName := range outputReferences(t) {
+			if !earlierTasks[taskName] {
+				problems = append(problems, fmt.Sprintf("parameter '%s': cannot resolve output of task '%s': it does not run before this task", key, taskName))
+			}
+		}
+		for _, ref := range t.References() {
+			if ref.Root == "secrets" && len(ref.Path) > 0 {
+				if name, ok := ref.Path[0].(string); ok {
+					referencedSecrets[name] = true
+				}
+			}
+		}
+	}
+	for name := range referencedSecrets {
+		if _, err := pr.store.GetSecretByName(ctx, db.GetSecretByNameParams{
+			Name:   name,
+			UserID: userID,
+		}); err != nil {
+			problems = append(problems, fmt.Sprintf("secret '%s' not found: %v", name, err))
+		}
+	}
+
+	secretEnvVars := make(map[string]string, len(task.Config.Secrets))
+	for secretName, envVarName := range task.Config.Secrets {
+		secret, err := pr.store.GetSecretByName(ctx, db.GetSecretByNameParams{
+			Name:   secretName,
+			UserID: userID,
+		})
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("secret '%s' not found: %v", secretName, err))
+			continue
 		}
-		return match // Keep original if not found
-	})
-
-	// Handle ${task_name.output} pattern
-	re2 := regexp.MustCompile(`\$\{([^}]+)\.output\}`)
-	value = re2.ReplaceAllStringFunc(value, func(match string) string {
-		taskName := strings.TrimSuffix(strings.TrimPrefix(match, "${"), ".output}")
-		if output, exists := taskOutputs[taskName]; exists {
-			return output
+		if pr.secretManager != nil {
+			if _, err := pr.secretManager.Decrypt(secret.EncryptedValue); err != nil {
+				problems = append(problems, fmt.Sprintf("failed to decrypt secret '%s': %v", secretName, err))
+				continue
+			}
 		}
-		return match // Keep original if not found
-	})
+		secretEnvVars[envVarName] = maskedSecret
+	}
 
-	return value
+	sort.Strings(problems)
+	return params, secretEnvVars, problems
+}
+
+// resolveParameters copies parameters with their outputs and secrets rendered
+func (pr *ParameterResolver) resolveParameters(ctx context.Context, userID pgtype.UUID, parameters map[string]string, taskOutputs map[string]string) (map[string]string, error) {
+	data, opts := outputData(ctx, pr.store, pr.secretManager, userID, taskOutputs)
+	return renderParameters(parameters, data, opts)
+}
+
+// renderParameters renders every parameter against the given namespaces
+func renderParameters(parameters map[string]string, data map[string]interface{}, opts tmpl.Options) (map[string]string, error) {
+	resolvedParams := make(map[string]string, len(parameters))
+	for key, value := range parameters {
+		resolvedValue, err := tmpl.Render(value, data, opts)
+		if err != nil {
+			return nil, fmt.Errorf("parameter '%s': %w", key, err)
+		}
+		resolvedParams[key] = resolvedValue
+	}
+	return resolvedParams, nil
 }
@@ -0,0 +1,181 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/b0nbon1/stratal/internal/runner"
+	"github.com/b0nbon1/stratal/internal/security"
+	db "github.com/b0nbon1/stratal/internal/storage/db/sqlc"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Plan describes how a job run would execute, computed without running any task
+type Plan struct {
+	JobID    string                 `json:"job_id"`
+	JobName  string                 `json:"job_name"`
+	Valid    bool                   `json:"valid"`
+	Inputs   map[string]interface{} `json:"inputs"`
+	Levels   []PlanLevel            `json:"levels"`
+	Problems []string               `json:"problems"` // problems of the job as a whole, such as invalid inputs or a dependency cycle
+}
+
+// PlanLevel is a group of tasks that would run in parallel
+type PlanLevel struct {
+	Level int        `json:"level"`
+	Tasks []PlanTask `json:"tasks"`
+}
+
+// PlanTask is a task with its parameters resolved as far as possible before the run.
+// Task output references stay in place and secret values are masked.
+type PlanTask struct {
+	Name       string            `json:"name"`
+	Type       string            `json:"type"`
+	DependsOn  []string          `json:"depends_on,omitempty"`
+	When       string            `json:"when,omitempty"`
+	Parameters map[string]string `json:"parameters"`
+	Secrets    map[string]string `json:"secrets"` // env var name -> masked value
+	Problems   []string          `json:"problems"`
+}
+
+// PlanJob validates a job and computes its execution plan for the given inputs: the
+// execution levels, every task's parameters and secrets, and all problems that would make
+// the run fail, like missing secrets, unresolvable references, unknown builtin tasks or
+// unsupported script languages. Nothing is executed.
+func PlanJob(ctx context.Context, store *db.SQLStore, secretManager *security.SecretManager, job db.GetJobWithTasksRow, supplied map[string]interface{}) (*Plan, error) {
+	var tasks []db.Task
+	if err := json.Unmarshal(job.Tasks, &tasks); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal tasks: %w", err)
+	}
+
+	plan := &Plan{
+		JobID:    job.ID.String(),
+		JobName:  job.Name,
+		Levels:   []PlanLevel{},
+		Problems: []string{},
+	}
+
+	inputs, err := ResolveRunInputs(job.Config.Inputs, supplied)
+	if err != nil {
+		plan.Problems = append(plan.Problems, err.Error())
+		inputs = supplied
+	}
+	plan.Inputs = inputs
+	// a plan has no run yet, run.id and run.triggered_by render empty. The API server has no
+	// script sandbox, env reads its environment like a worker without one.
+	scope := newRunScope(pgtype.UUID{}, job, "", inputs, runner.Sandbox{})
+
+	for _, problem := range validateJob(job.Config, tasks) {
+		plan.Problems = append(plan.Problems, problem.Error())
+	}
+
+	levels, err := buildTaskLevels(tasks)
+	if err != nil {
+		plan.Problems = append(plan.Problems, fmt.Sprintf("failed to sort tasks: %v", err))
+		return plan, nil
+	}
+
+	resolver := NewParameterResolver(store, secretManager)
+	userID := secretOwnerID()
+	earlierTasks := make(map[string]bool)
+	valid := len(plan.Problems) == 0
+	for _, level := range levels {
+		planLevel := PlanLevel{Level: level.Level}
+		for _, task := range level.Tasks {
+			planTask := planTask(ctx, store, resolver, task, scope, userID, earlierTasks)
+			if len(planTask.Problems) > 0 {
+				valid = false
+			}
+			planLevel.Tasks = append(planLevel.Tasks, planTask)
+		}
+		// tasks of a level only see the outputs of earlier levels
+		for _, task := range level.Tasks {
+			earlierTasks[task.Name] = true
+		}
+		plan.Levels = append(plan.Levels, planLevel)
+	}
+	plan.Valid = valid
+
+	return plan, nil
+}
+
+// planTask resolves a single task for a plan and collects its problems
+func planTask(ctx context.Context, store *db.SQLStore, resolver *ParameterResolver, task db.Task, scope runScope, userID pgtype.UUID, earlierTasks map[string]bool) PlanTask {
+	var problems []string
+
+	resolved, err := withRunInputs(task, scope)
+	if err != nil {
+		problems = append(problems, err.Error())
+		resolved = task
+	}
+	params, secrets, paramProblems := resolver.PlanParameters(ctx, resolved, userID, earlierTasks)
+	problems = append(problems, paramProblems...)
+
+	switch task.Type {
+	case "builtin":
+		name := params["task_name"]
+		if name == "" {
+			problems = append(problems, "builtin task has no task_name parameter")
+		} else if !runner.IsBuiltinTask(name) {
+			problems = append(problems, fmt.Sprintf("unknown builtin task: %s", name))
+		} else if isSensorTask(task) {
+			if _, _, err := sensorSchedule(task); err != nil {
+				problems = append(problems, err.Error())
+			}
+		}
+	case "custom":
+		if err := runner.ValidateScript(task.Config.Script); err != nil {
+			problems = append(problems, err.Error())
+		}
+	case "job":
+		if task.Config.Job == nil || (task.Config.Job.JobID == "" && task.Config.Job.JobName == "") {
+			problems = append(problems, fmt.Sprintf("job task %s has no job_id or job_name configured", task.Name))
+		} else if childJob, err := lookupSubJob(ctx, store, task); err != nil {
+			problems = append(problems, err.Error())
+		} else if err := checkSubJob(ctx, store, childJob, map[string]bool{}); err != nil {
+			problems = append(problems, err.Error())
+		}
+	case "approval":
+		if cfg := task.Config.Approval; cfg != nil && cfg.Expiry != "" {
+			if err := checkTimeout(cfg.Expiry); err != nil {
+				problems = append(problems, fmt.Sprintf("approval task %s has an invalid expiry '%s'", task.Name, cfg.Expiry))
+			}
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("unsupported task type: %s", task.Type))
+	}
+
+	if err := checkTimeout(task.Config.Timeout); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if _, err := newRetryPolicy(task.Config.Retry); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if _, err := cacheTTL(task); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	if problems == nil {
+		problems = []string{}
+	}
+	return PlanTask{
+		Name:       task.Name,
+		Type:       task.Type,
+		DependsOn:  task.Config.DependsOn,
+		When:       task.Config.When,
+		Parameters: params,
+		Secrets:    secrets,
+		Problems:   problems,
+	}
+}
+
+// checkTimeout validates a timeout without starting a deadline
+func checkTimeout(timeout string) error {
+	_, cancel, err := withTimeout(context.Background(), timeout)
+	if err != nil {
+		return err
+	}
+	cancel()
+	return nil
+}
@@ -0,0 +1,142 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/b0nbon1/stratal/internal/security"
+	"github.com/b0nbon1/stratal/internal/storage/db/dto"
+	db "github.com/b0nbon1/stratal/internal/storage/db/sqlc"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// planJob returns a stored job, its tasks get IDs in their order
+func planJob(t *testing.T, config dto.JobConfig, tasks []db.Task) db.GetJobWithTasksRow {
+	for i := range tasks {
+		tasks[i].ID = testUUID(byte(10 + i))
+	}
+	encoded, err := json.Marshal(tasks)
+	require.NoError(t, err)
+	return db.GetJobWithTasksRow{ID: testUUID(7), Name: "nightly-report", Config: config, Tasks: encoded}
+}
+
+// newSecretStore serves the given secrets encrypted by secretManager
+func newSecretStore(t *testing.T, secretManager *security.SecretManager, secrets map[string]string) (*db.SQLStore, *fakeDB) {
+	var rows [][]interface{}
+	for name, value := range secrets {
+		encrypted, err := secretManager.Encrypt(value)
+		require.NoError(t, err)
+		rows = append(rows, []interface{}{testUUID(50), secretOwnerID(), name, encrypted, pgtype.Timestamptz{}})
+	}
+	store, fake := newFakeStore(map[string][][]interface{}{"GetSecretByName": rows})
+	fake.match = func(query string, args []interface{}, row []interface{}) bool {
+		return row[2] == args[0]
+	}
+	return store, fake
+}
+
+func TestPlanJob(t *testing.T) {
+	secretManager, err := security.NewSecretManager("0123456789abcdef0123456789abcdef")
+	require.NoError(t, err)
+	store, fake := newSecretStore(t, secretManager, map[string]string{"api_token": "s3cr3t"})
+
+	job := planJob(t, dto.JobConfig{Inputs: []dto.InputSpec{{Name: "region", Default: "eu-west-1"}}}, []db.Task{
+		{Name: "fetch", Type: "builtin", Config: dto.TaskConfig{
+			Parameters: map[string]string{"task_name": "http_request", "url": "https://api.example.com/${inputs.region}/report"},
+			Secrets:    map[string]string{"api_token": "API_TOKEN"},
+		}},
+		{Name: "teleport", Type: "builtin", Config: dto.TaskConfig{Parameters: map[string]string{"task_name": "teleport"}}},
+		{Name: "analyze", Type: "custom", Config: dto.TaskConfig{Script: &dto.ScriptConfig{Language: "cobol", Code: "DISPLAY 'HI'."}}},
+		{Name: "report", Type: "builtin", Config: dto.TaskConfig{
+			DependsOn:  []string{"fetch"},
+			Parameters: map[string]string{"task_name": "format_output", "template": "${fetch.output} ${archive.output} ${secrets.smtp_password}"},
+		}},
+		{Name: "archive", Type: "custom", Config: dto.TaskConfig{
+			DependsOn: []string{"analyze"},
+			Script:    &dto.ScriptConfig{Language: "bash", Code: "tar czf report.tgz out/"},
+		}},
+	})
+
+	plan, err := PlanJob(context.Background(), store, secretManager, job, nil)
+	require.NoError(t, err)
+
+	assert.False(t, plan.Valid)
+	assert.Empty(t, plan.Problems)
+	assert.Equal(t, map[string]interface{}{"region": "eu-west-1"}, plan.Inputs)
+	require.Len(t, plan.Levels, 2)
+
+	tasks := make(map[string]PlanTask)
+	var levels [][]string
+	for _, level := range plan.Levels {
+		var names []string
+		for _, task := range level.Tasks {
+			tasks[task.Name] = task
+			names = append(names, task.Name)
+		}
+		levels = append(levels, names)
+	}
+	assert.ElementsMatch(t, []string{"fetch", "teleport", "analyze"}, levels[0])
+	assert.ElementsMatch(t, []string{"report", "archive"}, levels[1])
+
+	fetch := tasks["fetch"]
+	assert.Empty(t, fetch.Problems)
+	assert.Equal(t, "https://api.example.com/eu-west-1/report", fetch.Parameters["url"])
+	assert.Equal(t, map[string]string{"API_TOKEN": maskedSecret}, fetch.Secrets, "secret values are masked")
+
+	assert.Equal(t, []string{"unknown builtin task: teleport"}, tasks["teleport"].Problems)
+	assert.Equal(t, []string{"unsupported script language: cobol"}, tasks["analyze"].Problems)
+
+	report := tasks["report"]
+	assert.Equal(t, "${fetch.output} ${archive.output} ${secrets.smtp_password}", report.Parameters["template"], "output references stay in place")
+	require.Len(t, report.Problems, 2)
+	assert.Contains(t, report.Problems[0], "cannot resolve output of task 'archive': it does not run before this task")
+	assert.Contains(t, report.Problems[1], "secret 'smtp_password' not found")
+
+	for _, query := range fake.queries {
+		assert.Equal(t, "GetSecretByName", query, "a plan only reads secrets")
+	}
+}
+
+func TestPlanJob_Valid(t *testing.T) {
+	store, _ := newFakeStore(nil)
+	job := planJob(t, dto.JobConfig{}, []db.Task{
+		{Name: "build", Type: "custom", Config: dto.TaskConfig{Script: &dto.ScriptConfig{Language: "bash", Code: "make"}}},
+		{Name: "notify", Type: "builtin", Config: dto.TaskConfig{
+			DependsOn:  []string{"build"},
+			Parameters: map[string]string{"task_name": "format_output", "template": "built ${build.output}"},
+		}},
+	})
+
+	plan, err := PlanJob(context.Background(), store, nil, job, nil)
+	require.NoError(t, err)
+
+	assert.True(t, plan.Valid)
+	assert.Empty(t, plan.Problems)
+	require.Len(t, plan.Levels, 2)
+	assert.Equal(t, "build", plan.Levels[0].Tasks[0].Name)
+	assert.Equal(t, "notify", plan.Levels[1].Tasks[0].Name)
+	assert.Equal(t, []string{"build"}, plan.Levels[1].Tasks[0].DependsOn)
+}
+
+func TestPlanJob_JobProblems(t *testing.T) {
+	store, _ := newFakeStore(nil)
+	job := planJob(t, dto.JobConfig{Inputs: []dto.InputSpec{{Name: "version", Required: true}}}, []db.Task{
+		{Name: "a", Type: "custom", Config: dto.TaskConfig{DependsOn: []string{"b"}, Script: &dto.ScriptConfig{Language: "bash", Code: "true"}}},
+		{Name: "b", Type: "custom", Config: dto.TaskConfig{DependsOn: []string{"a"}, Script: &dto.ScriptConfig{Language: "bash", Code: "true"}}},
+	})
+
+	plan, err := PlanJob(context.Background(), store, nil, job, nil)
+	require.NoError(t, err)
+
+	assert.False(t, plan.Valid)
+	assert.Empty(t, plan.Levels, "a job whose tasks cannot be sorted has no levels")
+	require.NotEmpty(t, plan.Problems)
+	assert.Contains(t, plan.Problems[0], "version")
+	assert.Contains(t, plan.Problems[len(plan.Problems)-1], "failed to sort tasks")
+
+	_, err = PlanJob(context.Background(), store, nil, db.GetJobWithTasksRow{Tasks: []byte("not json")}, nil)
+	assert.ErrorContains(t, err, "failed to unmarshal tasks")
+}
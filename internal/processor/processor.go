@@ -3,12 +3,11 @@ package processor
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	// "sync"
-	// "time"
+	"strings"
 
 	"github.com/b0nbon1/stratal/internal/logger"
-	"github.com/b0nbon1/stratal/internal/security"
 	db "github.com/b0nbon1/stratal/internal/storage/db/sqlc"
 	"github.com/b0nbon1/stratal/pkg/utils"
 	"github.com/jackc/pgx/v5/pgtype"
@@ -28,7 +27,8 @@ type TaskLevel struct {
 	Tasks []db.Task
 }
 
-func ProcessJob(ctx context.Context, store *db.SQLStore, secretManager *security.SecretManager, jobRunID pgtype.UUID, job db.GetJobWithTasksRow, jobLogger *logger.JobRunLogger) error {
+func ProcessJob(ctx context.Context, deps Dependencies, jobRunID pgtype.UUID, job db.GetJobWithTasksRow, jobLogger *logger.JobRunLogger) error {
+	store, secretManager := deps.Store, deps.SecretManager
 	fmt.Printf("Processing job run: %s for job: %s\n", jobRunID.String(), job.ID.String())
 
 	if jobLogger != nil {
@@ -50,10 +50,7 @@ func ProcessJob(ctx context.Context, store *db.SQLStore, secretManager *security
 	// Parse tasks from JSON
 	var tasks []db.Task
 	if err := json.Unmarshal(job.Tasks, &tasks); err != nil {
-		if jobLogger != nil {
-			jobLogger.Error(fmt.Sprintf("Failed to unmarshal tasks: %v", err))
-		}
-		return fmt.Errorf("failed to unmarshal tasks: %w", err)
+		return failJobRun(ctx, store, jobRunID, "failed", fmt.Sprintf("Failed to unmarshal tasks: %v", err), fmt.Errorf("failed to unmarshal tasks: %w", err), jobLogger)
 	}
 
 	if len(tasks) == 0 {
@@ -64,30 +61,88 @@ func ProcessJob(ctx context.Context, store *db.SQLStore, secretManager *security
 		return completeJobRun(ctx, store, jobRunID, "completed", jobLogger)
 	}
 
-	// Sort tasks based on dependencies
-	sortedTasks, err := utils.TopoSort(tasks)
+	// Group tasks into dependency levels, tasks in the same level run in parallel
+	levels, err := buildTaskLevels(tasks)
 	if err != nil {
-		if jobLogger != nil {
-			jobLogger.Error(fmt.Sprintf("Failed to sort tasks: %v", err))
-		}
-		return fmt.Errorf("failed to sort tasks: %w", err)
+		return failJobRun(ctx, store, jobRunID, "failed", fmt.Sprintf("Failed to sort tasks: %v", err), fmt.Errorf("failed to sort tasks: %w", err), jobLogger)
 	}
 
-	// Execute tasks in dependency order
-	taskOutputs := make(map[string]string)
+	if problems := validateJob(job.Config, tasks); len(problems) > 0 {
+		err := errors.Join(problems...)
+		return failJobRun(ctx, store, jobRunID, "failed", fmt.Sprintf("Job has an invalid configuration: %v", err), err, jobLogger)
+	}
+
+	// Execute tasks level by level
+	taskOutputs := newTaskOutputStore()
 	taskNameToID := make(map[string]string)
+	tasksByName := make(map[string]db.Task)
 
 	// Build task name to ID mapping
-	for _, task := range sortedTasks {
+	for _, task := range tasks {
 		taskNameToID[task.Name] = task.ID.String()
+		tasksByName[task.Name] = task
 	}
 
-	// For now, use a dummy user ID for secret resolution
-	userID := pgtype.UUID{}
-	userID.Scan("00000000-0000-0000-0000-000000000001")
+	jobRun, err := store.GetJobRun(ctx, jobRunID)
+	if err != nil {
+		if jobLogger != nil {
+			jobLogger.Error(fmt.Sprintf("Failed to load job run: %v", err))
+		}
+		return fmt.Errorf("failed to load job run: %w", err)
+	}
+	supplied := runInputs(jobRun.Metadata)
+	inputs, err := ResolveRunInputs(job.Config.Inputs, supplied)
+	if err != nil {
+		// the failure handlers of a run with invalid inputs see the inputs as supplied
+		handling := &failureHandling{
+			deps:      deps,
+			jobRunID:  jobRunID,
+			scope:     newRunScope(jobRunID, job, jobRun.TriggeredBy.String, supplied, deps.Sandbox),
+			jobLogger: jobLogger,
+		}
+		handling.onJobFailure(ctx, job.Config, tasks, "", err, nil)
+		return failJobRun(ctx, store, jobRunID, "failed", fmt.Sprintf("Job run has invalid inputs: %v", err), err, jobLogger)
+	}
+	scope := newRunScope(jobRunID, job, jobRun.TriggeredBy.String, inputs, deps.Sandbox)
+	handling := &failureHandling{
+		deps:      deps,
+		jobRunID:  jobRunID,
+		scope:     scope,
+		jobLogger: jobLogger,
+	}
+
+	// Reload tasks completed by an earlier attempt of this run so they are not executed again
+	completedTasks, err := loadCompletedTasks(ctx, store, jobRunID, taskOutputs)
+	if err != nil {
+		if jobLogger != nil {
+			jobLogger.Error(fmt.Sprintf("Failed to load task runs: %v", err))
+		}
+		return fmt.Errorf("failed to load task runs: %w", err)
+	}
+	if len(completedTasks) > 0 {
+		fmt.Printf("Resuming job run %s, %d task(s) already completed\n", jobRunID.String(), len(completedTasks))
+		if jobLogger != nil {
+			jobLogger.Info(fmt.Sprintf("Resuming job run, %d task(s) already completed", len(completedTasks)))
+		}
+	}
+
+	// The job level timeout is the deadline for all remaining tasks of the run
+	runCtx, cancelRun, err := withTimeout(ctx, job.Config.Timeout)
+	if err != nil {
+		return failJobRun(ctx, store, jobRunID, "failed", fmt.Sprintf("Invalid job configuration: %v", err), fmt.Errorf("invalid job configuration: %w", err), jobLogger)
+	}
+	defer cancelRun()
 
-	for _, task := range sortedTasks {
-		// Check if job run has been paused before executing next task
+	userID := secretOwnerID()
+
+	var failedTasks []TaskOutput
+	for _, level := range levels {
+		level = pendingLevelTasks(level, completedTasks)
+		if len(level.Tasks) == 0 {
+			continue
+		}
+
+		// Check if job run has been paused before executing next level
 		currentJobRun, checkErr := store.GetJobRun(ctx, jobRunID)
 		if checkErr != nil {
 			if jobLogger != nil {
@@ -103,45 +158,179 @@ func ProcessJob(ctx context.Context, store *db.SQLStore, secretManager *security
 			return nil // Exit gracefully without error
 		}
 
-		fmt.Printf("Executing task: %s (type: %s)\n", task.Name, task.Type)
+		// A cancellation whose signal did not reach this worker is noticed between levels
+		if currentJobRun.Status.String == "cancelled" {
+			cancelJobRun(ctx, store, jobRunID, "Job run has been cancelled, stopping execution", jobLogger)
+			return nil
+		}
+
+		fmt.Printf("Executing level %d with %d task(s)\n", level.Level, len(level.Tasks))
 		if jobLogger != nil {
-			jobLogger.Info(fmt.Sprintf("Executing task: %s (type: %s)", task.Name, task.Type))
+			jobLogger.Info(fmt.Sprintf("Executing level %d with %d task(s)", level.Level, len(level.Tasks)))
 		}
 
-		var output string
-		var err error
+		execTask := func(ctx context.Context, task db.Task, outputs map[string]string) (string, error) {
+			reason, err := skipReason(task, tasksByName, taskOutputs, conditionVars(outputs, inputs, deps.Sandbox))
+			if err != nil {
+				if jobLogger != nil {
+					jobLogger.Error(err.Error())
+				}
+				return "", err
+			}
+			if reason != "" {
+				if err := skipTaskRun(ctx, store, jobRunID, task, reason, jobLogger); err != nil {
+					return "", err
+				}
+				return "", errTaskSkipped
+			}
 
-		if secretManager != nil {
-			output, err = ExecuteTaskWithSecrets(ctx, task, store, secretManager, userID, taskOutputs, jobRunID, jobLogger)
-		} else {
-			output, err = ExecuteTaskWithOutputs(ctx, task, taskOutputs, taskNameToID, jobRunID, store, jobLogger)
-		}
+			if task.Type == "approval" {
+				return awaitApproval(ctx, store, jobRunID, task, jobLogger)
+			}
+
+			env, err := loadExportedEnv(ctx, store, jobRunID)
+			if err != nil {
+				if jobLogger != nil {
+					jobLogger.Error(err.Error())
+				}
+				return "", err
+			}
+			task = withExportedEnv(task, env)
+			taskScope := scope
+			taskScope.vars = env
+			task, err = withRunInputs(task, taskScope)
+			if err != nil {
+				if jobLogger != nil {
+					jobLogger.Error(err.Error())
+				}
+				return "", err
+			}
+			task = withOutputLimit(task, job.Config)
+			task = withResources(task, job.Config)
+			task = withScriptTimeout(task, job.Config, deps.ScriptTimeout)
 
-		if err != nil {
-			fmt.Printf("Task %s failed: %v\n", task.Name, err)
+			fmt.Printf("Executing task: %s (type: %s)\n", task.Name, task.Type)
 			if jobLogger != nil {
-				jobLogger.Error(fmt.Sprintf("Task %s failed: %v", task.Name, err))
-			}
-			// Mark job run as failed
-			failErr := store.UpdateJobRunError(ctx, db.UpdateJobRunErrorParams{
-				ID:           jobRunID,
-				ErrorMessage: utils.ParseText(fmt.Sprintf("Task %s failed: %v", task.Name, err)),
-			})
-			if failErr != nil {
-				fmt.Printf("Failed to update job run error: %v\n", failErr)
+				jobLogger.Info(fmt.Sprintf("Executing task: %s (type: %s)", task.Name, task.Type))
+			}
+
+			if isSensorTask(task) && task.Config.ForEach == nil {
+				return runSensor(ctx, deps, jobRunID, task, outputs, jobLogger)
+			}
+
+			if task.Config.ForEach != nil {
+				return executeFanOut(ctx, deps, jobRunID, task, outputs, func(ctx context.Context, task db.Task, taskRunID pgtype.UUID, outputs map[string]string) (string, error) {
+					if secretManager != nil {
+						return executeTaskRunWithSecrets(ctx, task, taskRunID, deps, userID, outputs, jobLogger)
+					}
+					return executeTaskRunWithOutputs(ctx, task, taskRunID, outputs, deps, jobLogger)
+				}, jobLogger)
+			}
+			run := func() (string, error) {
+				if secretManager != nil {
+					return ExecuteTaskWithSecrets(ctx, task, deps, userID, outputs, jobRunID, jobLogger)
+				}
+				return ExecuteTaskWithOutputs(ctx, task, outputs, taskNameToID, jobRunID, deps, jobLogger)
+			}
+			if task.Config.Cache != nil {
+				return runCached(ctx, store, jobRunID, task, outputs, jobLogger, run)
+			}
+			return run()
+		}
+
+		handled := handling.withTaskHandlers(execTask)
+		execTask = func(ctx context.Context, task db.Task, outputs map[string]string) (string, error) {
+			output, err := handled(ctx, task, outputs)
+			if err == nil {
+				fmt.Printf("Task %s completed successfully\n", task.Name)
 				if jobLogger != nil {
-					jobLogger.Error(fmt.Sprintf("Failed to update job run error: %v", failErr))
+					jobLogger.Info(fmt.Sprintf("Task %s completed successfully", task.Name))
 				}
 			}
-			return err
+			return output, err
 		}
 
-		// Store task output for future tasks
-		taskOutputs[task.Name] = output
-		fmt.Printf("Task %s completed successfully\n", task.Name)
-		if jobLogger != nil {
-			jobLogger.Info(fmt.Sprintf("Task %s completed successfully", task.Name))
+		failures, waiting := executeLevel(runCtx, level, execTask, taskOutputs)
+
+		// Approval tasks and rescheduled sensors park the run, the worker does not wait for them
+		for len(waiting) > 0 && runCtx.Err() == nil {
+			parked, err := parkJobRun(ctx, store, jobRunID, jobLogger)
+			if err != nil {
+				if jobLogger != nil {
+					jobLogger.Error(err.Error())
+				}
+				return err
+			}
+			if parked {
+				return nil
+			}
+
+			// a run that was paused or cancelled meanwhile is not parked, it stops here
+			currentJobRun, err := store.GetJobRun(ctx, jobRunID)
+			if err != nil {
+				return fmt.Errorf("failed to check job run status: %w", err)
+			}
+			if currentJobRun.Status.String != "running" {
+				if jobLogger != nil {
+					jobLogger.Info(fmt.Sprintf("Job run is %s, stopping execution", currentJobRun.Status.String))
+				}
+				return nil
+			}
+
+			// every approval was decided while the level was running, continue right away
+			var decided []TaskOutput
+			decided, waiting = executeLevel(runCtx, TaskLevel{Level: level.Level, Tasks: waiting}, execTask, taskOutputs)
+			failures = append(failures, decided...)
+		}
+
+		for _, failure := range failures {
+			if tasksByName[failure.TaskName].Config.AllowFailure {
+				fmt.Printf("Task %s failed but is allowed to fail: %v\n", failure.TaskName, failure.Error)
+				if jobLogger != nil {
+					jobLogger.Info(fmt.Sprintf("Task %s failed but is allowed to fail: %v", failure.TaskName, failure.Error))
+				}
+				continue
+			}
+			failedTasks = append(failedTasks, failure)
 		}
+
+		// A cancelled or expired run stops here, tasks that never started are skipped
+		if runCtx.Err() != nil {
+			if isCancelled(runCtx) {
+				cancelJobRun(ctx, store, jobRunID, fmt.Sprintf("Job run cancelled during level %d", level.Level), jobLogger)
+				return nil
+			}
+
+			status := "failed"
+			message := fmt.Sprintf("Job run cancelled during level %d: %v", level.Level, runCtx.Err())
+			if isTimeout(runCtx.Err()) {
+				status = "timed_out"
+				message = fmt.Sprintf("Job run timed out after %s during level %d", job.Config.Timeout, level.Level)
+				if len(failures) > 0 {
+					message = fmt.Sprintf("Job run timed out after %s while running task %s", job.Config.Timeout, failures[0].TaskName)
+				}
+			}
+			failedTask := ""
+			if len(failures) > 0 {
+				failedTask = failures[0].TaskName
+			}
+			handling.onJobFailure(runCtx, job.Config, tasks, failedTask, errors.New(message), taskOutputs.Snapshot())
+			return failJobRun(ctx, store, jobRunID, status, message, runCtx.Err(), jobLogger)
+		}
+	}
+
+	// The final status covers the whole DAG, only failures that are not allowed fail the run
+	if len(failedTasks) > 0 {
+		names := make([]string, 0, len(failedTasks))
+		for _, failure := range failedTasks {
+			names = append(names, failure.TaskName)
+		}
+		message := fmt.Sprintf("Task %s failed: %v", failedTasks[0].TaskName, failedTasks[0].Error)
+		if len(failedTasks) > 1 {
+			message = fmt.Sprintf("%d tasks failed (%s), first error: %v", len(failedTasks), strings.Join(names, ", "), failedTasks[0].Error)
+		}
+		handling.onJobFailure(ctx, job.Config, tasks, failedTasks[0].TaskName, failedTasks[0].Error, taskOutputs.Snapshot())
+		return failJobRun(ctx, store, jobRunID, "failed", message, failedTasks[0].Error, jobLogger)
 	}
 
 	fmt.Println("All tasks completed successfully")
@@ -151,8 +340,43 @@ func ProcessJob(ctx context.Context, store *db.SQLStore, secretManager *security
 	return completeJobRun(ctx, store, jobRunID, "completed", jobLogger)
 }
 
+// secretOwnerID returns the user secrets are resolved for.
+// For now, use a dummy user ID for secret resolution
+func secretOwnerID() pgtype.UUID {
+	userID := pgtype.UUID{}
+	userID.Scan("00000000-0000-0000-0000-000000000001")
+	return userID
+}
+
+// failJobRun records the error of a job run, skips the task runs that never started and
+// marks the run with the given final status. It returns err so callers can pass it on.
+func failJobRun(ctx context.Context, store *db.SQLStore, jobRunID pgtype.UUID, status, message string, err error, jobLogger *logger.JobRunLogger) error {
+	fmt.Println(message)
+	if jobLogger != nil {
+		jobLogger.Error(message)
+	}
+
+	// the run context may already be cancelled, the final state still has to be stored
+	ctx = context.WithoutCancel(ctx)
+	failErr := store.UpdateJobRunError(ctx, db.UpdateJobRunErrorParams{
+		ID:           jobRunID,
+		ErrorMessage: utils.ParseText(message),
+	})
+	if failErr != nil {
+		fmt.Printf("Failed to update job run error: %v\n", failErr)
+		if jobLogger != nil {
+			jobLogger.Error(fmt.Sprintf("Failed to update job run error: %v", failErr))
+		}
+	}
+	skipPendingTaskRuns(ctx, store, jobRunID, jobLogger)
+	if failErr := completeJobRun(ctx, store, jobRunID, status, jobLogger); failErr != nil {
+		fmt.Printf("Failed to mark job run as %s: %v\n", status, failErr)
+	}
+	return err
+}
+
 func completeJobRun(ctx context.Context, store *db.SQLStore, jobRunID pgtype.UUID, status string, jobLogger *logger.JobRunLogger) error {
-	err := store.UpdateJobRunStatus(ctx, db.UpdateJobRunStatusParams{
+	err := store.FinishJobRun(ctx, db.FinishJobRunParams{
 		ID:     jobRunID,
 		Status: utils.ParseText(status),
 	})
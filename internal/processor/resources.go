@@ -0,0 +1,103 @@
+package processor
+
+import (
+	"fmt"
+
+	"github.com/b0nbon1/stratal/internal/runner"
+	"github.com/b0nbon1/stratal/internal/storage/db/dto"
+	db "github.com/b0nbon1/stratal/internal/storage/db/sqlc"
+	"github.com/b0nbon1/stratal/pkg/utils"
+)
+
+// validateResources checks the default resource limits of a job and the limits of its tasks
+func validateResources(config dto.JobConfig, tasks []db.Task) error {
+	if config.Resources != nil {
+		if _, err := parseResources(*config.Resources); err != nil {
+			return fmt.Errorf("job resources %w", err)
+		}
+	}
+	for _, task := range tasks {
+		if task.Config.Resources == nil {
+			continue
+		}
+		if task.Type != "custom" {
+			return fmt.Errorf("task %s: only custom tasks can have resource limits", task.Name)
+		}
+		if _, err := parseResources(*task.Config.Resources); err != nil {
+			return fmt.Errorf("task %s resources %w", task.Name, err)
+		}
+	}
+	return nil
+}
+
+// withResources applies the default resource limits of the job to a custom task, limit by
+// limit where the task sets none
+func withResources(task db.Task, config dto.JobConfig) db.Task {
+	if task.Type != "custom" || config.Resources == nil {
+		return task
+	}
+	merged := *config.Resources
+	if own := task.Config.Resources; own != nil {
+		if own.Memory != "" {
+			merged.Memory = own.Memory
+		}
+		if own.CPU != 0 {
+			merged.CPU = own.CPU
+		}
+		if own.MaxProcesses != 0 {
+			merged.MaxProcesses = own.MaxProcesses
+		}
+		if own.MaxOpenFiles != 0 {
+			merged.MaxOpenFiles = own.MaxOpenFiles
+		}
+		if own.Disk != "" {
+			merged.Disk = own.Disk
+		}
+	}
+	task.Config.Resources = &merged
+	return task
+}
+
+// taskResources returns the resource limits of the script of a task, none when it sets none
+func taskResources(task db.Task) (runner.Resources, error) {
+	if task.Config.Resources == nil {
+		return runner.Resources{}, nil
+	}
+	resources, err := parseResources(*task.Config.Resources)
+	if err != nil {
+		return resources, fmt.Errorf("task %s resources %w", task.Name, err)
+	}
+	return resources, nil
+}
+
+// parseResources converts configured resource limits into the limits of the runner
+func parseResources(cfg dto.ResourcesConfig) (runner.Resources, error) {
+	var resources runner.Resources
+	if cfg.Memory != "" {
+		size, err := utils.ParseSize(cfg.Memory)
+		if err != nil {
+			return resources, fmt.Errorf("memory: %w", err)
+		}
+		resources.Memory = size
+	}
+	if cfg.Disk != "" {
+		size, err := utils.ParseSize(cfg.Disk)
+		if err != nil {
+			return resources, fmt.Errorf("disk: %w", err)
+		}
+		resources.Disk = size
+	}
+	if cfg.CPU < 0 {
+		return resources, fmt.Errorf("cpu must not be negative")
+	}
+	if cfg.MaxProcesses < 0 {
+		return resources, fmt.Errorf("max_processes must not be negative")
+	}
+	if cfg.MaxOpenFiles < 0 {
+		return resources, fmt.Errorf("max_open_files must not be negative")
+	}
+	resources.CPU = cfg.CPU
+	resources.MaxProcesses = cfg.MaxProcesses
+	resources.MaxOpenFiles = cfg.MaxOpenFiles
+	return resources, nil
+}
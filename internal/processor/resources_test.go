@@ -0,0 +1,115 @@
+package processor
+
+import (
+	"testing"
+
+	"github.com/b0nbon1/stratal/internal/runner"
+	"github.com/b0nbon1/stratal/internal/storage/db/dto"
+	db "github.com/b0nbon1/stratal/internal/storage/db/sqlc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func customTask(resources *dto.ResourcesConfig) db.Task {
+	return db.Task{Name: "build", Type: "custom", Config: dto.TaskConfig{Resources: resources}}
+}
+
+func TestTaskResources(t *testing.T) {
+	jobDefaults := dto.JobConfig{Resources: &dto.ResourcesConfig{Memory: "1GB", CPU: 2, MaxOpenFiles: 1024}}
+
+	tests := []struct {
+		name     string
+		task     db.Task
+		config   dto.JobConfig
+		expected runner.Resources
+	}{
+		{
+			name:     "no limits",
+			task:     customTask(nil),
+			expected: runner.Resources{},
+		},
+		{
+			name:     "task limits",
+			task:     customTask(&dto.ResourcesConfig{Memory: "512MB", CPU: 0.5, MaxProcesses: 64, MaxOpenFiles: 256, Disk: "1GiB"}),
+			expected: runner.Resources{Memory: 512 << 20, CPU: 0.5, MaxProcesses: 64, MaxOpenFiles: 256, Disk: 1 << 30},
+		},
+		{
+			name:     "job defaults",
+			task:     customTask(nil),
+			config:   jobDefaults,
+			expected: runner.Resources{Memory: 1 << 30, CPU: 2, MaxOpenFiles: 1024},
+		},
+		{
+			name:     "task overrides job defaults limit by limit",
+			task:     customTask(&dto.ResourcesConfig{Memory: "256MB", MaxProcesses: 16}),
+			config:   jobDefaults,
+			expected: runner.Resources{Memory: 256 << 20, CPU: 2, MaxProcesses: 16, MaxOpenFiles: 1024},
+		},
+		{
+			name:     "job defaults skip builtin tasks",
+			task:     db.Task{Name: "notify", Type: "builtin"},
+			config:   jobDefaults,
+			expected: runner.Resources{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resources, err := taskResources(withResources(tt.task, tt.config))
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, resources)
+		})
+	}
+}
+
+func TestWithResources_KeepsJobDefaults(t *testing.T) {
+	config := dto.JobConfig{Resources: &dto.ResourcesConfig{Memory: "1GB"}}
+	withResources(customTask(&dto.ResourcesConfig{Memory: "256MB"}), config)
+	assert.Equal(t, "1GB", config.Resources.Memory)
+}
+
+func TestValidateResources(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  dto.JobConfig
+		tasks   []db.Task
+		wantErr string
+	}{
+		{
+			name:  "valid",
+			tasks: []db.Task{customTask(&dto.ResourcesConfig{Memory: "512MB", Disk: "1GB"})},
+		},
+		{
+			name:    "invalid task memory",
+			tasks:   []db.Task{customTask(&dto.ResourcesConfig{Memory: "lots"})},
+			wantErr: "task build resources memory",
+		},
+		{
+			name:    "negative cpu",
+			tasks:   []db.Task{customTask(&dto.ResourcesConfig{CPU: -1})},
+			wantErr: "cpu must not be negative",
+		},
+		{
+			name:    "invalid job disk",
+			config:  dto.JobConfig{Resources: &dto.ResourcesConfig{Disk: "1XB"}},
+			wantErr: "job resources disk",
+		},
+		{
+			name:    "builtin task",
+			tasks:   []db.Task{{Name: "notify", Type: "builtin", Config: dto.TaskConfig{Resources: &dto.ResourcesConfig{Memory: "1GB"}}}},
+			wantErr: "only custom tasks can have resource limits",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateResources(tt.config, tt.tasks)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package processor
+
+import (
+	"context"
+
+	db "github.com/b0nbon1/stratal/internal/storage/db/sqlc"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// loadCompletedTasks finds the task runs of a job run that already completed or were
+// skipped and restores their stored outputs and statuses, so a resumed run continues
+// where it stopped. It returns the IDs of those tasks.
+func loadCompletedTasks(ctx context.Context, store *db.SQLStore, jobRunID pgtype.UUID, outputs *taskOutputStore) (map[string]bool, error) {
+	taskRuns, err := store.ListTaskRunsWithTaskName(ctx, jobRunID)
+	if err != nil {
+		return nil, err
+	}
+
+	completed := make(map[string]bool)
+	for _, taskRun := range taskRuns {
+		status := taskRun.Status.String
+		switch status {
+		case "completed", "cached":
+			// dependents see a cached task as completed
+			status = "completed"
+			outputs.Set(taskRun.TaskName, taskRun.Output.String)
+		case "skipped":
+		default:
+			continue
+		}
+		completed[taskRun.TaskID.String()] = true
+		outputs.SetStatus(taskRun.TaskName, status)
+	}
+	return completed, nil
+}
+
+// pendingLevelTasks drops the tasks of a level that already completed or were skipped
+func pendingLevelTasks(level TaskLevel, completed map[string]bool) TaskLevel {
+	if len(completed) == 0 {
+		return level
+	}
+
+	pending := TaskLevel{Level: level.Level}
+	for _, task := range level.Tasks {
+		if !completed[task.ID.String()] {
+			pending.Tasks = append(pending.Tasks, task)
+		}
+	}
+	return pending
+}
@@ -0,0 +1,66 @@
+package processor
+
+import (
+	"context"
+	"testing"
+
+	db "github.com/b0nbon1/stratal/internal/storage/db/sqlc"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func taskRunRow(taskID pgtype.UUID, name, status, output string) []interface{} {
+	return []interface{}{
+		testUUID(100 + taskID.Bytes[0]),
+		taskID,
+		name,
+		pgtype.Text{String: status, Valid: true},
+		pgtype.Text{String: output, Valid: output != ""},
+	}
+}
+
+func TestLoadCompletedTasks(t *testing.T) {
+	build, test, lint, deploy, notify := testUUID(1), testUUID(2), testUUID(3), testUUID(4), testUUID(5)
+	store, fake := newFakeStore(map[string][][]interface{}{
+		"ListTaskRunsWithTaskName": {
+			taskRunRow(build, "build", "completed", "v1.2.3"),
+			taskRunRow(test, "test", "cached", "42 passed"),
+			taskRunRow(lint, "lint", "skipped", ""),
+			taskRunRow(deploy, "deploy", "running", ""),
+			taskRunRow(notify, "notify", "failed", "smtp error"),
+		},
+	})
+	outputs := newTaskOutputStore()
+
+	completed, err := loadCompletedTasks(context.Background(), store, testUUID(9), outputs)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]bool{build.String(): true, test.String(): true, lint.String(): true}, completed)
+	assert.Equal(t, map[string]string{"build": "v1.2.3", "test": "42 passed"}, outputs.Snapshot())
+	assert.Equal(t, "completed", outputs.Status("build"))
+	assert.Equal(t, "completed", outputs.Status("test"), "dependents see a cached task as completed")
+	assert.Equal(t, "skipped", outputs.Status("lint"))
+	assert.Empty(t, outputs.Status("deploy"))
+	assert.Empty(t, outputs.Status("notify"))
+
+	level := TaskLevel{Level: 0, Tasks: []db.Task{
+		{ID: build, Name: "build"}, {ID: test, Name: "test"}, {ID: lint, Name: "lint"}, {ID: deploy, Name: "deploy"}, {ID: notify, Name: "notify"},
+	}}
+	assert.Equal(t, []db.Task{{ID: deploy, Name: "deploy"}, {ID: notify, Name: "notify"}}, pendingLevelTasks(level, completed).Tasks,
+		"only tasks that did not complete run again")
+
+	// fan-out instances share the task of their parent, only parent runs decide what is done
+	require.Equal(t, []string{"ListTaskRunsWithTaskName"}, fake.queries)
+	assert.Contains(t, fake.statements[0], "parent_task_run_id IS NULL")
+	assert.Equal(t, []interface{}{testUUID(9)}, fake.args[0])
+}
+
+func TestPendingLevelTasks(t *testing.T) {
+	build, test, lint := db.Task{ID: testUUID(1), Name: "build"}, db.Task{ID: testUUID(2), Name: "test"}, db.Task{ID: testUUID(3), Name: "lint"}
+	level := TaskLevel{Level: 1, Tasks: []db.Task{build, test, lint}}
+
+	assert.Equal(t, level, pendingLevelTasks(level, nil), "a fresh run keeps every task")
+	assert.Equal(t, TaskLevel{Level: 1, Tasks: []db.Task{test}}, pendingLevelTasks(level, map[string]bool{build.ID.String(): true, lint.ID.String(): true}))
+	assert.Equal(t, TaskLevel{Level: 1}, pendingLevelTasks(level, map[string]bool{build.ID.String(): true, test.ID.String(): true, lint.ID.String(): true}))
+}
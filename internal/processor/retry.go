@@ -0,0 +1,152 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/b0nbon1/stratal/internal/logger"
+	"github.com/b0nbon1/stratal/internal/runner"
+	"github.com/b0nbon1/stratal/internal/storage/db/dto"
+	db "github.com/b0nbon1/stratal/internal/storage/db/sqlc"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	defaultRetryInitialDelay = time.Second
+	defaultRetryMultiplier   = 2.0
+	defaultRetryMaxDelay     = 5 * time.Minute
+)
+
+// retryPolicy is the parsed form of a task's dto.RetryConfig
+type retryPolicy struct {
+	maxAttempts   int
+	initialDelay  time.Duration
+	multiplier    float64
+	maxDelay      time.Duration
+	exitCodes     map[int]bool
+	errorPatterns []*regexp.Regexp
+}
+
+// newRetryPolicy parses a retry config, a nil config runs the task exactly once
+func newRetryPolicy(cfg *dto.RetryConfig) (*retryPolicy, error) {
+	policy := &retryPolicy{
+		maxAttempts:  1,
+		initialDelay: defaultRetryInitialDelay,
+		multiplier:   defaultRetryMultiplier,
+		maxDelay:     defaultRetryMaxDelay,
+	}
+	if cfg == nil {
+		return policy, nil
+	}
+
+	if cfg.MaxAttempts > 1 {
+		policy.maxAttempts = cfg.MaxAttempts
+	}
+	if cfg.InitialDelay != "" {
+		delay, err := time.ParseDuration(cfg.InitialDelay)
+		if err != nil {
+			return nil, fmt.Errorf("invalid retry initial_delay '%s': %w", cfg.InitialDelay, err)
+		}
+		policy.initialDelay = delay
+	}
+	if cfg.MaxDelay != "" {
+		delay, err := time.ParseDuration(cfg.MaxDelay)
+		if err != nil {
+			return nil, fmt.Errorf("invalid retry max_delay '%s': %w", cfg.MaxDelay, err)
+		}
+		policy.maxDelay = delay
+	}
+	if cfg.Multiplier >= 1 {
+		policy.multiplier = cfg.Multiplier
+	}
+
+	if len(cfg.RetryOnExitCodes) > 0 {
+		policy.exitCodes = make(map[int]bool)
+		for _, code := range cfg.RetryOnExitCodes {
+			policy.exitCodes[code] = true
+		}
+	}
+	for _, pattern := range cfg.RetryOnErrors {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid retry_on_errors pattern '%s': %w", pattern, err)
+		}
+		policy.errorPatterns = append(policy.errorPatterns, re)
+	}
+
+	return policy, nil
+}
+
+// shouldRetry reports whether a failed attempt is retried. Without exit code or
+// error filters every failure is retried, with filters one of them has to match.
+func (p *retryPolicy) shouldRetry(attempt int, err error) bool {
+	if attempt >= p.maxAttempts || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if p.exitCodes == nil && p.errorPatterns == nil {
+		return true
+	}
+
+	var scriptErr *runner.ScriptError
+	if errors.As(err, &scriptErr) && p.exitCodes[scriptErr.ExitCode] {
+		return true
+	}
+	for _, re := range p.errorPatterns {
+		if re.MatchString(err.Error()) {
+			return true
+		}
+	}
+	return false
+}
+
+// delay returns the backoff before the attempt following the given one
+func (p *retryPolicy) delay(attempt int) time.Duration {
+	delay := float64(p.initialDelay)
+	for i := 1; i < attempt; i++ {
+		delay *= p.multiplier
+		if delay >= float64(p.maxDelay) {
+			return p.maxDelay
+		}
+	}
+	return time.Duration(delay)
+}
+
+// runWithRetry runs a task until it succeeds or its retry policy gives up. Every
+// attempt moves the task run back to running with the attempt number recorded.
+func runWithRetry(ctx context.Context, store *db.SQLStore, task db.Task, taskRunID pgtype.UUID, jobLogger *logger.JobRunLogger, run func() (string, error)) (string, error) {
+	policy, err := newRetryPolicy(task.Config.Retry)
+	if err != nil {
+		markTaskRunRunning(ctx, store, taskRunID, 1, jobLogger)
+		return "", fmt.Errorf("task %s has an invalid retry configuration: %w", task.Name, err)
+	}
+
+	for attempt := 1; ; attempt++ {
+		markTaskRunRunning(ctx, store, taskRunID, attempt, jobLogger)
+		if policy.maxAttempts > 1 && jobLogger != nil {
+			jobLogger.InfoWithTaskRun(taskRunID.String(), fmt.Sprintf("Attempt %d/%d of task %s", attempt, policy.maxAttempts, task.Name))
+		}
+
+		output, err := run()
+		// a cancelled or expired job run is not retried, a timed out attempt may be
+		if err == nil || ctx.Err() != nil || !policy.shouldRetry(attempt, err) {
+			return output, err
+		}
+
+		wait := policy.delay(attempt)
+		fmt.Printf("Task %s attempt %d/%d failed, retrying in %s: %v\n", task.Name, attempt, policy.maxAttempts, wait, err)
+		if jobLogger != nil {
+			jobLogger.LogTask(taskRunID.String(), logger.WarnLevel,
+				fmt.Sprintf("Attempt %d/%d of task %s failed, retrying in %s: %v", attempt, policy.maxAttempts, task.Name, wait, err),
+				"stderr", map[string]interface{}{"attempt": attempt, "max_attempts": policy.maxAttempts})
+		}
+
+		select {
+		case <-ctx.Done():
+			return output, err
+		case <-time.After(wait):
+		}
+	}
+}
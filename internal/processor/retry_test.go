@@ -0,0 +1,97 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/b0nbon1/stratal/internal/runner"
+	"github.com/b0nbon1/stratal/internal/storage/db/dto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRetryPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     *dto.RetryConfig
+		wantErr string
+	}{
+		{"no config", nil, ""},
+		{"valid", &dto.RetryConfig{MaxAttempts: 3, InitialDelay: "2s", MaxDelay: "1m", RetryOnErrors: []string{"timeout"}}, ""},
+		{"invalid initial delay", &dto.RetryConfig{MaxAttempts: 3, InitialDelay: "soon"}, "invalid retry initial_delay 'soon'"},
+		{"invalid max delay", &dto.RetryConfig{MaxAttempts: 3, MaxDelay: "10"}, "invalid retry max_delay '10'"},
+		{"invalid pattern", &dto.RetryConfig{MaxAttempts: 3, RetryOnErrors: []string{"("}}, "invalid retry_on_errors pattern '('"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newRetryPolicy(tt.cfg)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      *dto.RetryConfig
+		attempt  int
+		expected time.Duration
+	}{
+		{"defaults first retry", &dto.RetryConfig{MaxAttempts: 5}, 1, time.Second},
+		{"defaults third retry", &dto.RetryConfig{MaxAttempts: 5}, 3, 4 * time.Second},
+		{"defaults capped", &dto.RetryConfig{MaxAttempts: 20}, 12, 5 * time.Minute},
+		{"custom backoff", &dto.RetryConfig{MaxAttempts: 5, InitialDelay: "500ms", Multiplier: 3}, 3, 4500 * time.Millisecond},
+		{"custom cap", &dto.RetryConfig{MaxAttempts: 5, InitialDelay: "10s", MaxDelay: "25s"}, 3, 25 * time.Second},
+		{"multiplier below one keeps the default", &dto.RetryConfig{MaxAttempts: 5, Multiplier: 0.5}, 2, 2 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := newRetryPolicy(tt.cfg)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, policy.delay(tt.attempt))
+		})
+	}
+}
+
+func TestRetryPolicyShouldRetry(t *testing.T) {
+	exit := func(code int) error {
+		return &runner.ScriptError{ExitCode: code, Err: fmt.Errorf("exit status %d", code)}
+	}
+
+	tests := []struct {
+		name     string
+		cfg      *dto.RetryConfig
+		attempt  int
+		err      error
+		expected bool
+	}{
+		{"no config runs once", nil, 1, exit(1), false},
+		{"any failure", &dto.RetryConfig{MaxAttempts: 3}, 1, exit(1), true},
+		{"attempts exhausted", &dto.RetryConfig{MaxAttempts: 3}, 3, exit(1), false},
+		{"cancelled", &dto.RetryConfig{MaxAttempts: 3}, 1, fmt.Errorf("task stopped: %w", context.Canceled), false},
+		{"matching exit code", &dto.RetryConfig{MaxAttempts: 3, RetryOnExitCodes: []int{75}}, 1, exit(75), true},
+		{"other exit code", &dto.RetryConfig{MaxAttempts: 3, RetryOnExitCodes: []int{75}}, 1, exit(1), false},
+		{"exit code filter on a non script error", &dto.RetryConfig{MaxAttempts: 3, RetryOnExitCodes: []int{1}}, 1, errors.New("connection refused"), false},
+		{"matching error", &dto.RetryConfig{MaxAttempts: 3, RetryOnErrors: []string{"connection (refused|reset)"}}, 1, errors.New("dial tcp: connection reset by peer"), true},
+		{"other error", &dto.RetryConfig{MaxAttempts: 3, RetryOnErrors: []string{"connection refused"}}, 1, errors.New("invalid credentials"), false},
+		{"either filter", &dto.RetryConfig{MaxAttempts: 3, RetryOnExitCodes: []int{75}, RetryOnErrors: []string{"exit status 1"}}, 1, exit(1), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := newRetryPolicy(tt.cfg)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, policy.shouldRetry(tt.attempt, tt.err))
+		})
+	}
+}
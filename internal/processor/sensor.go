@@ -0,0 +1,153 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/b0nbon1/stratal/internal/logger"
+	"github.com/b0nbon1/stratal/internal/runner"
+	"github.com/b0nbon1/stratal/internal/security"
+	db "github.com/b0nbon1/stratal/internal/storage/db/sqlc"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// errSensorRescheduled is returned by a taskExecFunc when a sensor checks its condition again later
+var errSensorRescheduled = errors.New("sensor rescheduled")
+
+const (
+	// defaultPokeInterval is the time between two checks of a sensor condition
+	defaultPokeInterval = 30 * time.Second
+	// defaultSensorTimeout bounds how long a sensor waits when its task has no timeout
+	defaultSensorTimeout = 24 * time.Hour
+)
+
+// isSensorTask reports whether a task is a builtin sensor
+func isSensorTask(task db.Task) bool {
+	return task.Type == "builtin" && runner.IsSensor(task.Config.Parameters["task_name"])
+}
+
+// runSensor checks the condition of a sensor task once. When it does not hold the task run
+// moves to up_for_reschedule and the run is parked without holding a worker, the scheduler
+// re-queues it once the next check is due. The task timeout bounds the total time waited
+// since the first check, a sensor that runs out of it times out.
+func runSensor(ctx context.Context, deps Dependencies, jobRunID pgtype.UUID, task db.Task, outputs map[string]string, jobLogger *logger.JobRunLogger) (string, error) {
+	store := deps.Store
+	taskRun, err := store.GetTaskRunByJobRunAndTaskID(ctx, db.GetTaskRunByJobRunAndTaskIDParams{
+		JobRunID: jobRunID,
+		TaskID:   task.ID,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to find task run for task %s: %w", task.Name, err)
+	}
+	taskRunID := taskRun.ID.String()
+
+	sensor, err := store.GetTaskRunSensor(ctx, taskRun.ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to load sensor state of task %s: %w", task.Name, err)
+	}
+	// another task of the run was due first, this one keeps waiting
+	if sensor.Status.String == "up_for_reschedule" && !sensor.Due {
+		return "", errSensorRescheduled
+	}
+
+	pokeInterval, timeout, err := sensorSchedule(task)
+	if err != nil {
+		finishTaskRun(ctx, store, taskRun.ID, "", pgtype.Int4{}, err, jobLogger)
+		return "", err
+	}
+
+	params, err := resolveSensorParameters(ctx, store, deps.SecretManager, task, outputs)
+	if err != nil {
+		err = fmt.Errorf("failed to resolve parameters for task %s: %w", task.Name, err)
+		finishTaskRun(ctx, store, taskRun.ID, "", pgtype.Int4{}, err, jobLogger)
+		return "", err
+	}
+
+	poke, err := store.StartSensorPoke(ctx, taskRun.ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to mark sensor %s as running: %w", task.Name, err)
+	}
+	remaining := timeout - time.Duration(poke.WaitedSeconds*float64(time.Second))
+
+	pokeCtx, cancel := context.WithTimeout(ctx, remaining)
+	defer cancel()
+	met, output, err := runner.PokeSensor(pokeCtx, params["task_name"], params, deps.Sensors)
+	if err == nil && !met && pokeCtx.Err() != nil {
+		err = fmt.Errorf("sensor %s timed out after %s: %s: %w", task.Name, timeout, output, context.DeadlineExceeded)
+		if ctx.Err() != nil {
+			err = fmt.Errorf("sensor %s: %w", task.Name, ctx.Err())
+		}
+	}
+	if err != nil || met {
+		if met && jobLogger != nil {
+			jobLogger.InfoWithTaskRun(taskRunID, fmt.Sprintf("Sensor %s condition met after %d check(s)", task.Name, poke.SensorPokes))
+		}
+		finishTaskRun(ctx, store, taskRun.ID, output, pgtype.Int4{}, err, jobLogger)
+		return output, err
+	}
+
+	next := nextPoke(pokeInterval, remaining)
+	if err := store.RescheduleSensor(ctx, db.RescheduleSensorParams{
+		ID:              taskRun.ID,
+		IntervalSeconds: next.Seconds(),
+	}); err != nil {
+		return "", fmt.Errorf("failed to reschedule sensor %s: %w", task.Name, err)
+	}
+
+	message := fmt.Sprintf("Sensor %s condition not met (%s), checking again in %s", task.Name, output, next.Round(time.Second))
+	fmt.Println(message)
+	if jobLogger != nil {
+		jobLogger.InfoWithTaskRun(taskRunID, message)
+	}
+	return "", errSensorRescheduled
+}
+
+// nextPoke returns the time until the next check of a sensor. The last check happens at the
+// deadline so the sensor times out instead of waiting beyond it.
+func nextPoke(pokeInterval, remaining time.Duration) time.Duration {
+	if remaining < pokeInterval {
+		return remaining
+	}
+	return pokeInterval
+}
+
+// sensorSchedule reads the poke_interval parameter and the timeout of a sensor task
+func sensorSchedule(task db.Task) (time.Duration, time.Duration, error) {
+	pokeInterval := defaultPokeInterval
+	if raw := task.Config.Parameters["poke_interval"]; raw != "" {
+		interval, err := time.ParseDuration(raw)
+		if err != nil || interval <= 0 {
+			return 0, 0, fmt.Errorf("sensor %s has an invalid poke_interval '%s'", task.Name, raw)
+		}
+		pokeInterval = interval
+	}
+
+	timeout := defaultSensorTimeout
+	if task.Config.Timeout != "" {
+		duration, err := time.ParseDuration(task.Config.Timeout)
+		if err != nil || duration <= 0 {
+			return 0, 0, fmt.Errorf("sensor %s has an invalid timeout '%s'", task.Name, task.Config.Timeout)
+		}
+		timeout = duration
+	}
+	return pokeInterval, timeout, nil
+}
+
+// resolveSensorParameters resolves task output references and, with a secret manager, the
+// secrets of a sensor, which builtin tasks receive as parameters
+func resolveSensorParameters(ctx context.Context, store *db.SQLStore, secretManager *security.SecretManager, task db.Task, outputs map[string]string) (map[string]string, error) {
+	if secretManager == nil {
+		return (&ParameterResolver{}).resolveParameters(ctx, pgtype.UUID{}, task.Config.Parameters, outputs)
+	}
+
+	params, secretEnvVars, err := NewParameterResolver(store, secretManager).ResolveParameters(ctx, task, secretOwnerID(), outputs)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range secretEnvVars {
+		params[key] = value
+	}
+	return params, nil
+}
@@ -0,0 +1,84 @@
+package processor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/b0nbon1/stratal/internal/storage/db/dto"
+	db "github.com/b0nbon1/stratal/internal/storage/db/sqlc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sensorTask(pokeInterval, timeout string) db.Task {
+	params := map[string]string{"task_name": "wait_file", "path": "/tmp/done"}
+	if pokeInterval != "" {
+		params["poke_interval"] = pokeInterval
+	}
+	return db.Task{Name: "wait", Type: "builtin", Config: dto.TaskConfig{Parameters: params, Timeout: timeout}}
+}
+
+func TestSensorSchedule(t *testing.T) {
+	tests := []struct {
+		name         string
+		task         db.Task
+		pokeInterval time.Duration
+		timeout      time.Duration
+		wantErr      string
+	}{
+		{
+			name:         "defaults",
+			task:         sensorTask("", ""),
+			pokeInterval: defaultPokeInterval,
+			timeout:      defaultSensorTimeout,
+		},
+		{
+			name:         "configured",
+			task:         sensorTask("5m", "2h"),
+			pokeInterval: 5 * time.Minute,
+			timeout:      2 * time.Hour,
+		},
+		{
+			name:    "invalid poke_interval",
+			task:    sensorTask("often", ""),
+			wantErr: "sensor wait has an invalid poke_interval 'often'",
+		},
+		{
+			name:    "negative poke_interval",
+			task:    sensorTask("-1m", ""),
+			wantErr: "invalid poke_interval",
+		},
+		{
+			name:    "invalid timeout",
+			task:    sensorTask("", "0s"),
+			wantErr: "sensor wait has an invalid timeout '0s'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pokeInterval, timeout, err := sensorSchedule(tt.task)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.pokeInterval, pokeInterval)
+			assert.Equal(t, tt.timeout, timeout)
+		})
+	}
+}
+
+func TestNextPoke(t *testing.T) {
+	assert.Equal(t, 30*time.Second, nextPoke(30*time.Second, time.Hour))
+	// the last check is due when the sensor expires
+	assert.Equal(t, 10*time.Second, nextPoke(30*time.Second, 10*time.Second))
+	assert.Equal(t, 30*time.Second, nextPoke(30*time.Second, 30*time.Second))
+}
+
+func TestIsSensorTask(t *testing.T) {
+	assert.True(t, isSensorTask(sensorTask("", "")))
+	assert.True(t, isSensorTask(db.Task{Type: "builtin", Config: dto.TaskConfig{Parameters: map[string]string{"task_name": " WAIT_SQL "}}}))
+	assert.False(t, isSensorTask(db.Task{Type: "builtin", Config: dto.TaskConfig{Parameters: map[string]string{"task_name": "http_request"}}}))
+	assert.False(t, isSensorTask(db.Task{Type: "custom", Config: dto.TaskConfig{Parameters: map[string]string{"task_name": "wait_http"}}}))
+}
@@ -0,0 +1,213 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/b0nbon1/stratal/internal/logger"
+	db "github.com/b0nbon1/stratal/internal/storage/db/sqlc"
+	"github.com/b0nbon1/stratal/pkg/tmpl"
+	"github.com/b0nbon1/stratal/pkg/utils"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// jobChainKey holds the IDs of the jobs of all runs between the root run and the current
+// sub-job, used to refuse sub-jobs that would start one of their own ancestors
+type jobChainKey struct{}
+
+// runSubJob starts a run of another job for a task of type "job", executes it on this
+// worker and waits for it to finish. The output is a JSON object with the outputs of the
+// child's tasks by task name. A child run left unfinished by an earlier attempt, e.g. after
+// a worker crash, is resumed instead of starting a new one.
+func runSubJob(ctx context.Context, deps Dependencies, task db.Task, taskRunID pgtype.UUID, outputs map[string]string, jobLogger *logger.JobRunLogger) (string, error) {
+	store := deps.Store
+	childJob, err := lookupSubJob(ctx, store, task)
+	if err != nil {
+		return "", err
+	}
+
+	chain, _ := ctx.Value(jobChainKey{}).([]string)
+	chain = append(append([]string{}, chain...), task.JobID.String())
+	for _, jobID := range chain {
+		if jobID == childJob.ID.String() {
+			return "", fmt.Errorf("task %s: job %s would start itself recursively", task.Name, childJob.Name)
+		}
+	}
+	ctx = context.WithValue(ctx, jobChainKey{}, chain)
+
+	if err := checkSubJob(ctx, store, childJob, map[string]bool{}); err != nil {
+		return "", fmt.Errorf("task %s: %w", task.Name, err)
+	}
+
+	childRunID, err := childJobRun(ctx, store, task, taskRunID, childJob.ID, outputs)
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Printf("Task %s running job %s as child run %s\n", task.Name, childJob.Name, childRunID.String())
+	if jobLogger != nil {
+		jobLogger.InfoWithTaskRun(taskRunID.String(), fmt.Sprintf("Running job %s as child run %s", childJob.Name, childRunID.String()))
+	}
+
+	var childLogger *logger.JobRunLogger
+	if jobLogger != nil {
+		var release func()
+		childLogger, release, err = jobLogger.ForJobRun(childRunID.String())
+		if err != nil {
+			fmt.Printf("Error creating logger for child job run %s: %v\n", childRunID.String(), err)
+		} else {
+			defer release()
+		}
+	}
+
+	// the child run shares the deadline and cancellation of this task
+	processErr := ProcessJob(ctx, deps, childRunID, childJob, childLogger)
+
+	childRun, err := store.GetJobRun(context.WithoutCancel(ctx), childRunID)
+	if err != nil {
+		return "", fmt.Errorf("failed to load child job run %s: %w", childRunID.String(), err)
+	}
+	// errors before the first task leave the child running, it must not be resumed as is
+	if childRun.Status.String == "running" && processErr != nil {
+		failJobRun(ctx, store, childRunID, "failed", fmt.Sprintf("Job run failed: %v", processErr), processErr, childLogger)
+		childRun.Status.String = "failed"
+		childRun.ErrorMessage.String = processErr.Error()
+	}
+	if childRun.Status.String != "completed" {
+		return "", fmt.Errorf("child job run %s of job %s finished with status %s: %s",
+			childRunID.String(), childJob.Name, childRun.Status.String, childRun.ErrorMessage.String)
+	}
+
+	return childOutputs(ctx, store, childRunID)
+}
+
+// lookupSubJob loads the job referenced by a sub-job task, by ID or by name
+func lookupSubJob(ctx context.Context, store *db.SQLStore, task db.Task) (db.GetJobWithTasksRow, error) {
+	cfg := task.Config.Job
+
+	jobID, err := utils.ParseUUID(cfg.JobID)
+	if cfg.JobID == "" {
+		job, lookupErr := store.GetJobByName(ctx, cfg.JobName)
+		if lookupErr != nil {
+			return db.GetJobWithTasksRow{}, fmt.Errorf("task %s: job %s not found: %w", task.Name, cfg.JobName, lookupErr)
+		}
+		jobID, err = job.ID, nil
+	}
+	if err != nil {
+		return db.GetJobWithTasksRow{}, fmt.Errorf("task %s: invalid job_id %s: %w", task.Name, cfg.JobID, err)
+	}
+
+	job, err := store.GetJobWithTasks(ctx, jobID)
+	if err != nil {
+		return db.GetJobWithTasksRow{}, fmt.Errorf("task %s: job %s not found: %w", task.Name, cfg.JobID, err)
+	}
+	return job, nil
+}
+
+// checkSubJob rejects a job that cannot run as a child run, directly or through sub-jobs of
+// its own. Approval and sensor tasks park their run until the scheduler re-queues it, the
+// parent task waiting for the child would fail while the parked child continues on its own.
+// Child runs execute inline on the parent's worker and never pass through the queue, so a
+// concurrency policy could not be enforced on them and is refused as well.
+func checkSubJob(ctx context.Context, store *db.SQLStore, job db.GetJobWithTasksRow, seen map[string]bool) error {
+	if seen[job.ID.String()] {
+		return nil
+	}
+	seen[job.ID.String()] = true
+
+	if job.Config.Concurrency != nil {
+		return fmt.Errorf("job %s cannot run as a sub-job, it declares a concurrency policy", job.Name)
+	}
+
+	var tasks []db.Task
+	if err := json.Unmarshal(job.Tasks, &tasks); err != nil {
+		return fmt.Errorf("failed to unmarshal tasks of job %s: %w", job.Name, err)
+	}
+	for _, task := range tasks {
+		switch {
+		case task.Type == "approval":
+			return fmt.Errorf("job %s cannot run as a sub-job, its task %s waits for an approval", job.Name, task.Name)
+		case isSensorTask(task):
+			return fmt.Errorf("job %s cannot run as a sub-job, its task %s is a sensor", job.Name, task.Name)
+		case task.Type == "job" && task.Config.Job != nil:
+			nested, err := lookupSubJob(ctx, store, task)
+			if err != nil {
+				return err
+			}
+			if err := checkSubJob(ctx, store, nested, seen); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// childJobRun returns the child run to execute for a task run: the latest one if it is
+// unfinished, otherwise a new run with the task's inputs
+func childJobRun(ctx context.Context, store *db.SQLStore, task db.Task, taskRunID, childJobID pgtype.UUID, outputs map[string]string) (pgtype.UUID, error) {
+	latest, err := store.GetLatestChildJobRunByTaskRun(ctx, taskRunID)
+	switch {
+	case err == nil:
+		switch latest.Status.String {
+		case "completed", "failed", "timed_out", "cancelled":
+		default:
+			return latest.ID, nil
+		}
+	case !errors.Is(err, pgx.ErrNoRows):
+		return pgtype.UUID{}, fmt.Errorf("failed to look up child job run of task %s: %w", task.Name, err)
+	}
+
+	parentRun, err := store.GetTaskRun(ctx, taskRunID)
+	if err != nil {
+		return pgtype.UUID{}, fmt.Errorf("failed to load task run of task %s: %w", task.Name, err)
+	}
+
+	data, opts := outputData(ctx, nil, nil, pgtype.UUID{}, outputs)
+	inputs := make(map[string]string, len(task.Config.Job.Inputs))
+	for key, value := range task.Config.Job.Inputs {
+		resolved, err := tmpl.Render(value, data, opts)
+		if err != nil {
+			return pgtype.UUID{}, fmt.Errorf("input '%s' of task %s: %w", key, task.Name, err)
+		}
+		inputs[key] = resolved
+	}
+	metadata, err := json.Marshal(map[string]interface{}{"inputs": inputs})
+	if err != nil {
+		return pgtype.UUID{}, fmt.Errorf("failed to encode inputs of task %s: %w", task.Name, err)
+	}
+
+	result, err := store.CreateChildJobRunTx(ctx, db.ChildJobRunParams{
+		JobID:           childJobID,
+		ParentJobRunID:  parentRun.JobRunID,
+		ParentTaskRunID: taskRunID,
+		TriggeredBy:     "job:" + parentRun.JobRunID.String(),
+		Metadata:        metadata,
+	})
+	if err != nil {
+		return pgtype.UUID{}, fmt.Errorf("failed to start child job run for task %s: %w", task.Name, err)
+	}
+	return utils.ParseUUID(result.JobRunId)
+}
+
+// childOutputs collects the outputs of the completed tasks of a child run by task name
+func childOutputs(ctx context.Context, store *db.SQLStore, childRunID pgtype.UUID) (string, error) {
+	taskRuns, err := store.ListTaskRunsWithTaskName(ctx, childRunID)
+	if err != nil {
+		return "", fmt.Errorf("failed to load outputs of child job run %s: %w", childRunID.String(), err)
+	}
+
+	result := make(map[string]string, len(taskRuns))
+	for _, taskRun := range taskRuns {
+		if taskRun.Status.String == "completed" || taskRun.Status.String == "cached" {
+			result[taskRun.TaskName] = taskRun.Output.String
+		}
+	}
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
@@ -0,0 +1,61 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/b0nbon1/stratal/internal/storage/db/dto"
+	db "github.com/b0nbon1/stratal/internal/storage/db/sqlc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckSubJob(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  dto.JobConfig
+		tasks   []db.Task
+		wantErr string
+	}{
+		{
+			name: "scripts and builtin tasks",
+			tasks: []db.Task{
+				{Name: "build", Type: "custom"},
+				{Name: "notify", Type: "builtin", Config: dto.TaskConfig{Parameters: map[string]string{"task_name": "send_email"}}},
+			},
+		},
+		{
+			name:    "approval task",
+			tasks:   []db.Task{{Name: "build", Type: "custom"}, {Name: "sign_off", Type: "approval"}},
+			wantErr: "its task sign_off waits for an approval",
+		},
+		{
+			name:    "sensor task",
+			tasks:   []db.Task{{Name: "wait", Type: "builtin", Config: dto.TaskConfig{Parameters: map[string]string{"task_name": "wait_http"}}}},
+			wantErr: "its task wait is a sensor",
+		},
+		{
+			name:    "concurrency policy",
+			config:  dto.JobConfig{Concurrency: &dto.ConcurrencyConfig{MaxRuns: 1, OnConflict: dto.ConcurrencyQueue}},
+			tasks:   []db.Task{{Name: "build", Type: "custom"}},
+			wantErr: "it declares a concurrency policy",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded, err := json.Marshal(tt.tasks)
+			require.NoError(t, err)
+			job := db.GetJobWithTasksRow{Name: "deploy", Config: tt.config, Tasks: encoded}
+
+			err = checkSubJob(context.Background(), nil, job, map[string]bool{})
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, "job deploy cannot run as a sub-job")
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
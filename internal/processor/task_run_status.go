@@ -0,0 +1,82 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/b0nbon1/stratal/internal/logger"
+	"github.com/b0nbon1/stratal/internal/runner"
+	db "github.com/b0nbon1/stratal/internal/storage/db/sqlc"
+	"github.com/b0nbon1/stratal/pkg/utils"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// markTaskRunRunning moves a task run to running and records its start time and attempt
+func markTaskRunRunning(ctx context.Context, store *db.SQLStore, taskRunID pgtype.UUID, attempt int, jobLogger *logger.JobRunLogger) {
+	if err := store.StartTaskRun(ctx, db.StartTaskRunParams{ID: taskRunID, Attempt: int32(attempt)}); err != nil {
+		fmt.Printf("Failed to mark task run %s as running: %v\n", taskRunID.String(), err)
+		if jobLogger != nil {
+			jobLogger.ErrorWithTaskRun(taskRunID.String(), fmt.Sprintf("Failed to mark task run as running: %v", err))
+		}
+	}
+}
+
+// finishTaskRun records the final status, output, exit code and error of a task run, and
+// the failure reason of a script that breached one of its resource limits
+func finishTaskRun(ctx context.Context, store *db.SQLStore, taskRunID pgtype.UUID, output string, exitCode pgtype.Int4, taskErr error, jobLogger *logger.JobRunLogger) {
+	params := db.FinishTaskRunParams{
+		ID:       taskRunID,
+		Status:   utils.ParseText("completed"),
+		ExitCode: exitCode,
+		Output:   utils.ParseText(output),
+	}
+	if taskErr != nil {
+		params.Status = utils.ParseText("failed")
+		if isTimeout(taskErr) {
+			params.Status = utils.ParseText("timed_out")
+		} else if isCancelled(ctx) {
+			params.Status = utils.ParseText("cancelled")
+		}
+		params.ErrorMessage = utils.ParseText(taskErr.Error())
+		params.Output = pgtype.Text{String: output, Valid: output != ""}
+		var limitErr *runner.LimitError
+		if errors.As(taskErr, &limitErr) {
+			params.FailureReason = utils.ParseText(limitErr.Reason)
+		}
+	}
+
+	// the task context may already be cancelled, the final status still has to be stored
+	if err := store.FinishTaskRun(context.WithoutCancel(ctx), params); err != nil {
+		fmt.Printf("Failed to record result of task run %s: %v\n", taskRunID.String(), err)
+		if jobLogger != nil {
+			jobLogger.ErrorWithTaskRun(taskRunID.String(), fmt.Sprintf("Failed to record task run result: %v", err))
+		}
+	}
+}
+
+// skipPendingTaskRuns marks every task run of the job run that never started as skipped
+func skipPendingTaskRuns(ctx context.Context, store *db.SQLStore, jobRunID pgtype.UUID, jobLogger *logger.JobRunLogger) {
+	if err := store.SkipPendingTaskRuns(context.WithoutCancel(ctx), jobRunID); err != nil {
+		fmt.Printf("Failed to mark pending task runs as skipped: %v\n", err)
+		if jobLogger != nil {
+			jobLogger.Error(fmt.Sprintf("Failed to mark pending task runs as skipped: %v", err))
+		}
+	}
+}
+
+// exitCodeFromError extracts the exit code of a custom script. Builtin tasks have no exit code.
+func exitCodeFromError(taskType string, err error) pgtype.Int4 {
+	if taskType != "custom" {
+		return pgtype.Int4{}
+	}
+	if err == nil {
+		return pgtype.Int4{Int32: 0, Valid: true}
+	}
+
+	var scriptErr *runner.ScriptError
+	if errors.As(err, &scriptErr) && scriptErr.ExitCode >= 0 {
+		return pgtype.Int4{Int32: int32(scriptErr.ExitCode), Valid: true}
+	}
+	return pgtype.Int4{}
+}
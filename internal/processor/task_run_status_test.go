@@ -0,0 +1,256 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/b0nbon1/stratal/internal/runner"
+	db "github.com/b0nbon1/stratal/internal/storage/db/sqlc"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDB records the statements it executes and answers queries with the rows listed
+// under their query name, those match accepts when it is set
+type fakeDB struct {
+	rows       map[string][][]interface{}
+	match      func(query string, args []interface{}, row []interface{}) bool
+	queries    []string
+	statements []string
+	args       [][]interface{}
+}
+
+func newFakeStore(rows map[string][][]interface{}) (*db.SQLStore, *fakeDB) {
+	fake := &fakeDB{rows: rows}
+	return &db.SQLStore{Queries: db.New(fake)}, fake
+}
+
+// record keeps the query name, the first line of each statement is "-- name: StartTaskRun :exec"
+func (d *fakeDB) record(sql string, args []interface{}) string {
+	name := strings.Fields(strings.SplitN(sql, "\n", 2)[0])[2]
+	d.queries = append(d.queries, name)
+	d.statements = append(d.statements, sql)
+	d.args = append(d.args, args)
+	return name
+}
+
+func (d *fakeDB) Exec(_ context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	d.record(sql, args)
+	return pgconn.NewCommandTag("UPDATE 1"), nil
+}
+
+func (d *fakeDB) Query(_ context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	name := d.record(sql, args)
+	return &fakeRows{rows: d.matching(name, args), pos: -1}, nil
+}
+
+func (d *fakeDB) QueryRow(_ context.Context, sql string, args ...interface{}) pgx.Row {
+	name := d.record(sql, args)
+	rows := &fakeRows{rows: d.matching(name, args), pos: -1}
+	if !rows.Next() {
+		return errRow{pgx.ErrNoRows}
+	}
+	return rows
+}
+
+func (d *fakeDB) matching(query string, args []interface{}) [][]interface{} {
+	if d.match == nil {
+		return d.rows[query]
+	}
+	var rows [][]interface{}
+	for _, row := range d.rows[query] {
+		if d.match(query, args, row) {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// fakeRows scans each value of a row into the destination of the same position
+type fakeRows struct {
+	pgx.Rows
+	rows [][]interface{}
+	pos  int
+}
+
+func (r *fakeRows) Next() bool {
+	r.pos++
+	return r.pos < len(r.rows)
+}
+
+func (r *fakeRows) Scan(dest ...interface{}) error {
+	row := r.rows[r.pos]
+	if len(row) != len(dest) {
+		return fmt.Errorf("row has %d values, %d destinations given", len(row), len(dest))
+	}
+	for i, value := range row {
+		reflect.ValueOf(dest[i]).Elem().Set(reflect.ValueOf(value))
+	}
+	return nil
+}
+
+func (r *fakeRows) Close()     {}
+func (r *fakeRows) Err() error { return nil }
+
+type errRow struct{ err error }
+
+func (r errRow) Scan(...interface{}) error { return r.err }
+
+func testUUID(b byte) pgtype.UUID {
+	return pgtype.UUID{Bytes: [16]byte{b}, Valid: true}
+}
+
+func TestMarkTaskRunRunning(t *testing.T) {
+	store, fake := newFakeStore(nil)
+
+	markTaskRunRunning(context.Background(), store, testUUID(1), 2, nil)
+
+	require.Equal(t, []string{"StartTaskRun"}, fake.queries)
+	assert.Equal(t, []interface{}{testUUID(1), int32(2)}, fake.args[0])
+}
+
+func TestFinishTaskRun(t *testing.T) {
+	cancelledCtx, cancel := context.WithCancelCause(context.Background())
+	cancel(ErrJobRunCancelled)
+
+	scriptErr := &runner.ScriptError{ExitCode: 137, Err: errors.New("signal: killed")}
+
+	tests := []struct {
+		name          string
+		ctx           context.Context
+		output        string
+		exitCode      pgtype.Int4
+		err           error
+		status        string
+		storedOutput  pgtype.Text
+		errorMessage  pgtype.Text
+		failureReason pgtype.Text
+	}{
+		{
+			name:         "completed",
+			ctx:          context.Background(),
+			output:       "done",
+			exitCode:     pgtype.Int4{Int32: 0, Valid: true},
+			status:       "completed",
+			storedOutput: pgtype.Text{String: "done", Valid: true},
+		},
+		{
+			name:         "failed",
+			ctx:          context.Background(),
+			output:       "partial",
+			exitCode:     pgtype.Int4{Int32: 1, Valid: true},
+			err:          errors.New("exit status 1"),
+			status:       "failed",
+			storedOutput: pgtype.Text{String: "partial", Valid: true},
+			errorMessage: pgtype.Text{String: "exit status 1", Valid: true},
+		},
+		{
+			name:         "failed without output",
+			ctx:          context.Background(),
+			err:          errors.New("unknown builtin task"),
+			status:       "failed",
+			errorMessage: pgtype.Text{String: "unknown builtin task", Valid: true},
+		},
+		{
+			name:         "timed out",
+			ctx:          context.Background(),
+			err:          fmt.Errorf("script execution timed out: %w", context.DeadlineExceeded),
+			status:       "timed_out",
+			errorMessage: pgtype.Text{String: "script execution timed out: context deadline exceeded", Valid: true},
+		},
+		{
+			name:         "cancelled",
+			ctx:          cancelledCtx,
+			err:          context.Canceled,
+			status:       "cancelled",
+			errorMessage: pgtype.Text{String: "context canceled", Valid: true},
+		},
+		{
+			name:          "resource limit",
+			ctx:           context.Background(),
+			exitCode:      pgtype.Int4{Int32: 137, Valid: true},
+			err:           &runner.LimitError{Reason: runner.FailureOOMKilled, Err: scriptErr},
+			status:        "failed",
+			errorMessage:  pgtype.Text{String: (&runner.LimitError{Reason: runner.FailureOOMKilled, Err: scriptErr}).Error(), Valid: true},
+			failureReason: pgtype.Text{String: runner.FailureOOMKilled, Valid: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, fake := newFakeStore(nil)
+
+			finishTaskRun(tt.ctx, store, testUUID(1), tt.output, tt.exitCode, tt.err, nil)
+
+			require.Equal(t, []string{"FinishTaskRun"}, fake.queries)
+			assert.Equal(t, []interface{}{
+				testUUID(1),
+				pgtype.Text{String: tt.status, Valid: true},
+				tt.exitCode,
+				tt.storedOutput,
+				tt.errorMessage,
+				tt.failureReason,
+			}, fake.args[0])
+		})
+	}
+}
+
+func TestSkipPendingTaskRuns(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	store, fake := newFakeStore(nil)
+
+	// a cancelled run still records its skipped tasks
+	skipPendingTaskRuns(ctx, store, testUUID(3), nil)
+
+	require.Equal(t, []string{"SkipPendingTaskRuns"}, fake.queries)
+	assert.Equal(t, []interface{}{testUUID(3)}, fake.args[0])
+}
+
+func TestExitCodeFromError(t *testing.T) {
+	tests := []struct {
+		name     string
+		taskType string
+		err      error
+		expected pgtype.Int4
+	}{
+		{
+			name:     "script succeeded",
+			taskType: "custom",
+			expected: pgtype.Int4{Int32: 0, Valid: true},
+		},
+		{
+			name:     "script exited",
+			taskType: "custom",
+			err:      fmt.Errorf("task build: %w", &runner.ScriptError{ExitCode: 3, Err: errors.New("exit status 3")}),
+			expected: pgtype.Int4{Int32: 3, Valid: true},
+		},
+		{
+			name:     "script killed",
+			taskType: "custom",
+			err:      &runner.ScriptError{ExitCode: -1, Err: errors.New("signal: killed")},
+		},
+		{
+			name:     "script did not start",
+			taskType: "custom",
+			err:      errors.New("unsupported language"),
+		},
+		{
+			name:     "builtin task",
+			taskType: "builtin",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, exitCodeFromError(tt.taskType, tt.err))
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/b0nbon1/stratal/internal/runner"
+	"github.com/b0nbon1/stratal/internal/security"
+	db "github.com/b0nbon1/stratal/internal/storage/db/sqlc"
+	"github.com/b0nbon1/stratal/pkg/tmpl"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Task parameters are templates resolved in two stages. Before a task runs withRunInputs
+// renders inputs, vars, run and env, the namespaces known for the whole run. When it runs
+// the ParameterResolver renders outputs and secrets. The first stage escapes what it
+// renders, so a value containing ${...} is never expanded again.
+
+// runScope holds the namespaces of the first stage
+type runScope struct {
+	inputs  map[string]interface{}
+	vars    map[string]string // variables exported by earlier tasks, nil when not known yet
+	run     map[string]string
+	sandbox runner.Sandbox // decides which worker variables env reads
+}
+
+// newRunScope builds the scope of a job run
+func newRunScope(jobRunID pgtype.UUID, job db.GetJobWithTasksRow, triggeredBy string, inputs map[string]interface{}, sandbox runner.Sandbox) runScope {
+	return runScope{
+		inputs:  inputs,
+		sandbox: sandbox,
+		run: map[string]string{
+			"id":           jobRunID.String(),
+			"job_id":       job.ID.String(),
+			"job_name":     job.Name,
+			"triggered_by": triggeredBy,
+		},
+	}
+}
+
+// data returns the namespaces of the first stage for a task
+func (s runScope) data(task db.Task) map[string]interface{} {
+	run := make(map[string]interface{}, len(s.run)+1)
+	for k, v := range s.run {
+		run[k] = v
+	}
+	run["task"] = task.Name
+
+	data := map[string]interface{}{
+		"inputs": s.inputs,
+		"run":    run,
+		"env":    tmpl.LookupFunc(s.lookupEnv),
+	}
+	if data["inputs"] == nil {
+		data["inputs"] = map[string]interface{}{}
+	}
+	if s.vars != nil {
+		data["vars"] = s.vars
+	}
+	return data
+}
+
+// lookupEnv reads a variable of the worker environment that the script sandbox passes to
+// scripts, like env in `when` conditions
+func (s runScope) lookupEnv(name string) (interface{}, error) {
+	value, ok := s.sandbox.LookupEnv(name)
+	if !ok {
+		return nil, tmpl.Missing("environment variable '%s' is not set", name)
+	}
+	return value, nil
+}
+
+// renderRunScope renders the first stage of a template
+func renderRunScope(value string, task db.Task, scope runScope) (string, error) {
+	return tmpl.Render(value, scope.data(task), tmpl.Options{Strict: true, Partial: true})
+}
+
+// outputData returns the namespaces of the second stage: outputs, also reachable as
+// TASK_OUTPUT and through ${task.output}, and secrets looked up by name when a secret
+// manager is available
+func outputData(ctx context.Context, store *db.SQLStore, secretManager *security.SecretManager, userID pgtype.UUID, outputs map[string]string) (map[string]interface{}, tmpl.Options) {
+	lookupOutput := tmpl.LookupFunc(func(taskName string) (interface{}, error) {
+		output, ok := outputs[taskName]
+		if !ok {
+			return nil, tmpl.Missing("task '%s' has no output, it has not run or did not succeed", taskName)
+		}
+		return output, nil
+	})
+
+	data := map[string]interface{}{
+		"outputs":     lookupOutput,
+		"TASK_OUTPUT": lookupOutput,
+		"secrets": tmpl.LookupFunc(func(name string) (interface{}, error) {
+			if store == nil || secretManager == nil {
+				return nil, fmt.Errorf("secret '%s' cannot be resolved, secrets are not available", name)
+			}
+			secret, err := store.GetSecretByName(ctx, db.GetSecretByNameParams{
+				Name:   name,
+				UserID: userID,
+			})
+			if err != nil {
+				return nil, tmpl.Missing("secret '%s' not found: %v", name, err)
+			}
+			decrypted, err := secretManager.Decrypt(secret.EncryptedValue)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decrypt secret '%s': %w", name, err)
+			}
+			return decrypted, nil
+		}),
+	}
+	// namespaces of the first stage that are still referenced were combined with outputs or secrets
+	for _, namespace := range []string{"inputs", "vars", "run", "env"} {
+		namespace := namespace
+		data[namespace] = tmpl.LookupFunc(func(string) (interface{}, error) {
+			return nil, fmt.Errorf("%s cannot be combined with outputs or secrets in one placeholder", namespace)
+		})
+	}
+
+	opts := tmpl.Options{
+		Strict: true,
+		// ${task_name.output} and ${task_name.outputs}, optionally followed by a path
+		Unknown: func(ref tmpl.Reference) (interface{}, bool) {
+			if len(ref.Path) == 0 || (ref.Path[0] != "output" && ref.Path[0] != "outputs") {
+				return nil, false
+			}
+			output, err := lookupOutput(ref.Root)
+			return tmpl.LookupFunc(func(string) (interface{}, error) {
+				return output, err
+			}), true
+		},
+	}
+	return data, opts
+}
+
+// outputReferences returns the names of the tasks whose outputs a template references
+func outputReferences(t *tmpl.Template) []string {
+	var names []string
+	for _, ref := range t.References() {
+		switch {
+		case ref.Root == "outputs" || ref.Root == "TASK_OUTPUT":
+			if len(ref.Path) > 0 {
+				if name, ok := ref.Path[0].(string); ok {
+					names = append(names, name)
+				}
+			}
+		case len(ref.Path) > 0 && (ref.Path[0] == "output" || ref.Path[0] == "outputs"):
+			names = append(names, ref.Root)
+		}
+	}
+	return names
+}
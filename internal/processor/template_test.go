@@ -0,0 +1,33 @@
+package processor
+
+import (
+	"testing"
+
+	"github.com/b0nbon1/stratal/internal/runner"
+	db "github.com/b0nbon1/stratal/internal/storage/db/sqlc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvFollowsScriptSandbox(t *testing.T) {
+	t.Setenv("STRATAL_TEST_REGION", "eu-west-1")
+	t.Setenv("STRATAL_TEST_KEY", "secret")
+	sandbox := runner.Sandbox{ScrubEnv: true, AllowEnv: []string{"STRATAL_TEST_REGION"}}
+	scope := runScope{sandbox: sandbox}
+
+	task := db.Task{Name: "deploy"}
+	rendered, err := renderRunScope("${env.STRATAL_TEST_REGION}", task, scope)
+	require.NoError(t, err)
+	assert.Equal(t, "eu-west-1", rendered)
+
+	_, err = renderRunScope("${env.STRATAL_TEST_KEY}", task, scope)
+	assert.Error(t, err)
+
+	rendered, err = renderRunScope("${env.STRATAL_TEST_KEY}", task, runScope{})
+	require.NoError(t, err)
+	assert.Equal(t, "secret", rendered, "without a sandbox env reads the worker environment")
+
+	env := conditionVars(nil, nil, sandbox)["env"].(map[string]string)
+	assert.Equal(t, "eu-west-1", env["STRATAL_TEST_REGION"])
+	assert.NotContains(t, env, "STRATAL_TEST_KEY")
+}
@@ -0,0 +1,44 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/b0nbon1/stratal/internal/storage/db/dto"
+	db "github.com/b0nbon1/stratal/internal/storage/db/sqlc"
+)
+
+// withTimeout derives a context with the given Go duration as deadline, an empty timeout adds no deadline
+func withTimeout(ctx context.Context, timeout string) (context.Context, context.CancelFunc, error) {
+	if timeout == "" {
+		ctx, cancel := context.WithCancel(ctx)
+		return ctx, cancel, nil
+	}
+
+	duration, err := time.ParseDuration(timeout)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid timeout '%s': %w", timeout, err)
+	}
+	if duration <= 0 {
+		return nil, nil, fmt.Errorf("invalid timeout '%s': must be positive", timeout)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, duration)
+	return ctx, cancel, nil
+}
+
+// withScriptTimeout applies the default script timeout to a custom task when neither the
+// task nor its job set a timeout, so that a script that never exits cannot hold the worker
+func withScriptTimeout(task db.Task, config dto.JobConfig, timeout time.Duration) db.Task {
+	if task.Type == "custom" && task.Config.Timeout == "" && config.Timeout == "" && timeout > 0 {
+		task.Config.Timeout = timeout.String()
+	}
+	return task
+}
+
+// isTimeout reports whether an error was caused by an exceeded deadline
+func isTimeout(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,124 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/b0nbon1/stratal/internal/runner"
+	"github.com/b0nbon1/stratal/internal/storage/db/dto"
+	db "github.com/b0nbon1/stratal/internal/storage/db/sqlc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithTimeout(t *testing.T) {
+	ctx, cancel, err := withTimeout(context.Background(), "")
+	require.NoError(t, err)
+	_, hasDeadline := ctx.Deadline()
+	assert.False(t, hasDeadline)
+	cancel()
+	assert.ErrorIs(t, ctx.Err(), context.Canceled)
+
+	ctx, cancel, err = withTimeout(context.Background(), "45m")
+	require.NoError(t, err)
+	defer cancel()
+	deadline, hasDeadline := ctx.Deadline()
+	assert.True(t, hasDeadline)
+	assert.WithinDuration(t, time.Now().Add(45*time.Minute), deadline, time.Minute)
+
+	for _, timeout := range []string{"soon", "10", "0s", "-1m"} {
+		_, _, err := withTimeout(context.Background(), timeout)
+		assert.ErrorContains(t, err, fmt.Sprintf("invalid timeout '%s'", timeout))
+	}
+}
+
+func TestIsTimeout(t *testing.T) {
+	assert.True(t, isTimeout(context.DeadlineExceeded))
+	assert.True(t, isTimeout(fmt.Errorf("script execution timed out: %w", context.DeadlineExceeded)))
+	assert.False(t, isTimeout(context.Canceled))
+	assert.False(t, isTimeout(errors.New("exit status 1")))
+	assert.False(t, isTimeout(nil))
+}
+
+func TestWithScriptTimeout(t *testing.T) {
+	tests := []struct {
+		name     string
+		task     db.Task
+		config   dto.JobConfig
+		expected string
+	}{
+		{
+			name:     "script without timeouts",
+			task:     db.Task{Type: "custom"},
+			expected: "5m0s",
+		},
+		{
+			name:     "task timeout",
+			task:     db.Task{Type: "custom", Config: dto.TaskConfig{Timeout: "1h"}},
+			expected: "1h",
+		},
+		{
+			name:   "job timeout",
+			task:   db.Task{Type: "custom"},
+			config: dto.JobConfig{Timeout: "2h"},
+		},
+		{
+			name: "builtin task",
+			task: db.Task{Type: "builtin"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := withScriptTimeout(tt.task, tt.config, 5*time.Minute)
+			assert.Equal(t, tt.expected, task.Config.Timeout)
+		})
+	}
+
+	task := withScriptTimeout(db.Task{Type: "custom"}, dto.JobConfig{}, 0)
+	assert.Empty(t, task.Config.Timeout, "a zero default sets no timeout")
+}
+
+func TestScriptTimeoutIsTimedOut(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash is not available")
+	}
+
+	ctx, cancel, err := withTimeout(context.Background(), "200ms")
+	require.NoError(t, err)
+	defer cancel()
+
+	started := time.Now()
+	_, err = runner.RunScript(ctx, &dto.ScriptConfig{Language: "bash", Code: "sleep 30"}, nil, nil, nil, runner.ScriptOptions{})
+	require.Error(t, err)
+	assert.True(t, isTimeout(err), "got %v", err)
+	assert.Less(t, time.Since(started), 10*time.Second)
+}
+
+func TestExecuteLevel_TimedOutStatus(t *testing.T) {
+	level := TaskLevel{Level: 0, Tasks: []db.Task{{Name: "slow"}, {Name: "broken"}, {Name: "fine"}}}
+	exec := func(ctx context.Context, task db.Task, outputs map[string]string) (string, error) {
+		switch task.Name {
+		case "slow":
+			return "", fmt.Errorf("script execution timed out: %w", context.DeadlineExceeded)
+		case "broken":
+			return "", errors.New("exit status 1")
+		}
+		return "ok", nil
+	}
+
+	outputs := newTaskOutputStore()
+	failures, waiting := executeLevel(context.Background(), level, exec, outputs)
+
+	assert.Empty(t, waiting)
+	require.Len(t, failures, 2)
+	assert.Equal(t, "slow", failures[0].TaskName)
+	assert.Equal(t, "broken", failures[1].TaskName)
+	assert.Equal(t, "timed_out", outputs.Status("slow"))
+	assert.Equal(t, "failed", outputs.Status("broken"))
+	assert.Equal(t, "completed", outputs.Status("fine"))
+}
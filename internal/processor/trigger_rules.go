@@ -0,0 +1,86 @@
+package processor
+
+import (
+	"fmt"
+
+	"github.com/b0nbon1/stratal/internal/storage/db/dto"
+	db "github.com/b0nbon1/stratal/internal/storage/db/sqlc"
+)
+
+type upstreamOutcome int
+
+const (
+	upstreamSucceeded upstreamOutcome = iota
+	upstreamFailed
+	upstreamSkipped
+)
+
+// outcomeOf classifies a finished upstream task as its dependents see it. Allowed
+// failures count as successes, skipped tasks without skip propagation as well.
+func outcomeOf(name string, tasksByName map[string]db.Task, outputs *taskOutputStore) upstreamOutcome {
+	cfg := tasksByName[name].Config
+	switch outputs.Status(name) {
+	case "completed":
+		return upstreamSucceeded
+	case "failed", "timed_out":
+		if cfg.AllowFailure {
+			return upstreamSucceeded
+		}
+		return upstreamFailed
+	case "skipped":
+		if cfg.SkipPropagation == dto.SkipPropagationNone {
+			return upstreamSucceeded
+		}
+	}
+	return upstreamSkipped
+}
+
+// triggerRuleReason evaluates the trigger rule of a task against the outcome of its
+// upstream tasks. An empty reason means the rule is satisfied and the task runs.
+func triggerRuleReason(task db.Task, tasksByName map[string]db.Task, outputs *taskOutputStore) string {
+	var succeeded, failed, skipped []string
+	for _, dep := range task.Config.DependsOn {
+		switch outcomeOf(dep, tasksByName, outputs) {
+		case upstreamSucceeded:
+			succeeded = append(succeeded, dep)
+		case upstreamFailed:
+			failed = append(failed, dep)
+		default:
+			skipped = append(skipped, dep)
+		}
+	}
+
+	switch task.Config.TriggerRule {
+	case dto.TriggerAllDone:
+		return ""
+	case dto.TriggerOneFailed:
+		if len(failed) == 0 {
+			return "trigger rule one_failed not met, no upstream task failed"
+		}
+	case dto.TriggerOneSuccess:
+		if len(succeeded) == 0 {
+			return "trigger rule one_success not met, no upstream task succeeded"
+		}
+	case dto.TriggerNoneFailed:
+		if len(failed) > 0 {
+			return fmt.Sprintf("trigger rule none_failed not met, upstream task %s failed", failed[0])
+		}
+	default:
+		if len(failed) > 0 {
+			return fmt.Sprintf("upstream task %s failed", failed[0])
+		}
+		if len(skipped) > 0 {
+			return fmt.Sprintf("upstream task %s was skipped", skipped[0])
+		}
+	}
+	return ""
+}
+
+// validTriggerRule reports whether rule is empty or one of the known trigger rules
+func validTriggerRule(rule string) bool {
+	switch rule {
+	case "", dto.TriggerAllSuccess, dto.TriggerAllDone, dto.TriggerOneFailed, dto.TriggerOneSuccess, dto.TriggerNoneFailed:
+		return true
+	}
+	return false
+}
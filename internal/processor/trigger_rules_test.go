@@ -0,0 +1,55 @@
+package processor
+
+import (
+	"testing"
+
+	"github.com/b0nbon1/stratal/internal/storage/db/dto"
+	db "github.com/b0nbon1/stratal/internal/storage/db/sqlc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTriggerRuleReason(t *testing.T) {
+	tasksByName := map[string]db.Task{
+		"build":   {Name: "build"},
+		"lint":    {Name: "lint", Config: dto.TaskConfig{AllowFailure: true}},
+		"test":    {Name: "test"},
+		"docs":    {Name: "docs"},
+		"preview": {Name: "preview", Config: dto.TaskConfig{SkipPropagation: dto.SkipPropagationNone}},
+	}
+
+	tests := []struct {
+		name     string
+		rule     string
+		statuses map[string]string
+		expected string
+	}{
+		{"all succeeded", "", map[string]string{"build": "completed", "test": "completed"}, ""},
+		{"failed upstream", "", map[string]string{"build": "completed", "test": "failed"}, "upstream task test failed"},
+		{"timed out upstream", dto.TriggerAllSuccess, map[string]string{"build": "timed_out", "test": "completed"}, "upstream task build failed"},
+		{"skipped upstream", "", map[string]string{"build": "completed", "docs": "skipped"}, "upstream task docs was skipped"},
+		{"allowed failure", "", map[string]string{"build": "completed", "lint": "failed"}, ""},
+		{"skip without propagation", "", map[string]string{"build": "completed", "preview": "skipped"}, ""},
+		{"all done", dto.TriggerAllDone, map[string]string{"build": "failed", "docs": "skipped"}, ""},
+		{"one failed met", dto.TriggerOneFailed, map[string]string{"build": "completed", "test": "failed"}, ""},
+		{"one failed not met", dto.TriggerOneFailed, map[string]string{"build": "completed", "lint": "failed"}, "trigger rule one_failed not met, no upstream task failed"},
+		{"one success met", dto.TriggerOneSuccess, map[string]string{"build": "failed", "test": "completed"}, ""},
+		{"one success not met", dto.TriggerOneSuccess, map[string]string{"build": "failed", "docs": "skipped"}, "trigger rule one_success not met, no upstream task succeeded"},
+		{"none failed with skipped upstream", dto.TriggerNoneFailed, map[string]string{"build": "completed", "docs": "skipped"}, ""},
+		{"none failed not met", dto.TriggerNoneFailed, map[string]string{"build": "completed", "test": "failed"}, "trigger rule none_failed not met, upstream task test failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outputs := newTaskOutputStore()
+			var dependsOn []string
+			for _, name := range []string{"build", "lint", "test", "docs", "preview"} {
+				if status, ok := tt.statuses[name]; ok {
+					outputs.SetStatus(name, status)
+					dependsOn = append(dependsOn, name)
+				}
+			}
+			task := db.Task{Name: "deploy", Config: dto.TaskConfig{DependsOn: dependsOn, TriggerRule: tt.rule}}
+			assert.Equal(t, tt.expected, triggerRuleReason(task, tasksByName, outputs))
+		})
+	}
+}
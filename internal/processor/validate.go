@@ -0,0 +1,35 @@
+package processor
+
+import (
+	"fmt"
+
+	"github.com/b0nbon1/stratal/internal/storage/db/dto"
+	db "github.com/b0nbon1/stratal/internal/storage/db/sqlc"
+)
+
+// validateJob checks the configuration of a job and its tasks before a run starts or a plan
+// is computed, every problem found is returned
+func validateJob(cfg dto.JobConfig, tasks []db.Task) []error {
+	checks := []struct {
+		what string
+		err  error
+	}{
+		{"invalid job configuration", checkTimeout(cfg.Timeout)},
+		{"invalid concurrency policy", ValidateConcurrency(cfg.Concurrency)},
+		{"invalid task condition", validateTaskConditions(tasks)},
+		{"invalid fan-out configuration", validateFanOut(tasks)},
+		{"invalid cache configuration", validateCache(tasks)},
+		{"invalid failure handler", validateFailureHandlers(cfg, tasks)},
+		{"invalid artifacts configuration", validateArtifacts(tasks)},
+		{"invalid output limit", validateOutputLimits(cfg, tasks)},
+		{"invalid resource limits", validateResources(cfg, tasks)},
+	}
+
+	var problems []error
+	for _, check := range checks {
+		if check.err != nil {
+			problems = append(problems, fmt.Errorf("%s: %w", check.what, check.err))
+		}
+	}
+	return problems
+}
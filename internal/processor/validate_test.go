@@ -0,0 +1,23 @@
+package processor
+
+import (
+	"testing"
+
+	"github.com/b0nbon1/stratal/internal/storage/db/dto"
+	db "github.com/b0nbon1/stratal/internal/storage/db/sqlc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateJob(t *testing.T) {
+	assert.Empty(t, validateJob(dto.JobConfig{}, []db.Task{{Name: "build", Type: "custom"}}))
+
+	problems := validateJob(
+		dto.JobConfig{Timeout: "soon", OnFailure: []dto.HandlerTask{{Name: "page", Type: "approval"}}},
+		[]db.Task{{Name: "gate", Type: "approval", Config: dto.TaskConfig{Cache: &dto.CacheConfig{}}}},
+	)
+	require.Len(t, problems, 3)
+	assert.Contains(t, problems[0].Error(), "invalid job configuration: invalid timeout 'soon'")
+	assert.Contains(t, problems[1].Error(), "invalid cache configuration: task gate: approval tasks cannot be cached")
+	assert.Contains(t, problems[2].Error(), "invalid failure handler: job: handler page has unsupported type 'approval'")
+}
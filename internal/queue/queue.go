@@ -1,6 +1,9 @@
 package queue
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 type TaskQueue interface {
 	Enqueue(jobRunID string) error
@@ -9,4 +12,7 @@ type TaskQueue interface {
 	RetryOrDLQ(msgID string, values map[string]interface{}) error
 	MoveToDeadLetter(values map[string]interface{}) error
 	ReclaimStuckJobs(idleTimeout time.Duration)
+	Heartbeat(msgID string) error
+	PublishCancel(jobRunID string) error
+	SubscribeCancel(ctx context.Context) <-chan string
 }
@@ -119,8 +119,24 @@ func (rq *RedisQueue) Enqueue(jobRunID string) error {
 	return nil
 }
 
-// Dequeue gets a job (blocking up to `block`) and returns ID + values
+// Dequeue gets a job (blocking up to `block`) and returns ID + values.
+// Jobs reclaimed from crashed consumers are returned before new ones.
 func (rq *RedisQueue) Dequeue(block time.Duration) (string, map[string]interface{}, error) {
+	// pending entries of this consumer are jobs claimed by ReclaimStuckJobs
+	pending, err := rq.client.XReadGroup(rq.ctx, &redis.XReadGroupArgs{
+		Group:    rq.group,
+		Consumer: rq.consumer,
+		Streams:  []string{rq.stream, "0"},
+		Count:    1,
+	}).Result()
+	if err != nil && err != redis.Nil {
+		return "", nil, err
+	}
+	if len(pending) > 0 && len(pending[0].Messages) > 0 {
+		msg := pending[0].Messages[0]
+		return msg.ID, msg.Values, nil
+	}
+
 	streams, err := rq.client.XReadGroup(rq.ctx, &redis.XReadGroupArgs{
 		Group:    rq.group,
 		Consumer: rq.consumer,
@@ -217,3 +233,58 @@ func (rq *RedisQueue) ReclaimStuckJobs(idleTimeout time.Duration) {
 		}
 	}
 }
+
+// Heartbeat resets the idle time of a job that is still being processed,
+// so ReclaimStuckJobs on other workers does not take it over
+func (rq *RedisQueue) Heartbeat(msgID string) error {
+	return rq.client.XClaim(rq.ctx, &redis.XClaimArgs{
+		Stream:   rq.stream,
+		Group:    rq.group,
+		Consumer: rq.consumer,
+		MinIdle:  0,
+		Messages: []string{msgID},
+	}).Err()
+}
+
+// cancelChannel is the pub/sub channel cancellation requests are broadcast on
+func (rq *RedisQueue) cancelChannel() string {
+	return rq.stream + ":cancel"
+}
+
+// PublishCancel broadcasts a cancellation request for a job run to all workers
+func (rq *RedisQueue) PublishCancel(jobRunID string) error {
+	if err := rq.client.Publish(rq.ctx, rq.cancelChannel(), jobRunID).Err(); err != nil {
+		return fmt.Errorf("unable to publish cancellation of Job_run_id '%s': %w", jobRunID, err)
+	}
+	return nil
+}
+
+// SubscribeCancel returns the IDs of job runs whose cancellation was requested, until ctx ends
+func (rq *RedisQueue) SubscribeCancel(ctx context.Context) <-chan string {
+	jobRunIDs := make(chan string)
+	pubsub := rq.client.Subscribe(ctx, rq.cancelChannel())
+
+	go func() {
+		defer close(jobRunIDs)
+		defer pubsub.Close()
+
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				select {
+				case jobRunIDs <- msg.Payload:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return jobRunIDs
+}
@@ -0,0 +1,35 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ArtifactsDirEnv names the environment variable holding the directory a script reads the
+// artifacts of upstream tasks from and writes its own artifacts to
+const ArtifactsDirEnv = "STRATAL_ARTIFACTS_DIR"
+
+// ArtifactExchange moves artifacts in and out of the artifacts directory of a script run
+type ArtifactExchange interface {
+	// Download places the input artifacts in dir before the script starts
+	Download(ctx context.Context, dir string) error
+	// Upload stores the output artifacts found in dir once the script succeeded
+	Upload(ctx context.Context, dir string) error
+}
+
+// createArtifactsDir creates the STRATAL_ARTIFACTS_DIR of a script in dir and fills it with
+// the input artifacts
+func createArtifactsDir(ctx context.Context, dir string, artifacts ArtifactExchange) (string, error) {
+	artifactsDir := filepath.Join(dir, "artifacts")
+	if err := os.Mkdir(artifactsDir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create artifacts directory: %w", err)
+	}
+	if artifacts != nil {
+		if err := artifacts.Download(ctx, artifactsDir); err != nil {
+			return "", fmt.Errorf("failed to download artifacts: %w", err)
+		}
+	}
+	return artifactsDir, nil
+}
@@ -0,0 +1,70 @@
+package runner
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// maxLineLength bounds the buffer of a lineWriter, longer lines are passed on in pieces
+const maxLineLength = 64 * 1024
+
+// lineWriter passes what is written to it on one line at a time, without the line ending,
+// so that every write to the target is a complete log line. Once limit bytes were passed on
+// the rest is dropped after a final notice, a zero limit is no limit. Errors of the target
+// are ignored, failing to log output must not fail the script. Without a target writes are
+// discarded.
+type lineWriter struct {
+	target  io.Writer
+	limit   int64
+	written int64
+	buf     []byte
+}
+
+func newLineWriter(target io.Writer, limit int64) *lineWriter {
+	return &lineWriter{target: target, limit: limit}
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	if w.target == nil {
+		return len(p), nil
+	}
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.emit(w.buf[:i])
+		w.buf = w.buf[i+1:]
+	}
+	for len(w.buf) >= maxLineLength {
+		w.emit(w.buf[:maxLineLength])
+		w.buf = w.buf[maxLineLength:]
+	}
+	// copy the partial line so the buffer does not keep the lines already passed on
+	w.buf = append([]byte(nil), w.buf...)
+	return len(p), nil
+}
+
+// Flush passes on the last line when the output did not end with a newline
+func (w *lineWriter) Flush() {
+	if w.target == nil || len(w.buf) == 0 {
+		return
+	}
+	w.emit(w.buf)
+	w.buf = nil
+}
+
+func (w *lineWriter) emit(line []byte) {
+	line = bytes.TrimSuffix(line, []byte("\r"))
+	if w.limit > 0 && w.written+int64(len(line)) > w.limit {
+		if w.written <= w.limit {
+			w.target.Write([]byte(fmt.Sprintf("[log output truncated after %d bytes]", w.written)))
+			w.written = w.limit + 1
+		}
+		return
+	}
+	w.written += int64(len(line))
+	w.target.Write(line)
+}
@@ -0,0 +1,72 @@
+package runner
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// lineRecorder keeps every write as a separate line
+type lineRecorder struct {
+	lines []string
+}
+
+func (r *lineRecorder) Write(p []byte) (int, error) {
+	r.lines = append(r.lines, string(p))
+	return len(p), nil
+}
+
+func TestLineWriter(t *testing.T) {
+	tests := []struct {
+		name     string
+		limit    int64
+		writes   []string
+		expected []string
+	}{
+		{"complete lines", 0, []string{"first\nsecond\n"}, []string{"first", "second"}},
+		{"lines split across writes", 0, []string{"fir", "st\nsec", "ond\n"}, []string{"first", "second"}},
+		{"last line without newline", 0, []string{"first\nlast"}, []string{"first", "last"}},
+		{"carriage returns", 0, []string{"first\r\nsecond\r\n"}, []string{"first", "second"}},
+		{"empty lines", 0, []string{"a\n\nb\n"}, []string{"a", "", "b"}},
+		{"within the limit", 10, []string{"12345\n12345\n"}, []string{"12345", "12345"}},
+		{"over the limit", 8, []string{"12345\n12345\n12345\n"}, []string{"12345", "[log output truncated after 5 bytes]"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := &lineRecorder{}
+			w := newLineWriter(recorder, tt.limit)
+			for _, s := range tt.writes {
+				n, err := w.Write([]byte(s))
+				assert.NoError(t, err)
+				assert.Equal(t, len(s), n)
+			}
+			w.Flush()
+			assert.Equal(t, tt.expected, recorder.lines)
+		})
+	}
+}
+
+func TestLineWriter_LongLines(t *testing.T) {
+	recorder := &lineRecorder{}
+	w := newLineWriter(recorder, 0)
+	w.Write([]byte(strings.Repeat("x", maxLineLength+10) + "\n"))
+	w.Write([]byte(strings.Repeat("y", maxLineLength*2+5)))
+	w.Flush()
+
+	assert.Equal(t, []string{
+		strings.Repeat("x", maxLineLength+10),
+		strings.Repeat("y", maxLineLength),
+		strings.Repeat("y", maxLineLength),
+		strings.Repeat("y", 5),
+	}, recorder.lines)
+}
+
+func TestLineWriter_NoTarget(t *testing.T) {
+	w := newLineWriter(nil, 0)
+	n, err := w.Write([]byte("dropped\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, 8, n)
+	w.Flush()
+}
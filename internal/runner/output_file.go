@@ -0,0 +1,107 @@
+package runner
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	// OutputFileEnv names the environment variable holding the file a script writes its named outputs to
+	OutputFileEnv = "STRATAL_OUTPUT"
+	// EnvFileEnv names the environment variable holding the file a script exports variables for later tasks to
+	EnvFileEnv = "STRATAL_ENV"
+)
+
+// outputNamePattern restricts output and variable names so they are usable as references and env vars
+var outputNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// createCommandFiles creates the empty STRATAL_OUTPUT and STRATAL_ENV files in dir
+func createCommandFiles(dir string) (outputFile, envFile string, err error) {
+	outputFile = filepath.Join(dir, "stratal_output")
+	envFile = filepath.Join(dir, "stratal_env")
+	for _, path := range []string{outputFile, envFile} {
+		if err := os.WriteFile(path, nil, 0600); err != nil {
+			return "", "", fmt.Errorf("failed to create %s: %w", filepath.Base(path), err)
+		}
+	}
+	return outputFile, envFile, nil
+}
+
+// parseCommandFile reads the entries a script wrote to a STRATAL_OUTPUT or STRATAL_ENV file.
+// Each entry is either a single line name=value or a multi-line value delimited like a heredoc:
+//
+//	name<<EOF
+//	first line
+//	second line
+//	EOF
+//
+// Later entries for the same name override earlier ones.
+func parseCommandFile(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]string{}, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if name, delimiter, ok := strings.Cut(line, "<<"); ok && !strings.Contains(name, "=") {
+			name = strings.TrimSpace(name)
+			delimiter = strings.TrimSpace(delimiter)
+			if !outputNamePattern.MatchString(name) {
+				return nil, fmt.Errorf("line %d: invalid name '%s'", lineNo, name)
+			}
+			if delimiter == "" {
+				return nil, fmt.Errorf("line %d: missing delimiter for '%s'", lineNo, name)
+			}
+
+			start := lineNo
+			var valueLines []string
+			terminated := false
+			for scanner.Scan() {
+				lineNo++
+				valueLine := strings.TrimSuffix(scanner.Text(), "\r")
+				if valueLine == delimiter {
+					terminated = true
+					break
+				}
+				valueLines = append(valueLines, valueLine)
+			}
+			if !terminated {
+				return nil, fmt.Errorf("line %d: delimiter '%s' for '%s' is never closed", start, delimiter, name)
+			}
+			values[name] = strings.Join(valueLines, "\n")
+			continue
+		}
+
+		name, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected name=value or name<<DELIMITER", lineNo)
+		}
+		name = strings.TrimSpace(name)
+		if !outputNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("line %d: invalid name '%s'", lineNo, name)
+		}
+		values[name] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
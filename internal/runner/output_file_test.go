@@ -0,0 +1,92 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCommandFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected map[string]string
+		wantErr  string
+	}{
+		{
+			name:     "empty file",
+			content:  "",
+			expected: map[string]string{},
+		},
+		{
+			name:     "single line entries",
+			content:  "version=1.2.0\nurl=https://example.com/?a=b\n\nempty=\n",
+			expected: map[string]string{"version": "1.2.0", "url": "https://example.com/?a=b", "empty": ""},
+		},
+		{
+			name:     "heredoc",
+			content:  "notes<<EOF\nfirst line\n\nname=value inside\nEOF\nnext=1\n",
+			expected: map[string]string{"notes": "first line\n\nname=value inside", "next": "1"},
+		},
+		{
+			name:     "windows line endings",
+			content:  "a=1\r\nb<<END\r\nx\r\nEND\r\n",
+			expected: map[string]string{"a": "1", "b": "x"},
+		},
+		{
+			name:     "later entries override earlier ones",
+			content:  "a=1\na=2\n",
+			expected: map[string]string{"a": "2"},
+		},
+		{
+			name:     "value containing <<",
+			content:  "cmd=cat <<EOF\n",
+			expected: map[string]string{"cmd": "cat <<EOF"},
+		},
+		{
+			name:    "missing separator",
+			content: "a=1\njust text\n",
+			wantErr: "line 2: expected name=value or name<<DELIMITER",
+		},
+		{
+			name:    "invalid name",
+			content: "1st=value\n",
+			wantErr: "line 1: invalid name '1st'",
+		},
+		{
+			name:    "missing delimiter",
+			content: "notes<<\n",
+			wantErr: "line 1: missing delimiter for 'notes'",
+		},
+		{
+			name:    "unclosed heredoc",
+			content: "a=1\nnotes<<EOF\ntext\n",
+			wantErr: "line 2: delimiter 'EOF' for 'notes' is never closed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "stratal_output")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0600))
+
+			values, err := parseCommandFile(path)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, values)
+		})
+	}
+}
+
+func TestParseCommandFile_Missing(t *testing.T) {
+	values, err := parseCommandFile(filepath.Join(t.TempDir(), "missing"))
+	require.NoError(t, err)
+	assert.Empty(t, values)
+}
@@ -0,0 +1,149 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// OutputLimits bounds what a script run keeps in memory and passes on, in bytes. A zero
+// limit is no limit.
+type OutputLimits struct {
+	MaxOutputSize int64 // stdout and stderr kept in memory, the rest of stdout is spilled
+	MaxLogSize    int64 // stdout and stderr each written to the Stdout and Stderr writers
+	MaxEnvSize    int64 // a single TASK_OUTPUT_ variable
+	MaxEnvTotal   int64 // all TASK_OUTPUT_ variables together
+}
+
+// OutputSpill stores the complete stdout of a script that exceeded MaxOutputSize
+type OutputSpill interface {
+	Spill(ctx context.Context, r io.Reader) error
+}
+
+// outputCapture keeps the first limit bytes written to it in memory. With spill set, the
+// complete output is copied to a temporary file once it exceeds the limit.
+type outputCapture struct {
+	limit int64
+	spill bool
+	buf   bytes.Buffer
+	size  int64
+	file  *os.File
+	err   error
+}
+
+func (c *outputCapture) Write(p []byte) (int, error) {
+	c.size += int64(len(p))
+	if c.limit <= 0 {
+		c.buf.Write(p)
+		return len(p), nil
+	}
+
+	head := p
+	if room := c.limit - int64(c.buf.Len()); int64(len(head)) > room {
+		head = p[:room]
+	}
+	c.buf.Write(head)
+	rest := p[len(head):]
+	if len(rest) == 0 || !c.spill || c.err != nil {
+		return len(p), nil
+	}
+
+	// a failing spill file must not fail the script, the error is reported once it exited
+	if c.file == nil {
+		c.file, c.err = os.CreateTemp("", "stratal-output-*")
+		if c.err == nil {
+			_, c.err = c.file.Write(c.buf.Bytes())
+		}
+	}
+	if c.err == nil {
+		_, c.err = c.file.Write(rest)
+	}
+	return len(p), nil
+}
+
+// Truncated reports whether more was written than kept in memory
+func (c *outputCapture) Truncated() bool {
+	return c.size > int64(c.buf.Len())
+}
+
+// String returns the output kept in memory
+func (c *outputCapture) String() string {
+	return c.buf.String()
+}
+
+// spillTo passes the complete output to spill
+func (c *outputCapture) spillTo(ctx context.Context, spill OutputSpill) error {
+	if c.err != nil {
+		return c.err
+	}
+	if c.file == nil {
+		return spill.Spill(ctx, bytes.NewReader(c.buf.Bytes()))
+	}
+	if _, err := c.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return spill.Spill(ctx, c.file)
+}
+
+// Close removes the spill file
+func (c *outputCapture) Close() {
+	if c.file != nil {
+		c.file.Close()
+		os.Remove(c.file.Name())
+	}
+}
+
+// TruncateOutput cuts an output to at most limit bytes on a character boundary and appends
+// a marker stating the total size, note describes what happened to the rest
+func TruncateOutput(output string, limit, size int64, note string) string {
+	cut := len(output)
+	if limit < int64(cut) {
+		cut = int(max(limit, 0))
+	}
+	for cut > 0 && cut < len(output) && !utf8.RuneStart(output[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%s\n[output truncated: %d of %d bytes shown, %s]", output[:cut], cut, size, note)
+}
+
+// taskOutputEnv returns the TASK_OUTPUT_ variables of a script. Outputs larger than
+// MaxEnvSize are truncated, once MaxEnvTotal is used up the remaining outputs are only
+// announced by the truncation marker so that exec does not fail with an oversized
+// environment.
+func taskOutputEnv(taskOutputs, parameters map[string]string, limits OutputLimits) []string {
+	names := make([]string, 0, len(taskOutputs))
+	for taskName := range taskOutputs {
+		names = append(names, taskName)
+	}
+	sort.Strings(names)
+
+	var env []string
+	var total int64
+	for _, taskName := range names {
+		envName := fmt.Sprintf("TASK_OUTPUT_%s", strings.ToUpper(strings.ReplaceAll(taskName, "-", "_")))
+		if _, exists := parameters[envName]; exists {
+			continue
+		}
+		output := taskOutputs[taskName]
+		limit := int64(-1)
+		if limits.MaxEnvSize > 0 {
+			limit = limits.MaxEnvSize
+		}
+		if limits.MaxEnvTotal > 0 {
+			if remaining := max(limits.MaxEnvTotal-total, 0); limit < 0 || remaining < limit {
+				limit = remaining
+			}
+		}
+		if limit >= 0 && int64(len(output)) > limit {
+			output = TruncateOutput(output, limit, int64(len(output)), "the environment size limit was reached")
+		}
+		total += int64(len(output))
+		env = append(env, fmt.Sprintf("%s=%s", envName, output))
+	}
+	return env
+}
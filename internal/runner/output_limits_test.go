@@ -0,0 +1,130 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// spillRecorder keeps what is spilled to it
+type spillRecorder struct {
+	content string
+}
+
+func (s *spillRecorder) Spill(_ context.Context, r io.Reader) error {
+	content, err := io.ReadAll(r)
+	s.content = string(content)
+	return err
+}
+
+func TestOutputCapture(t *testing.T) {
+	tests := []struct {
+		name      string
+		limit     int64
+		spill     bool
+		writes    []string
+		kept      string
+		truncated bool
+	}{
+		{"no limit", 0, false, []string{"hello ", "world"}, "hello world", false},
+		{"within the limit", 11, true, []string{"hello ", "world"}, "hello world", false},
+		{"over the limit", 8, false, []string{"hello ", "world"}, "hello wo", true},
+		{"over the limit with spill", 8, true, []string{"hello ", "world", "!"}, "hello wo", true},
+		{"limit reached by an earlier write", 5, true, []string{"hello", " world"}, "hello", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &outputCapture{limit: tt.limit, spill: tt.spill}
+			defer c.Close()
+			complete := ""
+			for _, s := range tt.writes {
+				n, err := c.Write([]byte(s))
+				require.NoError(t, err)
+				assert.Equal(t, len(s), n)
+				complete += s
+			}
+
+			assert.Equal(t, tt.kept, c.String())
+			assert.Equal(t, tt.truncated, c.Truncated())
+			assert.Equal(t, int64(len(complete)), c.size)
+
+			spill := &spillRecorder{}
+			require.NoError(t, c.spillTo(context.Background(), spill))
+			if tt.spill || !tt.truncated {
+				assert.Equal(t, complete, spill.content)
+			} else {
+				assert.Equal(t, tt.kept, spill.content)
+			}
+		})
+	}
+}
+
+func TestTruncateOutput(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		limit    int64
+		expected string
+	}{
+		{"cut", "hello world", 5, "hello\n[output truncated: 5 of 11 bytes shown, rest dropped]"},
+		{"limit beyond the output", "hello", 10, "hello\n[output truncated: 5 of 11 bytes shown, rest dropped]"},
+		{"zero limit", "hello", 0, "\n[output truncated: 0 of 11 bytes shown, rest dropped]"},
+		{"negative limit", "hello", -1, "\n[output truncated: 0 of 11 bytes shown, rest dropped]"},
+		{"multi-byte character", "héllo", 2, "h\n[output truncated: 1 of 11 bytes shown, rest dropped]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, TruncateOutput(tt.output, tt.limit, 11, "rest dropped"))
+		})
+	}
+}
+
+func TestTaskOutputEnv(t *testing.T) {
+	outputs := map[string]string{
+		"build":      "1234567890",
+		"unit-tests": "ok",
+		"lint":       "clean",
+	}
+	marker := func(shown, size int) string {
+		return fmt.Sprintf("\n[output truncated: %d of %d bytes shown, the environment size limit was reached]", shown, size)
+	}
+
+	tests := []struct {
+		name       string
+		parameters map[string]string
+		limits     OutputLimits
+		expected   []string
+	}{
+		{
+			name:     "no limits",
+			expected: []string{"TASK_OUTPUT_BUILD=1234567890", "TASK_OUTPUT_LINT=clean", "TASK_OUTPUT_UNIT_TESTS=ok"},
+		},
+		{
+			name:       "parameters take precedence",
+			parameters: map[string]string{"TASK_OUTPUT_LINT": "configured"},
+			expected:   []string{"TASK_OUTPUT_BUILD=1234567890", "TASK_OUTPUT_UNIT_TESTS=ok"},
+		},
+		{
+			name:     "size limit per output",
+			limits:   OutputLimits{MaxEnvSize: 4},
+			expected: []string{"TASK_OUTPUT_BUILD=1234" + marker(4, 10), "TASK_OUTPUT_LINT=clea" + marker(4, 5), "TASK_OUTPUT_UNIT_TESTS=ok"},
+		},
+		{
+			name:     "total limit",
+			limits:   OutputLimits{MaxEnvTotal: 12},
+			expected: []string{"TASK_OUTPUT_BUILD=1234567890", "TASK_OUTPUT_LINT=cl" + marker(2, 5), "TASK_OUTPUT_UNIT_TESTS=" + marker(0, 2)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, taskOutputEnv(outputs, tt.parameters, tt.limits))
+		})
+	}
+}
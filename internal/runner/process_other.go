@@ -0,0 +1,16 @@
+//go:build !unix
+
+package runner
+
+import "os/exec"
+
+// setProcessGroup is a no-op on platforms without process groups
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// killProcessGroup kills the script process, processes it spawned are not tracked
+func killProcessGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return cmd.Process.Kill()
+}
@@ -0,0 +1,70 @@
+//go:build unix
+
+package runner
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/b0nbon1/stratal/internal/storage/db/dto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// processGone reports whether a process exited, an exited child the test cannot reap
+// lingers as a zombie
+func processGone(pid int) bool {
+	if err := syscall.Kill(pid, 0); errors.Is(err, syscall.ESRCH) {
+		return true
+	}
+	stat, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return os.IsNotExist(err)
+	}
+	// the state follows the command name in parentheses
+	fields := strings.Fields(string(stat[strings.LastIndexByte(string(stat), ')')+1:]))
+	return len(fields) > 0 && fields[0] == "Z"
+}
+
+func TestRunScript_CancelKillsProcessGroup(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "child.pid")
+	script := &dto.ScriptConfig{Language: "bash", Code: `sleep 60 &
+echo $! > "$PID_FILE"
+wait`}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		_, err := RunScript(ctx, script, map[string]string{"PID_FILE": pidFile}, nil, nil, ScriptOptions{})
+		done <- err
+	}()
+
+	var pid int
+	require.Eventually(t, func() bool {
+		content, err := os.ReadFile(pidFile)
+		if err != nil {
+			return false
+		}
+		pid, err = strconv.Atoi(strings.TrimSpace(string(content)))
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond, "the script did not start its child")
+	require.False(t, processGone(pid))
+
+	cancel()
+	select {
+	case err := <-done:
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "script execution cancelled")
+	case <-time.After(5 * time.Second):
+		t.Fatal("the script was not stopped")
+	}
+	assert.Eventually(t, func() bool { return processGone(pid) }, 5*time.Second, 10*time.Millisecond, "the child of the script survived")
+}
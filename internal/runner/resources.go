@@ -0,0 +1,120 @@
+package runner
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Resources limits what a script may use, a zero field is no limit. Limits are enforced on
+// Linux only: memory, CPU and processes through a cgroup of the script when the sandbox
+// has a cgroup parent available. Without one memory is a best effort rlimit of each process
+// and CPU and processes are not limited. Open files are an rlimit of each process, the
+// script directory is measured while the script runs.
+type Resources struct {
+	Memory       int64   // bytes of memory, without swap
+	CPU          float64 // cores, e.g. 0.5 for half of one core
+	MaxProcesses int     // processes and threads of the script
+	MaxOpenFiles int     // open files of each process
+	Disk         int64   // bytes in the script directory
+}
+
+// IsZero reports whether no limit is set
+func (r Resources) IsZero() bool {
+	return r == Resources{}
+}
+
+// Failure reasons of scripts that breached one of their resource limits, only breaches the
+// kernel or the runner observed are reported
+const (
+	FailureOOMKilled    = "oom_killed"    // killed by the kernel for exceeding its cgroup memory limit
+	FailureProcessLimit = "process_limit" // its cgroup refused to start more processes than allowed
+	FailureDiskLimit    = "disk_limit"    // killed for writing more to its directory than allowed
+)
+
+var failureDescriptions = map[string]string{
+	FailureOOMKilled:    "script was killed for exceeding its memory limit",
+	FailureProcessLimit: "script exceeded its process limit",
+	FailureDiskLimit:    "script was killed for exceeding its disk quota",
+}
+
+// LimitError is returned when a script failed after breaching one of its resource limits
+type LimitError struct {
+	Reason string // one of the Failure reasons
+	Err    *ScriptError
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s: %s", failureDescriptions[e.Reason], e.Err.Error())
+}
+
+func (e *LimitError) Unwrap() error {
+	return e.Err
+}
+
+// diskPollInterval is how often the directory of a script with a disk quota is measured
+var diskPollInterval = 500 * time.Millisecond
+
+// diskWatcher kills a script once its directory grows beyond the quota
+type diskWatcher struct {
+	exceeded atomic.Bool
+	stop     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// watchDisk measures dir until Stop is called and calls kill once it holds more than quota
+// bytes. It returns nil without a quota.
+func watchDisk(dir string, quota int64, kill func()) *diskWatcher {
+	if quota <= 0 {
+		return nil
+	}
+	w := &diskWatcher{stop: make(chan struct{})}
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		ticker := time.NewTicker(diskPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-w.stop:
+				return
+			case <-ticker.C:
+				if dirSize(dir) > quota {
+					w.exceeded.Store(true)
+					kill()
+					return
+				}
+			}
+		}
+	}()
+	return w
+}
+
+// Stop ends the watch and reports whether the quota was exceeded
+func (w *diskWatcher) Stop() bool {
+	if w == nil {
+		return false
+	}
+	close(w.stop)
+	w.wg.Wait()
+	return w.exceeded.Load()
+}
+
+// dirSize adds up the sizes of the regular files below dir, files removed while it walks
+// are skipped
+func dirSize(dir string) int64 {
+	var size int64
+	filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
@@ -0,0 +1,254 @@
+//go:build linux
+
+package runner
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// cgroup2Magic is the file system type of a cgroup v2 hierarchy
+const cgroup2Magic = 0x63677270
+
+// cpuPeriod is the cgroup CPU period in microseconds the CPU quota of a script is a share of
+const cpuPeriod = 100000
+
+// rlimit is a resource limit the sandbox init sets before it executes the script
+type rlimit struct {
+	Resource int    `json:"resource"`
+	Limit    uint64 `json:"limit"`
+}
+
+// scriptLimits enforces the resource limits of a single script run
+type scriptLimits struct {
+	resources Resources
+	cgroup    *scriptCgroup
+	rlimits   []rlimit
+}
+
+// applyResources prepares the command of a script for its resource limits. Memory, CPU and
+// processes get a cgroup of the script when the cgroup parent of the sandbox is available.
+// Without it memory falls back to an rlimit of each process, CPU and processes are not
+// limited. Open files are always an rlimit of each process.
+func applyResources(cmd *exec.Cmd, r Resources, s Sandbox) (*scriptLimits, error) {
+	limits := &scriptLimits{resources: r}
+	if r.IsZero() {
+		return limits, nil
+	}
+
+	var parent *cgroupParent
+	if s.CgroupParent != "" && (r.Memory > 0 || r.CPU > 0 || r.MaxProcesses > 0) {
+		parent = prepareCgroupParent(s.CgroupParent)
+	}
+	if parent != nil {
+		cgroup, err := newScriptCgroup(parent, r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create cgroup: %w", err)
+		}
+		limits.cgroup = cgroup
+		if cmd.SysProcAttr == nil {
+			cmd.SysProcAttr = &syscall.SysProcAttr{}
+		}
+		cmd.SysProcAttr.UseCgroupFD = true
+		cmd.SysProcAttr.CgroupFD = int(cgroup.fd.Fd())
+	}
+
+	if r.Memory > 0 && !limits.cgroup.controls("memory") {
+		// best effort, each process of the script may allocate up to the limit
+		limits.rlimits = append(limits.rlimits, rlimit{Resource: syscall.RLIMIT_DATA, Limit: uint64(r.Memory)})
+	}
+	if (r.CPU > 0 && !limits.cgroup.controls("cpu")) || (r.MaxProcesses > 0 && !limits.cgroup.controls("pids")) {
+		fmt.Printf("Script CPU and process limits are not enforced without a cgroup\n")
+	}
+	if r.MaxOpenFiles > 0 {
+		limits.rlimits = append(limits.rlimits, rlimit{Resource: syscall.RLIMIT_NOFILE, Limit: uint64(r.MaxOpenFiles)})
+	}
+	return limits, nil
+}
+
+// breach returns the failure reason of a failed script that breached a limit of its
+// cgroup, as counted by the kernel. Breaches of rlimits are not recorded anywhere, they
+// surface as errors of the script.
+func (l *scriptLimits) breach() string {
+	if l == nil || l.cgroup == nil {
+		return ""
+	}
+	if l.cgroup.controls("memory") && l.cgroup.events("memory.events", "oom_kill") > 0 {
+		return FailureOOMKilled
+	}
+	if l.cgroup.controls("pids") && l.cgroup.events("pids.events", "max") > 0 {
+		return FailureProcessLimit
+	}
+	return ""
+}
+
+// release kills what is left of the script in its cgroup and removes the cgroup
+func (l *scriptLimits) release() {
+	if l != nil && l.cgroup != nil {
+		l.cgroup.remove()
+	}
+}
+
+// cgroupParent is a cgroup v2 the cgroups of scripts are created in
+type cgroupParent struct {
+	dir         string
+	controllers map[string]bool // enabled for the cgroups of scripts
+}
+
+var cgroupParents = struct {
+	sync.Mutex
+	prepared map[string]*cgroupParent
+}{prepared: map[string]*cgroupParent{}}
+
+// prepareCgroupParent creates the parent cgroup and enables the memory, cpu and pids
+// controllers for its children, once per directory. It returns nil when cgroup v2 is not
+// available there, the worker then falls back to rlimits.
+func prepareCgroupParent(dir string) *cgroupParent {
+	cgroupParents.Lock()
+	defer cgroupParents.Unlock()
+	if parent, ok := cgroupParents.prepared[dir]; ok {
+		return parent
+	}
+
+	parent, err := newCgroupParent(dir)
+	if err != nil {
+		fmt.Printf("cgroup v2 is not available at %s, memory limits fall back to rlimits, CPU and process limits are not enforced: %v\n", dir, err)
+	}
+	cgroupParents.prepared[dir] = parent
+	return parent
+}
+
+func newCgroupParent(dir string) (*cgroupParent, error) {
+	existing := dir
+	for {
+		if _, err := os.Stat(existing); err == nil || existing == filepath.Dir(existing) {
+			break
+		}
+		existing = filepath.Dir(existing)
+	}
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(existing, &st); err != nil {
+		return nil, err
+	}
+	if st.Type != cgroup2Magic {
+		return nil, fmt.Errorf("%s is not a cgroup v2 hierarchy", existing)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	available, err := os.ReadFile(filepath.Join(dir, "cgroup.controllers"))
+	if err != nil {
+		return nil, err
+	}
+	parent := &cgroupParent{dir: dir, controllers: map[string]bool{}}
+	for _, controller := range strings.Fields(string(available)) {
+		if controller != "memory" && controller != "cpu" && controller != "pids" {
+			continue
+		}
+		if err := os.WriteFile(filepath.Join(dir, "cgroup.subtree_control"), []byte("+"+controller), 0644); err != nil {
+			return nil, fmt.Errorf("failed to enable the %s controller: %w", controller, err)
+		}
+		parent.controllers[controller] = true
+	}
+	return parent, nil
+}
+
+// scriptCgroup is the cgroup of a single script run
+type scriptCgroup struct {
+	dir         string
+	fd          *os.File
+	controllers map[string]bool
+}
+
+// newScriptCgroup creates a cgroup below parent limited to r, limits whose controller the
+// parent lacks are left to rlimits
+func newScriptCgroup(parent *cgroupParent, r Resources) (*scriptCgroup, error) {
+	dir, err := os.MkdirTemp(parent.dir, "script-")
+	if err != nil {
+		return nil, err
+	}
+	c := &scriptCgroup{dir: dir, controllers: map[string]bool{}}
+
+	settings := []struct {
+		controller, file, value string
+		set                     bool
+	}{
+		{"memory", "memory.max", strconv.FormatInt(r.Memory, 10), r.Memory > 0},
+		{"memory", "memory.swap.max", "0", r.Memory > 0},
+		// an out of memory kill ends the whole script instead of one of its processes
+		{"memory", "memory.oom.group", "1", r.Memory > 0},
+		{"cpu", "cpu.max", fmt.Sprintf("%d %d", max(int64(r.CPU*cpuPeriod), 1000), cpuPeriod), r.CPU > 0},
+		{"pids", "pids.max", strconv.Itoa(r.MaxProcesses), r.MaxProcesses > 0},
+	}
+	for _, setting := range settings {
+		if !setting.set || !parent.controllers[setting.controller] {
+			continue
+		}
+		path := filepath.Join(dir, setting.file)
+		if _, err := os.Stat(path); err != nil && setting.file == "memory.swap.max" {
+			// swap is not accounted on this host
+			continue
+		}
+		if err := os.WriteFile(path, []byte(setting.value), 0644); err != nil {
+			c.remove()
+			return nil, fmt.Errorf("failed to set %s: %w", setting.file, err)
+		}
+		c.controllers[setting.controller] = true
+	}
+
+	c.fd, err = os.Open(dir)
+	if err != nil {
+		c.remove()
+		return nil, err
+	}
+	return c, nil
+}
+
+// controls reports whether the cgroup enforces the limit of a controller
+func (c *scriptCgroup) controls(controller string) bool {
+	return c != nil && c.controllers[controller]
+}
+
+// events returns a counter of an events file of the cgroup, 0 when it cannot be read
+func (c *scriptCgroup) events(file, key string) int64 {
+	f, err := os.Open(filepath.Join(c.dir, file))
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == key {
+			count, _ := strconv.ParseInt(fields[1], 10, 64)
+			return count
+		}
+	}
+	return 0
+}
+
+// remove kills the processes left in the cgroup and removes it, the kernel only removes
+// a cgroup once its processes are gone
+func (c *scriptCgroup) remove() {
+	if c.fd != nil {
+		c.fd.Close()
+	}
+	os.WriteFile(filepath.Join(c.dir, "cgroup.kill"), []byte("1"), 0644)
+	for attempt := 0; attempt < 20; attempt++ {
+		if err := syscall.Rmdir(c.dir); err == nil || errors.Is(err, syscall.ENOENT) {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	fmt.Printf("Failed to remove cgroup %s\n", c.dir)
+}
@@ -0,0 +1,106 @@
+//go:build linux
+
+package runner
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScriptLimitsBreach(t *testing.T) {
+	tests := []struct {
+		name        string
+		controllers []string
+		events      map[string]string
+		expected    string
+	}{
+		{
+			name:        "no breach",
+			controllers: []string{"memory", "pids"},
+			events: map[string]string{
+				"memory.events": "low 0\nhigh 0\nmax 3\noom 0\noom_kill 0\n",
+				"pids.events":   "max 0\n",
+			},
+			expected: "",
+		},
+		{
+			name:        "out of memory kill",
+			controllers: []string{"memory", "pids"},
+			events: map[string]string{
+				"memory.events": "low 0\nhigh 0\nmax 12\noom 1\noom_kill 1\n",
+				"pids.events":   "max 0\n",
+			},
+			expected: FailureOOMKilled,
+		},
+		{
+			name:        "process limit",
+			controllers: []string{"memory", "pids"},
+			events: map[string]string{
+				"memory.events": "oom_kill 0\n",
+				"pids.events":   "max 4\n",
+			},
+			expected: FailureProcessLimit,
+		},
+		{
+			name:        "memory kill wins over process limit",
+			controllers: []string{"memory", "pids"},
+			events: map[string]string{
+				"memory.events": "oom_kill 2\n",
+				"pids.events":   "max 4\n",
+			},
+			expected: FailureOOMKilled,
+		},
+		{
+			name:        "events of controllers the script is not limited by",
+			controllers: []string{"cpu"},
+			events: map[string]string{
+				"memory.events": "oom_kill 1\n",
+				"pids.events":   "max 1\n",
+			},
+			expected: "",
+		},
+		{
+			name:        "missing events files",
+			controllers: []string{"memory", "pids"},
+			expected:    "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for file, content := range tt.events {
+				require.NoError(t, os.WriteFile(filepath.Join(dir, file), []byte(content), 0600))
+			}
+			cgroup := &scriptCgroup{dir: dir, controllers: map[string]bool{}}
+			for _, controller := range tt.controllers {
+				cgroup.controllers[controller] = true
+			}
+
+			limits := &scriptLimits{resources: Resources{Memory: 1 << 20, MaxProcesses: 8}, cgroup: cgroup}
+			assert.Equal(t, tt.expected, limits.breach())
+		})
+	}
+}
+
+func TestScriptLimitsBreach_WithoutCgroup(t *testing.T) {
+	var none *scriptLimits
+	assert.Empty(t, none.breach())
+	assert.Empty(t, (&scriptLimits{resources: Resources{Memory: 1 << 20}}).breach())
+}
+
+func TestApplyResources_Rlimits(t *testing.T) {
+	limits, err := applyResources(exec.Command("true"), Resources{Memory: 64 << 20, MaxOpenFiles: 32}, Sandbox{})
+	require.NoError(t, err)
+	assert.Nil(t, limits.cgroup)
+	assert.ElementsMatch(t, []rlimit{
+		{Resource: syscall.RLIMIT_DATA, Limit: 64 << 20},
+		{Resource: syscall.RLIMIT_NOFILE, Limit: 32},
+	}, limits.rlimits)
+}
@@ -0,0 +1,23 @@
+//go:build !linux
+
+package runner
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// scriptLimits has nothing to enforce on this platform
+type scriptLimits struct{}
+
+// applyResources reports resource limits, they are only supported on Linux
+func applyResources(cmd *exec.Cmd, r Resources, s Sandbox) (*scriptLimits, error) {
+	if !r.IsZero() {
+		return nil, fmt.Errorf("resource limits are only supported on Linux")
+	}
+	return &scriptLimits{}, nil
+}
+
+func (l *scriptLimits) breach() string { return "" }
+
+func (l *scriptLimits) release() {}
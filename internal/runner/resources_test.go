@@ -0,0 +1,83 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, path string, size int) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+	require.NoError(t, os.WriteFile(path, make([]byte, size), 0600))
+}
+
+func TestDirSize(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a"), 100)
+	writeFile(t, filepath.Join(dir, "nested", "b"), 250)
+	writeFile(t, filepath.Join(dir, "nested", "deeper", "c"), 50)
+	// a symlink is not counted, nor what it points to
+	outside := filepath.Join(t.TempDir(), "outside")
+	writeFile(t, outside, 1000)
+	require.NoError(t, os.Symlink(outside, filepath.Join(dir, "link")))
+
+	assert.Equal(t, int64(400), dirSize(dir))
+	assert.Equal(t, int64(0), dirSize(filepath.Join(dir, "missing")))
+}
+
+func TestWatchDisk(t *testing.T) {
+	defer func(interval time.Duration) { diskPollInterval = interval }(diskPollInterval)
+	diskPollInterval = 5 * time.Millisecond
+
+	tests := []struct {
+		name     string
+		quota    int64
+		size     int
+		exceeded bool
+	}{
+		{"below quota", 1 << 20, 1 << 10, false},
+		{"at quota", 1 << 10, 1 << 10, false},
+		{"above quota", 1 << 10, 4 << 10, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFile(t, filepath.Join(dir, "out"), tt.size)
+
+			var kills atomic.Int32
+			w := watchDisk(dir, tt.quota, func() { kills.Add(1) })
+			require.NotNil(t, w)
+			time.Sleep(50 * time.Millisecond)
+
+			assert.Equal(t, tt.exceeded, w.Stop())
+			if tt.exceeded {
+				assert.Equal(t, int32(1), kills.Load())
+			} else {
+				assert.Zero(t, kills.Load())
+			}
+		})
+	}
+}
+
+func TestWatchDisk_NoQuota(t *testing.T) {
+	w := watchDisk(t.TempDir(), 0, func() { t.Fatal("killed without a quota") })
+	assert.Nil(t, w)
+	assert.False(t, w.Stop())
+}
+
+func TestLimitError(t *testing.T) {
+	scriptErr := &ScriptError{ExitCode: 137, Err: assert.AnError}
+	err := &LimitError{Reason: FailureOOMKilled, Err: scriptErr}
+
+	assert.Contains(t, err.Error(), "killed for exceeding its memory limit")
+	var unwrapped *ScriptError
+	require.ErrorAs(t, err, &unwrapped)
+	assert.Equal(t, 137, unwrapped.ExitCode)
+}
@@ -2,6 +2,7 @@ package runner
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 
@@ -44,13 +45,29 @@ func RunBuiltinTask(ctx context.Context, name string, params map[string]string,
 	// Log task execution
 	fmt.Printf("Executing builtin task: %s with %d parameters\n", taskName, len(params))
 
-	// Execute task with context
-	output, err := taskFunc(ctx, params)
-	if err != nil {
-		return output, fmt.Errorf("task %s failed: %w", taskName, err)
+	// Execute task with context, tasks that ignore it are abandoned once it ends
+	type result struct {
+		output string
+		err    error
+	}
+	done := make(chan result, 1)
+	go func() {
+		output, err := taskFunc(ctx, params)
+		done <- result{output, err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return "", fmt.Errorf("task %s timed out: %w", taskName, ctx.Err())
+		}
+		return "", fmt.Errorf("task %s cancelled: %w", taskName, ctx.Err())
+	case res := <-done:
+		if res.err != nil {
+			return res.output, fmt.Errorf("task %s failed: %w", taskName, res.err)
+		}
+		return res.output, nil
 	}
-
-	return output, nil
 }
 
 // RegisterBuiltinTask allows registering new builtin tasks at runtime
@@ -65,6 +82,12 @@ func RegisterBuiltinTask(name string, fn TaskFunc) error {
 	return nil
 }
 
+// IsBuiltinTask reports whether a builtin task is registered under name
+func IsBuiltinTask(name string) bool {
+	_, exists := taskRegistry[strings.ToLower(strings.TrimSpace(name))]
+	return exists
+}
+
 // GetAvailableTasks returns a list of all registered builtin tasks
 func GetAvailableTasks() []string {
 	tasks := make([]string, 0, len(taskRegistry))
@@ -90,4 +113,7 @@ func init() {
 	taskRegistry["send_email"] = tasks.SendEmailTaskV2
 	taskRegistry["http_request"] = tasks.HTTPRequestTask
 	taskRegistry["format_output"] = tasks.FormatOutputTask
+	for name := range sensorRegistry {
+		taskRegistry[name] = runSensorOnce(name)
+	}
 }
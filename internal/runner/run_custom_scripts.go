@@ -1,8 +1,9 @@
 package runner
 
 import (
-	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -31,11 +32,49 @@ var languageConfig = map[string]struct {
 	"perl":       {"perl", ".pl", []string{}},
 }
 
+// ScriptError is returned when a script fails to run or exits with a non-zero code
+type ScriptError struct {
+	ExitCode int // -1 when the script did not exit on its own
+	Stderr   string
+	Err      error
+}
+
+func (e *ScriptError) Error() string {
+	if e.Stderr != "" {
+		return fmt.Sprintf("script execution failed: %s\nError output: %s", e.Err.Error(), e.Stderr)
+	}
+	return fmt.Sprintf("script execution failed: %s", e.Err.Error())
+}
+
+func (e *ScriptError) Unwrap() error {
+	return e.Err
+}
+
 // RunCustomScriptWithOutputs runs a custom script with environment variables containing outputs from previous tasks
 func RunCustomScriptWithOutputs(ctx context.Context, script *dto.ScriptConfig, outputs map[string]string) (string, error) {
 	return RunCustomScriptWithSecrets(ctx, script, nil, nil, outputs)
 }
 
+// ScriptResult is the outcome of a script run
+type ScriptResult struct {
+	Stdout  string            // what the script printed to stdout, truncated beyond MaxOutputSize
+	Outputs map[string]string // named outputs the script wrote to its STRATAL_OUTPUT file
+	Env     map[string]string // variables the script exported through its STRATAL_ENV file
+}
+
+// Output returns the task output of the script: its named outputs as a JSON object when it
+// wrote any, otherwise its stdout
+func (r *ScriptResult) Output() string {
+	if len(r.Outputs) == 0 {
+		return r.Stdout
+	}
+	encoded, err := json.Marshal(r.Outputs)
+	if err != nil {
+		return r.Stdout
+	}
+	return string(encoded)
+}
+
 // RunCustomScriptWithSecrets runs a custom script with environment variables containing outputs, parameters, and secrets
 func RunCustomScriptWithSecrets(
 	ctx context.Context,
@@ -44,31 +83,80 @@ func RunCustomScriptWithSecrets(
 	secrets map[string]string,
 	taskOutputs map[string]string,
 ) (string, error) {
+	result, err := RunScript(ctx, script, parameters, secrets, taskOutputs, ScriptOptions{})
+	if err != nil {
+		return "", err
+	}
+	return result.Output(), nil
+}
+
+// ScriptOptions holds the optional parts of a script run
+type ScriptOptions struct {
+	// Artifacts are downloaded into the STRATAL_ARTIFACTS_DIR of the script before it starts
+	// and the ones it wrote there are uploaded once it succeeded. Without an exchange the
+	// directory starts empty and is discarded with the script.
+	Artifacts ArtifactExchange
+	// Stdout and Stderr receive the output of the script one line per write while it runs,
+	// the output is still captured for the result
+	Stdout io.Writer
+	Stderr io.Writer
+	// Limits bounds the output kept in memory, logged and passed in TASK_OUTPUT_ variables
+	Limits OutputLimits
+	// Spill receives the complete stdout when it exceeded Limits.MaxOutputSize and the
+	// script wrote no named outputs, without it the rest of the output is discarded
+	Spill OutputSpill
+	// Sandbox isolates the script from the worker
+	Sandbox Sandbox
+	// Resources limits what the script may use, a breach fails it with a LimitError
+	Resources Resources
+}
+
+// RunScript runs a custom script with environment variables containing outputs, parameters
+// and secrets, and collects the named outputs and variables the script wrote to the files
+// named by STRATAL_OUTPUT and STRATAL_ENV
+func RunScript(
+	ctx context.Context,
+	script *dto.ScriptConfig,
+	parameters map[string]string,
+	secrets map[string]string,
+	taskOutputs map[string]string,
+	opts ScriptOptions,
+) (*ScriptResult, error) {
 	if script == nil {
-		return "", fmt.Errorf("script configuration is nil")
+		return nil, fmt.Errorf("script configuration is nil")
 	}
 
 	if script.Code == "" {
-		return "", fmt.Errorf("script code is empty")
+		return nil, fmt.Errorf("script code is empty")
 	}
 
 	language := strings.ToLower(script.Language)
 	config, exists := languageConfig[language]
 	if !exists {
-		return "", fmt.Errorf("unsupported script language: %s", script.Language)
+		return nil, fmt.Errorf("unsupported script language: %s", script.Language)
 	}
 
 	// Create temporary directory for script execution
 	tempDir, err := os.MkdirTemp("", "stratal-script-*")
 	if err != nil {
-		return "", fmt.Errorf("failed to create temp directory: %w", err)
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
 	}
 	defer os.RemoveAll(tempDir)
 
 	// Write script to temporary file
 	scriptFile := filepath.Join(tempDir, "script"+config.extension)
 	if err := os.WriteFile(scriptFile, []byte(script.Code), 0600); err != nil {
-		return "", fmt.Errorf("failed to write script file: %w", err)
+		return nil, fmt.Errorf("failed to write script file: %w", err)
+	}
+
+	outputFile, envFile, err := createCommandFiles(tempDir)
+	if err != nil {
+		return nil, err
+	}
+
+	artifactsDir, err := createArtifactsDir(ctx, tempDir, opts.Artifacts)
+	if err != nil {
+		return nil, err
 	}
 
 	// Prepare command
@@ -76,13 +164,31 @@ func RunCustomScriptWithSecrets(
 	cmd := exec.CommandContext(ctx, config.interpreter, args...)
 	cmd.Dir = tempDir
 
-	// Set up output capture
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	// cancelling the context kills the script together with everything it started
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error { return killProcessGroup(cmd) }
+
+	// Set up output capture, streaming each line as it is printed
+	stdout := &outputCapture{limit: opts.Limits.MaxOutputSize, spill: opts.Spill != nil}
+	defer stdout.Close()
+	stderr := &outputCapture{limit: opts.Limits.MaxOutputSize}
+	stdoutLines := newLineWriter(opts.Stdout, opts.Limits.MaxLogSize)
+	stderrLines := newLineWriter(opts.Stderr, opts.Limits.MaxLogSize)
+	cmd.Stdout = io.MultiWriter(stdout, stdoutLines)
+	cmd.Stderr = io.MultiWriter(stderr, stderrLines)
+
+	limits, err := applyResources(cmd, opts.Resources, opts.Sandbox)
+	if err != nil {
+		return nil, fmt.Errorf("failed to limit script resources: %w", err)
+	}
+	defer limits.release()
+
+	if err := applySandbox(cmd, opts.Sandbox, limits, tempDir); err != nil {
+		return nil, fmt.Errorf("failed to sandbox script: %w", err)
+	}
 
 	// Set up environment variables
-	cmd.Env = os.Environ()
+	cmd.Env = opts.Sandbox.environ(tempDir)
 
 	// Add regular parameters as environment variables
 	for key, value := range parameters {
@@ -94,58 +200,112 @@ func RunCustomScriptWithSecrets(
 		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", envName, secretValue))
 	}
 
+	// Files the script writes its named outputs and exported variables to
+	cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", OutputFileEnv, outputFile), fmt.Sprintf("%s=%s", EnvFileEnv, envFile))
+	cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", ArtifactsDirEnv, artifactsDir))
+
 	// Add TASK_OUTPUT_ prefix to all task outputs
-	for taskName, output := range taskOutputs {
-		envName := fmt.Sprintf("TASK_OUTPUT_%s", strings.ToUpper(strings.ReplaceAll(taskName, "-", "_")))
-		if _, exists := parameters[envName]; exists {
-			continue
+	cmd.Env = append(cmd.Env, taskOutputEnv(taskOutputs, parameters, opts.Limits)...)
+
+	// Execute script, the context deadline is the timeout
+	if err := cmd.Start(); err != nil {
+		if ctx.Err() != nil {
+			return nil, contextError(ctx)
 		}
-		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", envName, output))
+		return nil, &ScriptError{ExitCode: -1, Err: err}
 	}
+	disk := watchDisk(tempDir, opts.Resources.Disk, func() { killProcessGroup(cmd) })
 
 	// Create a channel to signal completion
 	done := make(chan error, 1)
-
-	// Execute script with timeout handling
 	go func() {
-		done <- cmd.Run()
+		done <- cmd.Wait()
 	}()
 
-	// Wait for completion or timeout
+	// Wait for completion, timeout or cancellation
 	select {
 	case <-ctx.Done():
-		// Context cancelled, kill the process
-		if cmd.Process != nil {
-			cmd.Process.Kill()
-		}
-		return "", fmt.Errorf("script execution cancelled: %w", ctx.Err())
+		// Context cancelled or deadline exceeded, kill the process
+		killProcessGroup(cmd)
+		// the output is copied until the killed process exits, the spill file is removed after
+		<-done
+		disk.Stop()
+		return nil, contextError(ctx)
 
 	case err := <-done:
+		diskExceeded := disk.Stop()
+		stdoutLines.Flush()
+		stderrLines.Flush()
 		output := stdout.String()
 		errorOutput := stderr.String()
+		if stderr.Truncated() {
+			errorOutput = TruncateOutput(errorOutput, stderr.limit, stderr.size, "the rest was discarded")
+		}
+
+		// the process may have been killed by the context before ctx.Done was observed
+		if err != nil && ctx.Err() != nil {
+			return nil, contextError(ctx)
+		}
 
 		if err != nil {
-			if errorOutput != "" {
-				return "", fmt.Errorf("script execution failed: %s\nError output: %s", err.Error(), errorOutput)
+			scriptErr := &ScriptError{ExitCode: -1, Stderr: errorOutput, Err: err}
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				scriptErr.ExitCode = exitErr.ExitCode()
 			}
-			return "", fmt.Errorf("script execution failed: %s", err.Error())
+			if diskExceeded {
+				return nil, &LimitError{Reason: FailureDiskLimit, Err: scriptErr}
+			}
+			if reason := limits.breach(); reason != "" {
+				return nil, &LimitError{Reason: reason, Err: scriptErr}
+			}
+			return nil, scriptErr
 		}
 
 		// If there's error output but the script succeeded, log it but don't fail
-		if errorOutput != "" {
+		if errorOutput != "" && opts.Stderr == nil {
 			fmt.Printf("Script completed with warnings: %s\n", errorOutput)
 		}
 
-		return output, nil
+		outputs, err := parseCommandFile(outputFile)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s file: %w", OutputFileEnv, err)
+		}
+		env, err := parseCommandFile(envFile)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s file: %w", EnvFileEnv, err)
+		}
 
-	case <-time.After(5 * time.Minute): // 5 minute timeout
-		if cmd.Process != nil {
-			cmd.Process.Kill()
+		if opts.Artifacts != nil {
+			if err := opts.Artifacts.Upload(ctx, artifactsDir); err != nil {
+				return nil, fmt.Errorf("failed to upload artifacts: %w", err)
+			}
 		}
-		return "", fmt.Errorf("script execution timed out after 5 minutes")
+
+		// named outputs replace stdout as the task output, only stdout used as output is kept
+		if stdout.Truncated() {
+			note := "the rest was discarded"
+			if opts.Spill != nil && len(outputs) == 0 {
+				if err := stdout.spillTo(ctx, opts.Spill); err != nil {
+					return nil, fmt.Errorf("failed to store complete output: %w", err)
+				}
+				note = "the complete output is stored with the task run"
+			}
+			output = TruncateOutput(output, stdout.limit, stdout.size, note)
+		}
+
+		return &ScriptResult{Stdout: output, Outputs: outputs, Env: env}, nil
 	}
 }
 
+// contextError describes why the context of a script ended
+func contextError(ctx context.Context) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("script execution timed out: %w", ctx.Err())
+	}
+	return fmt.Errorf("script execution cancelled: %w", ctx.Err())
+}
+
 func RunCustomScript(ctx context.Context, script *dto.ScriptConfig) (string, error) {
 	// Call the new function with empty outputs for backward compatibility
 	return RunCustomScriptWithOutputs(ctx, script, nil)
@@ -204,6 +364,10 @@ func StreamCustomScript(ctx context.Context, script *dto.ScriptConfig, outputWri
 	cmd := exec.CommandContext(ctx, config.interpreter, args...)
 	cmd.Dir = tempDir
 
+	// cancelling the context kills the script together with everything it started
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error { return killProcessGroup(cmd) }
+
 	// Set up pipes for real-time output
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
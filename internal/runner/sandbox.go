@@ -0,0 +1,108 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"slices"
+)
+
+// Sandbox isolates scripts from the worker that runs them. The zero value runs scripts with
+// the environment and user of the worker.
+type Sandbox struct {
+	// ScrubEnv starts scripts from the variables in AllowEnv instead of the whole worker
+	// environment, which holds database passwords and the encryption key. A script running
+	// as the worker user in the PID namespace of the worker can still read the environment
+	// of the worker from /proc, only with Namespaces or a UID the worker secrets are hidden.
+	ScrubEnv bool
+	AllowEnv []string
+	// UID and GID run scripts as this user and group without supplementary groups, a UID of
+	// 0 keeps the user of the worker. Switching users requires a worker running as root.
+	UID int
+	GID int
+	// Namespaces runs scripts in new mount, PID, IPC and UTS namespaces, Linux only. A worker
+	// not running as root uses a user namespace as well.
+	Namespaces bool
+	// PrivateTmp gives scripts an empty /tmp, ReadOnlyRoot a read-only root file system.
+	// Both require Namespaces, the directory of the script stays writable.
+	PrivateTmp   bool
+	ReadOnlyRoot bool
+	// DenyNetwork runs scripts in a network namespace without network interfaces, Linux only
+	DenyNetwork bool
+	// CgroupParent is a cgroup v2 directory the resource limits of scripts are enforced
+	// below, one cgroup per script, Linux only. Without it or when it is unavailable the
+	// limits fall back to rlimits.
+	CgroupParent string
+}
+
+// DefaultAllowEnv are the worker variables scripts receive when the environment is scrubbed
+var DefaultAllowEnv = []string{"PATH", "LANG", "LC_ALL", "TZ"}
+
+const (
+	SandboxNone = "none" // default, scripts inherit the worker environment
+	// SandboxStandard starts scripts from an allow-listed environment. It keeps secrets out
+	// of what scripts inherit and log, it does not hide /proc/<worker pid>/environ from them
+	// unless a UID is set as well.
+	SandboxStandard = "standard"
+	SandboxStrict   = "strict" // standard plus namespaces, private /tmp, read-only root and no network
+)
+
+// SandboxProfile returns the sandbox of a named profile
+func SandboxProfile(name string) (Sandbox, error) {
+	switch name {
+	case SandboxNone, "":
+		return Sandbox{}, nil
+	case SandboxStandard:
+		return Sandbox{ScrubEnv: true, AllowEnv: DefaultAllowEnv}, nil
+	case SandboxStrict:
+		return Sandbox{
+			ScrubEnv:     true,
+			AllowEnv:     DefaultAllowEnv,
+			Namespaces:   true,
+			PrivateTmp:   true,
+			ReadOnlyRoot: true,
+			DenyNetwork:  true,
+		}, nil
+	}
+	return Sandbox{}, fmt.Errorf("unknown sandbox profile '%s', expected %s, %s or %s", name, SandboxNone, SandboxStandard, SandboxStrict)
+}
+
+// Validate checks that the sandbox can be set up on this platform by this worker
+func (s Sandbox) Validate() error {
+	if (s.PrivateTmp || s.ReadOnlyRoot) && !s.Namespaces {
+		return fmt.Errorf("a private /tmp and a read-only root require namespaces")
+	}
+	if (s.UID == 0) != (s.GID == 0) {
+		return fmt.Errorf("the uid and gid of scripts must be set together")
+	}
+	return checkSandbox(s)
+}
+
+// Isolated reports whether scripts are kept from reading the environment of the worker,
+// either through their own PID namespace or as a different user
+func (s Sandbox) Isolated() bool {
+	return s.Namespaces || s.UID != 0
+}
+
+// LookupEnv reads a worker variable as scripts in the sandbox see it, with a scrubbed
+// environment only the variables in AllowEnv are set
+func (s Sandbox) LookupEnv(name string) (string, bool) {
+	if s.ScrubEnv && !slices.Contains(s.AllowEnv, name) {
+		return "", false
+	}
+	return os.LookupEnv(name)
+}
+
+// environ returns the environment a script in the sandbox starts from, HOME is its directory
+// unless the worker passes its own
+func (s Sandbox) environ(workDir string) []string {
+	if !s.ScrubEnv {
+		return os.Environ()
+	}
+	env := []string{"HOME=" + workDir}
+	for _, name := range s.AllowEnv {
+		if value, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+value)
+		}
+	}
+	return env
+}
@@ -0,0 +1,235 @@
+//go:build linux
+
+package runner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// sandboxInitArg is the first argument of the copy of the worker that prepares the mounts
+// and rlimits of a sandbox before it executes the script
+const sandboxInitArg = "__stratal_sandbox_init"
+
+// sandboxSpec tells the sandbox init what to prepare
+type sandboxSpec struct {
+	WorkDir      string   `json:"work_dir"`
+	Namespaces   bool     `json:"namespaces"`
+	PrivateTmp   bool     `json:"private_tmp"`
+	ReadOnlyRoot bool     `json:"read_only_root"`
+	UID          int      `json:"uid"`
+	GID          int      `json:"gid"`
+	Rlimits      []rlimit `json:"rlimits,omitempty"`
+}
+
+// checkSandbox reports sandbox settings this worker cannot apply
+func checkSandbox(s Sandbox) error {
+	if s.UID != 0 && os.Geteuid() != 0 {
+		return fmt.Errorf("running scripts as uid %d and gid %d requires a worker running as root", s.UID, s.GID)
+	}
+	return nil
+}
+
+// applySandbox prepares the command of a script run in workDir for the sandbox and the
+// rlimits of the script
+func applySandbox(cmd *exec.Cmd, s Sandbox, limits *scriptLimits, workDir string) error {
+	if err := checkSandbox(s); err != nil {
+		return err
+	}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	attr := cmd.SysProcAttr
+
+	if s.UID != 0 {
+		if err := chownTree(workDir, s.UID, s.GID); err != nil {
+			return fmt.Errorf("failed to hand the script directory to the sandbox user: %w", err)
+		}
+	}
+
+	var flags uintptr
+	if s.Namespaces {
+		flags |= syscall.CLONE_NEWNS | syscall.CLONE_NEWPID | syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS
+	}
+	if s.DenyNetwork {
+		flags |= syscall.CLONE_NEWNET
+	}
+	if flags != 0 && os.Geteuid() != 0 {
+		// the worker is root within a user namespace of its own and may set up the others
+		flags |= syscall.CLONE_NEWUSER
+		attr.UidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Geteuid(), Size: 1}}
+		attr.GidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getegid(), Size: 1}}
+		attr.GidMappingsEnableSetgroups = false
+	}
+	attr.Cloneflags = flags
+
+	var rlimits []rlimit
+	if limits != nil {
+		rlimits = limits.rlimits
+	}
+	if !s.Namespaces && len(rlimits) == 0 {
+		if s.UID != 0 {
+			attr.Credential = &syscall.Credential{Uid: uint32(s.UID), Gid: uint32(s.GID), Groups: []uint32{}}
+		}
+		return nil
+	}
+
+	// the mounts and rlimits are set up by a copy of the worker within the namespaces, which
+	// then drops its privileges and executes the script
+	spec, err := json.Marshal(sandboxSpec{
+		WorkDir:      workDir,
+		Namespaces:   s.Namespaces,
+		PrivateTmp:   s.PrivateTmp,
+		ReadOnlyRoot: s.ReadOnlyRoot,
+		UID:          s.UID,
+		GID:          s.GID,
+		Rlimits:      rlimits,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode sandbox: %w", err)
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate the worker executable: %w", err)
+	}
+	cmd.Args = append([]string{self, sandboxInitArg, string(spec), cmd.Path}, cmd.Args[1:]...)
+	cmd.Path = self
+	return nil
+}
+
+// chownTree hands a directory and everything in it to a user and group
+func chownTree(dir string, uid, gid int) error {
+	return filepath.WalkDir(dir, func(path string, _ fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		return os.Lchown(path, uid, gid)
+	})
+}
+
+// SandboxInit prepares a sandbox and executes the script when the process was started as
+// the sandbox init of a script, otherwise it returns. Workers call it before anything else.
+func SandboxInit() {
+	if len(os.Args) < 2 || os.Args[1] != sandboxInitArg {
+		return
+	}
+	err := sandboxInit(os.Args[2:])
+	fmt.Fprintf(os.Stderr, "sandbox: %v\n", err)
+	os.Exit(126)
+}
+
+// sandboxInit runs as PID 1 of the namespaces of a script, or in place of the script when
+// it only sets rlimits. It only returns on failure.
+func sandboxInit(args []string) error {
+	if len(args) < 2 {
+		return errors.New("missing sandbox spec or command")
+	}
+	var spec sandboxSpec
+	if err := json.Unmarshal([]byte(args[0]), &spec); err != nil {
+		return fmt.Errorf("invalid sandbox spec: %w", err)
+	}
+	command := args[1:]
+
+	if spec.Namespaces {
+		if err := prepareNamespaces(spec); err != nil {
+			return err
+		}
+	}
+
+	for _, limit := range spec.Rlimits {
+		if err := syscall.Setrlimit(limit.Resource, &syscall.Rlimit{Cur: limit.Limit, Max: limit.Limit}); err != nil {
+			return fmt.Errorf("failed to set rlimit %d: %w", limit.Resource, err)
+		}
+	}
+
+	if spec.UID != 0 {
+		if err := syscall.Setgroups([]int{}); err != nil {
+			return fmt.Errorf("failed to drop supplementary groups: %w", err)
+		}
+		if err := syscall.Setgid(spec.GID); err != nil {
+			return fmt.Errorf("failed to switch to gid %d: %w", spec.GID, err)
+		}
+		if err := syscall.Setuid(spec.UID); err != nil {
+			return fmt.Errorf("failed to switch to uid %d: %w", spec.UID, err)
+		}
+	}
+
+	return syscall.Exec(command[0], command, os.Environ())
+}
+
+// prepareNamespaces sets up the mounts and hostname of the namespaces of a script
+func prepareNamespaces(spec sandboxSpec) error {
+	// mounts below stay within the mount namespace of the script
+	if err := syscall.Mount("", "/", "", syscall.MS_REC|syscall.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("failed to make mounts private: %w", err)
+	}
+
+	// the script directory is mounted again by its descriptor, a private /tmp hides it
+	workDir, err := os.Open(spec.WorkDir)
+	if err != nil {
+		return fmt.Errorf("failed to open script directory: %w", err)
+	}
+	defer workDir.Close()
+	if spec.PrivateTmp {
+		if err := syscall.Mount("tmpfs", "/tmp", "tmpfs", syscall.MS_NOSUID|syscall.MS_NODEV, "mode=1777"); err != nil {
+			return fmt.Errorf("failed to mount private /tmp: %w", err)
+		}
+		if err := os.MkdirAll(spec.WorkDir, 0700); err != nil {
+			return fmt.Errorf("failed to recreate script directory: %w", err)
+		}
+	}
+	source := fmt.Sprintf("/proc/self/fd/%d", workDir.Fd())
+	if err := syscall.Mount(source, spec.WorkDir, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+		return fmt.Errorf("failed to mount script directory: %w", err)
+	}
+
+	// a /proc of the PID namespace, the script does not see the processes of the host
+	if err := syscall.Mount("proc", "/proc", "proc", syscall.MS_NOSUID|syscall.MS_NODEV|syscall.MS_NOEXEC, ""); err != nil {
+		return fmt.Errorf("failed to mount /proc: %w", err)
+	}
+
+	if spec.ReadOnlyRoot {
+		if err := remountReadOnly("/"); err != nil {
+			return fmt.Errorf("failed to make the root file system read-only: %w", err)
+		}
+	}
+
+	if err := syscall.Sethostname([]byte("stratal-sandbox")); err != nil {
+		return fmt.Errorf("failed to set hostname: %w", err)
+	}
+	if err := os.Chdir(spec.WorkDir); err != nil {
+		return fmt.Errorf("failed to enter script directory: %w", err)
+	}
+	return nil
+}
+
+// remountReadOnly makes a mount read-only within the mount namespace. Flags the mount
+// already has are kept, a user namespace may not clear them.
+func remountReadOnly(target string) error {
+	var st unix.Statfs_t
+	if err := unix.Statfs(target, &st); err != nil {
+		return err
+	}
+	flags := uintptr(unix.MS_REMOUNT | unix.MS_BIND | unix.MS_RDONLY)
+	for _, kept := range []struct{ statfs, mount uintptr }{
+		{unix.ST_NOSUID, unix.MS_NOSUID},
+		{unix.ST_NODEV, unix.MS_NODEV},
+		{unix.ST_NOEXEC, unix.MS_NOEXEC},
+		{unix.ST_NOATIME, unix.MS_NOATIME},
+		{unix.ST_NODIRATIME, unix.MS_NODIRATIME},
+		{unix.ST_RELATIME, unix.MS_RELATIME},
+	} {
+		if uintptr(st.Flags)&kept.statfs != 0 {
+			flags |= kept.mount
+		}
+	}
+	return unix.Mount("", target, "", flags, "")
+}
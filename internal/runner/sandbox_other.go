@@ -0,0 +1,25 @@
+//go:build !linux
+
+package runner
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// checkSandbox reports sandbox settings this platform cannot apply, only the environment
+// can be scrubbed outside of Linux
+func checkSandbox(s Sandbox) error {
+	if s.UID != 0 || s.Namespaces || s.DenyNetwork {
+		return fmt.Errorf("switching users, namespaces and denying network access are only supported on Linux")
+	}
+	return nil
+}
+
+// applySandbox prepares the command of a script for the sandbox
+func applySandbox(cmd *exec.Cmd, s Sandbox, limits *scriptLimits, workDir string) error {
+	return checkSandbox(s)
+}
+
+// SandboxInit returns, scripts are not started through a sandbox init on this platform
+func SandboxInit() {}
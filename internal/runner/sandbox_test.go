@@ -0,0 +1,32 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSandboxLookupEnv(t *testing.T) {
+	t.Setenv("STRATAL_TEST_ALLOWED", "visible")
+	t.Setenv("STRATAL_TEST_SECRET", "hidden")
+
+	tests := []struct {
+		name    string
+		sandbox Sandbox
+		secret  bool
+	}{
+		{"inherited environment", Sandbox{}, true},
+		{"scrubbed environment", Sandbox{ScrubEnv: true, AllowEnv: []string{"STRATAL_TEST_ALLOWED"}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, ok := tt.sandbox.LookupEnv("STRATAL_TEST_ALLOWED")
+			assert.True(t, ok)
+			assert.Equal(t, "visible", value)
+
+			_, ok = tt.sandbox.LookupEnv("STRATAL_TEST_SECRET")
+			assert.Equal(t, tt.secret, ok)
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/b0nbon1/stratal/internal/runner/tasks"
+)
+
+// SensorFunc checks the condition of a sensor once. It reports whether the condition holds
+// and the task output, or the reason it does not hold yet. Errors are reserved for
+// misconfigured sensors, which fail instead of waiting.
+type SensorFunc func(ctx context.Context, params map[string]string, opts tasks.SensorOptions) (bool, string, error)
+
+// sensorRegistry holds the builtin tasks that wait for an external condition
+var sensorRegistry = map[string]SensorFunc{
+	"wait_http": tasks.WaitHTTPSensor,
+	"wait_file": tasks.WaitFileSensor,
+	"wait_sql":  tasks.WaitSQLSensor,
+}
+
+// IsSensor reports whether a sensor is registered under name
+func IsSensor(name string) bool {
+	_, exists := sensorRegistry[strings.ToLower(strings.TrimSpace(name))]
+	return exists
+}
+
+// PokeSensor checks the condition of a sensor once
+func PokeSensor(ctx context.Context, name string, params map[string]string, opts tasks.SensorOptions) (bool, string, error) {
+	sensorName := strings.ToLower(strings.TrimSpace(name))
+	sensor, exists := sensorRegistry[sensorName]
+	if !exists {
+		return false, "", fmt.Errorf("unknown sensor: %s", name)
+	}
+
+	fmt.Printf("Poking sensor: %s\n", sensorName)
+	met, output, err := sensor(ctx, params, opts)
+	if err != nil {
+		return false, "", fmt.Errorf("sensor %s failed: %w", sensorName, err)
+	}
+	return met, output, nil
+}
+
+// runSensorOnce adapts a sensor to a builtin task that fails when the condition does not
+// hold yet. Job runs reschedule sensors instead, this is used where nothing can wait and
+// no connections are configured.
+func runSensorOnce(sensorName string) TaskFunc {
+	return func(ctx context.Context, params map[string]string) (string, error) {
+		met, output, err := PokeSensor(ctx, sensorName, params, tasks.SensorOptions{})
+		if err != nil {
+			return "", err
+		}
+		if !met {
+			return "", fmt.Errorf("condition not met: %s", output)
+		}
+		return output, nil
+	}
+}
@@ -4,9 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"regexp"
 	"strings"
 	"time"
+
+	"github.com/b0nbon1/stratal/pkg/tmpl"
 )
 
 // FormatOutputTask formats and prints data with user-specified formatting
@@ -70,35 +71,40 @@ func FormatOutputTask(ctx context.Context, params map[string]string) (string, er
 	return result, nil
 }
 
-// formatAsText processes a text template with variable substitution
+// formatAsText processes a text template with variable substitution. Data fields are
+// referenced by name, ${timestamp}, task outputs passed as TASK_OUTPUT_<NAME> parameters
+// as ${TASK_OUTPUT.task_name} or ${task_name.output}. Like task parameters, references that
+// cannot be resolved fail the task, placeholders of unknown names are kept.
 func formatAsText(template string, data map[string]interface{}, params map[string]string) (string, error) {
-	result := template
-
-	// Replace data variables
-	for key, value := range data {
-		placeholder := fmt.Sprintf("${%s}", key)
-		result = strings.ReplaceAll(result, placeholder, fmt.Sprintf("%v", value))
-	}
-
-	// Replace task output variables (these come from parameter interpolation)
-	// Look for ${TASK_OUTPUT.task_name} or ${task_name.output} patterns
-	taskOutputPattern := regexp.MustCompile(`\$\{([^}]+)\.output\}|\$\{TASK_OUTPUT\.([^}]+)\}`)
-	matches := taskOutputPattern.FindAllStringSubmatch(result, -1)
-
-	for _, match := range matches {
-		fullMatch := match[0]
-		var taskName string
-		if match[1] != "" {
-			taskName = match[1]
-		} else {
-			taskName = match[2]
-		}
-
-		// Look for the task output in params (it should be available as TASK_OUTPUT_TASKNAME)
+	lookupOutput := tmpl.LookupFunc(func(taskName string) (interface{}, error) {
+		// task outputs are available as TASK_OUTPUT_TASKNAME
 		taskOutputKey := fmt.Sprintf("TASK_OUTPUT_%s", strings.ToUpper(strings.ReplaceAll(taskName, "-", "_")))
 		if output, exists := params[taskOutputKey]; exists {
-			result = strings.ReplaceAll(result, fullMatch, output)
+			return output, nil
 		}
+		return nil, tmpl.Missing("no output for task '%s'", taskName)
+	})
+
+	values := make(map[string]interface{}, len(data)+1)
+	for key, value := range data {
+		values[key] = value
+	}
+	values["TASK_OUTPUT"] = lookupOutput
+
+	result, err := tmpl.Render(template, values, tmpl.Options{
+		Strict: true,
+		Unknown: func(ref tmpl.Reference) (interface{}, bool) {
+			if len(ref.Path) == 0 || ref.Path[0] != "output" {
+				return nil, false
+			}
+			output, err := lookupOutput(ref.Root)
+			return tmpl.LookupFunc(func(string) (interface{}, error) {
+				return output, err
+			}), true
+		},
+	})
+	if err != nil {
+		return "", err
 	}
 
 	// Handle special formatting
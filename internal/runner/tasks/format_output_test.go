@@ -0,0 +1,56 @@
+package tasks
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatOutputTask_Text(t *testing.T) {
+	tests := []struct {
+		name     string
+		params   map[string]string
+		expected string
+		wantErr  string
+	}{
+		{
+			name:     "fields and outputs",
+			params:   map[string]string{"template": "${greeting}, ${TASK_OUTPUT.fetch-users} and ${build.output}", "field_greeting": "hello", "TASK_OUTPUT_FETCH_USERS": "3 users", "TASK_OUTPUT_BUILD": "ok"},
+			expected: "hello, 3 users and ok",
+		},
+		{
+			name:     "unknown names are kept",
+			params:   map[string]string{"template": "home is ${HOME}"},
+			expected: "home is ${HOME}",
+		},
+		{
+			name:    "missing task output",
+			params:  map[string]string{"template": "users: ${TASK_OUTPUT.fetch_users}"},
+			wantErr: "no output for task 'fetch_users'",
+		},
+		{
+			name:    "missing output of task.output",
+			params:  map[string]string{"template": "users: ${fetch_users.output}"},
+			wantErr: "no output for task 'fetch_users'",
+		},
+		{
+			name:    "missing path",
+			params:  map[string]string{"template": "${TASK_OUTPUT.build.version}", "TASK_OUTPUT_BUILD": `{"tag": "v1"}`},
+			wantErr: "cannot resolve ${TASK_OUTPUT.build.version}",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := FormatOutputTask(context.Background(), tt.params)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, output)
+		})
+	}
+}
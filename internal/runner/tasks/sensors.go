@@ -0,0 +1,187 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/b0nbon1/stratal/pkg/expr"
+	"github.com/jackc/pgx/v5"
+)
+
+// Sensors check an external condition once per poke. They report whether the condition
+// holds and either the task output or, when it does not hold yet, the reason why.
+// An error is only returned for a misconfigured sensor, failures to reach the checked
+// system count as the condition not holding yet.
+
+// SensorOptions holds what sensors get from the worker rather than from task parameters
+type SensorOptions struct {
+	// SQLConnections are the databases wait_sql may query by name, name -> connection string
+	SQLConnections map[string]string
+}
+
+// WaitHTTPSensor waits for a URL to answer with an expected status code and, optionally,
+// a JSON body whose value at json_path equals expected_value
+func WaitHTTPSensor(ctx context.Context, params map[string]string, _ SensorOptions) (bool, string, error) {
+	url := params["url"]
+	if url == "" {
+		return false, "", fmt.Errorf("missing required parameter: url")
+	}
+
+	method := strings.ToUpper(params["method"])
+	if method == "" {
+		method = "GET"
+	}
+
+	expectedStatus := []int{http.StatusOK}
+	if raw := params["expected_status"]; raw != "" {
+		expectedStatus = nil
+		for _, part := range strings.Split(raw, ",") {
+			code, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil {
+				return false, "", fmt.Errorf("invalid expected_status '%s'", raw)
+			}
+			expectedStatus = append(expectedStatus, code)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return false, "", fmt.Errorf("failed to create request: %w", err)
+	}
+	for key, value := range params {
+		if strings.HasPrefix(key, "header_") {
+			req.Header.Set(strings.TrimPrefix(key, "header_"), value)
+		}
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, fmt.Sprintf("request failed: %v", err), nil
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 10*1024*1024))
+	if err != nil {
+		return false, fmt.Sprintf("failed to read response: %v", err), nil
+	}
+
+	statusMatched := false
+	for _, code := range expectedStatus {
+		if resp.StatusCode == code {
+			statusMatched = true
+			break
+		}
+	}
+	if !statusMatched {
+		return false, fmt.Sprintf("status code %d", resp.StatusCode), nil
+	}
+
+	jsonPath := params["json_path"]
+	if jsonPath == "" {
+		return true, string(body), nil
+	}
+
+	value, err := expr.Lookup(string(body), strings.TrimPrefix(jsonPath, "$"))
+	if err != nil {
+		return false, fmt.Sprintf("json_path %s: %v", jsonPath, err), nil
+	}
+	if expected, ok := params["expected_value"]; ok && expr.Format(value) != expected {
+		return false, fmt.Sprintf("json_path %s is '%s', waiting for '%s'", jsonPath, expr.Format(value), expected), nil
+	}
+	return true, expr.Format(value), nil
+}
+
+// WaitFileSensor waits for a file to exist. The path may be a glob pattern, the output
+// is a JSON array of the matching paths.
+func WaitFileSensor(ctx context.Context, params map[string]string, _ SensorOptions) (bool, string, error) {
+	pattern := params["path"]
+	if pattern == "" {
+		return false, "", fmt.Errorf("missing required parameter: path")
+	}
+
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return false, "", fmt.Errorf("invalid path pattern '%s': %w", pattern, err)
+	}
+
+	// a pattern without glob characters only matches an existing file
+	var existing []string
+	for _, match := range matches {
+		if _, err := os.Stat(match); err == nil {
+			existing = append(existing, match)
+		}
+	}
+	if len(existing) == 0 {
+		return false, fmt.Sprintf("no file matches %s", pattern), nil
+	}
+
+	output, err := json.Marshal(existing)
+	if err != nil {
+		return false, "", err
+	}
+	return true, string(output), nil
+}
+
+// WaitSQLSensor waits for a query to return at least one row. The output is the first
+// row as a JSON object. The database is one of the connections configured on the worker,
+// named by the connection parameter, so that a job cannot reach arbitrary hosts.
+func WaitSQLSensor(ctx context.Context, params map[string]string, opts SensorOptions) (bool, string, error) {
+	if _, ok := params["dsn"]; ok {
+		return false, "", fmt.Errorf("the dsn parameter is not supported, name a connection configured on the worker instead")
+	}
+	name := params["connection"]
+	if name == "" {
+		return false, "", fmt.Errorf("missing required parameter: connection")
+	}
+	dsn, ok := opts.SQLConnections[name]
+	if !ok {
+		return false, "", fmt.Errorf("unknown connection '%s'", name)
+	}
+	query := params["query"]
+	if query == "" {
+		return false, "", fmt.Errorf("missing required parameter: query")
+	}
+
+	conn, err := pgx.Connect(ctx, dsn)
+	if err != nil {
+		return false, fmt.Sprintf("failed to connect: %v", err), nil
+	}
+	defer conn.Close(context.WithoutCancel(ctx))
+
+	rows, err := conn.Query(ctx, query)
+	if err != nil {
+		return false, fmt.Sprintf("query failed: %v", err), nil
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return false, fmt.Sprintf("query failed: %v", err), nil
+		}
+		return false, "query returned no rows", nil
+	}
+
+	values, err := rows.Values()
+	if err != nil {
+		return false, fmt.Sprintf("failed to read row: %v", err), nil
+	}
+	row := make(map[string]interface{}, len(values))
+	for i, field := range rows.FieldDescriptions() {
+		row[field.Name] = values[i]
+	}
+
+	output, err := json.Marshal(row)
+	if err != nil {
+		return true, "", nil
+	}
+	return true, string(output), nil
+}
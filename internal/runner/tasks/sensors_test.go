@@ -0,0 +1,169 @@
+package tasks
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitHTTPSensor(t *testing.T) {
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "token", r.Header.Get("Authorization"))
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"build": {"state": "done", "steps": [{"name": "test"}]}}`))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		status   int
+		params   map[string]string
+		met      bool
+		expected string
+	}{
+		{
+			name:     "status matches",
+			status:   http.StatusOK,
+			params:   map[string]string{},
+			met:      true,
+			expected: `{"build": {"state": "done", "steps": [{"name": "test"}]}}`,
+		},
+		{
+			name:     "status does not match",
+			status:   http.StatusServiceUnavailable,
+			params:   map[string]string{},
+			expected: "status code 503",
+		},
+		{
+			name:   "one of several expected statuses",
+			status: http.StatusAccepted,
+			params: map[string]string{"expected_status": "200, 202"},
+			met:    true,
+		},
+		{
+			name:     "json_path value",
+			status:   http.StatusOK,
+			params:   map[string]string{"json_path": "$.build.steps[0].name"},
+			met:      true,
+			expected: "test",
+		},
+		{
+			name:     "json_path equals expected_value",
+			status:   http.StatusOK,
+			params:   map[string]string{"json_path": "$.build.state", "expected_value": "done"},
+			met:      true,
+			expected: "done",
+		},
+		{
+			name:     "json_path differs from expected_value",
+			status:   http.StatusOK,
+			params:   map[string]string{"json_path": "$.build.state", "expected_value": "failed"},
+			expected: "json_path $.build.state is 'done', waiting for 'failed'",
+		},
+		{
+			name:     "json_path not found",
+			status:   http.StatusOK,
+			params:   map[string]string{"json_path": "$.build.owner"},
+			expected: "json_path $.build.owner",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status = tt.status
+			params := map[string]string{"url": server.URL, "header_Authorization": "token"}
+			for k, v := range tt.params {
+				params[k] = v
+			}
+
+			met, output, err := WaitHTTPSensor(context.Background(), params, SensorOptions{})
+			require.NoError(t, err)
+			assert.Equal(t, tt.met, met)
+			if tt.met {
+				if tt.expected != "" {
+					assert.Equal(t, tt.expected, output)
+				}
+				return
+			}
+			assert.Contains(t, output, tt.expected)
+		})
+	}
+
+	_, _, err := WaitHTTPSensor(context.Background(), map[string]string{}, SensorOptions{})
+	assert.ErrorContains(t, err, "missing required parameter: url")
+
+	_, _, err = WaitHTTPSensor(context.Background(), map[string]string{"url": server.URL, "expected_status": "ok"}, SensorOptions{})
+	assert.ErrorContains(t, err, "invalid expected_status")
+}
+
+func TestWaitFileSensor(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "export-1.csv"), nil, 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "export-2.csv"), nil, 0600))
+
+	met, output, err := WaitFileSensor(context.Background(), map[string]string{"path": filepath.Join(dir, "*.csv")}, SensorOptions{})
+	require.NoError(t, err)
+	assert.True(t, met)
+	assert.JSONEq(t, `["`+filepath.Join(dir, "export-1.csv")+`", "`+filepath.Join(dir, "export-2.csv")+`"]`, output)
+
+	met, output, err = WaitFileSensor(context.Background(), map[string]string{"path": filepath.Join(dir, "done.flag")}, SensorOptions{})
+	require.NoError(t, err)
+	assert.False(t, met)
+	assert.Contains(t, output, "no file matches")
+
+	_, _, err = WaitFileSensor(context.Background(), map[string]string{"path": filepath.Join(dir, "[")}, SensorOptions{})
+	assert.ErrorContains(t, err, "invalid path pattern")
+
+	_, _, err = WaitFileSensor(context.Background(), map[string]string{}, SensorOptions{})
+	assert.ErrorContains(t, err, "missing required parameter: path")
+}
+
+func TestWaitSQLSensor_Connections(t *testing.T) {
+	opts := SensorOptions{SQLConnections: map[string]string{"warehouse": "postgres://localhost/warehouse"}}
+
+	tests := []struct {
+		name    string
+		params  map[string]string
+		wantErr string
+	}{
+		{
+			name:    "dsn from task parameters",
+			params:  map[string]string{"dsn": "postgres://attacker.example/db", "query": "SELECT 1"},
+			wantErr: "the dsn parameter is not supported",
+		},
+		{
+			name:    "dsn next to a connection",
+			params:  map[string]string{"dsn": "postgres://attacker.example/db", "connection": "warehouse", "query": "SELECT 1"},
+			wantErr: "the dsn parameter is not supported",
+		},
+		{
+			name:    "no connection",
+			params:  map[string]string{"query": "SELECT 1"},
+			wantErr: "missing required parameter: connection",
+		},
+		{
+			name:    "unknown connection",
+			params:  map[string]string{"connection": "orders", "query": "SELECT 1"},
+			wantErr: "unknown connection 'orders'",
+		},
+		{
+			name:    "no query",
+			params:  map[string]string{"connection": "warehouse"},
+			wantErr: "missing required parameter: query",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := WaitSQLSensor(context.Background(), tt.params, opts)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
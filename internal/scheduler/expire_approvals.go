@@ -0,0 +1,35 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+
+	"github.com/b0nbon1/stratal/internal/queue"
+	db "github.com/b0nbon1/stratal/internal/storage/db/sqlc"
+)
+
+// expireApprovals rejects approval tasks whose expiry elapsed and re-queues their job runs,
+// so the runs continue with the rejection
+func expireApprovals(q queue.TaskQueue, store *db.SQLStore, ctx context.Context) {
+	jobRunIDs, err := store.ExpireApprovals(ctx)
+	if err != nil {
+		log.Println("Error expiring approvals:", err)
+		return
+	}
+
+	for _, jobRunID := range jobRunIDs {
+		log.Printf("Approval of job run %s expired", jobRunID.String())
+		requeued, err := store.RequeueApprovedJobRun(ctx, jobRunID)
+		if err != nil {
+			log.Println("Error re-queueing job run with expired approval:", err)
+			continue
+		}
+		if requeued == 0 {
+			// the run is still being processed and notices the expiry itself
+			continue
+		}
+		if err := q.Enqueue(jobRunID.String()); err != nil {
+			log.Println("Error queueing job run with expired approval:", err)
+		}
+	}
+}
@@ -29,6 +29,25 @@ func StartScheduler(q queue.TaskQueue, store *db.SQLStore, ctx context.Context)
 			}
 		}
 	})
+	c.AddFunc("@every 30s", func() {
+		expireApprovals(q, store, ctx)
+	})
+	c.AddFunc("@every 10s", func() {
+		requeueDueSensors(q, store, ctx)
+	})
+	c.AddFunc("@every 10s", func() {
+		requeueDeferredJobRuns(q, store, ctx)
+	})
+	c.AddFunc("@every 1h", func() {
+		deleted, err := store.DeleteExpiredTaskCacheEntries(ctx)
+		if err != nil {
+			log.Println("Error deleting expired cache entries:", err)
+			return
+		}
+		if deleted > 0 {
+			log.Printf("Deleted %d expired cache entries", deleted)
+		}
+	})
 	c.Start()
 
 	return c
@@ -0,0 +1,25 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+
+	"github.com/b0nbon1/stratal/internal/queue"
+	db "github.com/b0nbon1/stratal/internal/storage/db/sqlc"
+)
+
+// requeueDeferredJobRuns queues job runs held back by the concurrency policy of their job
+// again once their retry time has passed
+func requeueDeferredJobRuns(q queue.TaskQueue, store *db.SQLStore, ctx context.Context) {
+	jobRunIDs, err := store.RequeueDeferredJobRuns(ctx)
+	if err != nil {
+		log.Println("Error re-queueing deferred job runs:", err)
+		return
+	}
+
+	for _, jobRunID := range jobRunIDs {
+		if err := q.Enqueue(jobRunID.String()); err != nil {
+			log.Println("Error queueing deferred job run:", err)
+		}
+	}
+}
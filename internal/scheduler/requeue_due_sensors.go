@@ -0,0 +1,24 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+
+	"github.com/b0nbon1/stratal/internal/queue"
+	db "github.com/b0nbon1/stratal/internal/storage/db/sqlc"
+)
+
+// requeueDueSensors re-queues parked job runs with a sensor whose next check is due
+func requeueDueSensors(q queue.TaskQueue, store *db.SQLStore, ctx context.Context) {
+	jobRunIDs, err := store.RequeueDueSensorJobRuns(ctx)
+	if err != nil {
+		log.Println("Error re-queueing job runs with due sensors:", err)
+		return
+	}
+
+	for _, jobRunID := range jobRunIDs {
+		if err := q.Enqueue(jobRunID.String()); err != nil {
+			log.Println("Error queueing job run with due sensor:", err)
+		}
+	}
+}
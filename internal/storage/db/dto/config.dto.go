@@ -1,13 +1,177 @@
 package dto
 
+// JobConfig holds settings that apply to a whole job run
+type JobConfig struct {
+	Timeout string `json:"timeout,omitempty" yaml:"timeout,omitempty"` // overall run deadline, e.g. "2h"
+	// Inputs declares the inputs a run of the job can be triggered with
+	Inputs []InputSpec `json:"inputs,omitempty" yaml:"inputs,omitempty"`
+	// OnFailure runs when the job run fails, after the completed tasks were compensated
+	OnFailure []HandlerTask `json:"on_failure,omitempty" yaml:"on_failure,omitempty"`
+	// Concurrency limits how many runs of the job execute at the same time
+	Concurrency *ConcurrencyConfig `json:"concurrency,omitempty" yaml:"concurrency,omitempty"`
+	// MaxOutputSize is the default MaxOutputSize of the tasks of the job
+	MaxOutputSize string `json:"max_output_size,omitempty" yaml:"max_output_size,omitempty"`
+	// Resources are the default resource limits of the custom tasks of the job, a task
+	// overrides them limit by limit
+	Resources *ResourcesConfig `json:"resources,omitempty" yaml:"resources,omitempty"`
+}
+
+// ConcurrencyConfig limits the runs of a job that have started and not finished yet, parked
+// runs waiting for an approval or a sensor included. OnConflict decides what happens to a
+// run that would exceed the limit.
+type ConcurrencyConfig struct {
+	MaxRuns    int    `json:"max_runs" yaml:"max_runs"`
+	OnConflict string `json:"on_conflict,omitempty" yaml:"on_conflict,omitempty"`
+}
+
+const (
+	ConcurrencyQueue          = "queue"           // default, the run waits until a slot frees up
+	ConcurrencySkip           = "skip"            // the run is not executed and ends as skipped
+	ConcurrencyCancelPrevious = "cancel_previous" // the oldest running runs are cancelled to make room
+)
+
+// InputSpec declares a run input. Tasks reference it as ${inputs.name} in their
+// parameters and scripts receive it as the INPUT_NAME environment variable.
+type InputSpec struct {
+	Name        string        `json:"name" yaml:"name"`
+	Type        string        `json:"type,omitempty" yaml:"type,omitempty"` // string (default), number, integer or boolean
+	Description string        `json:"description,omitempty" yaml:"description,omitempty"`
+	Default     interface{}   `json:"default,omitempty" yaml:"default,omitempty"`
+	Required    bool          `json:"required,omitempty" yaml:"required,omitempty"` // a required input without default must be supplied
+	Enum        []interface{} `json:"enum,omitempty" yaml:"enum,omitempty"`         // allowed values
+}
+
+const (
+	InputTypeString  = "string"
+	InputTypeNumber  = "number"
+	InputTypeInteger = "integer"
+	InputTypeBoolean = "boolean"
+)
+
 type TaskConfig struct {
 	DependsOn  []string          `json:"depends_on,omitempty" yaml:"depends_on,omitempty"`
 	Parameters map[string]string `json:"parameters,omitempty" yaml:"parameters,omitempty"`
 	Secrets    map[string]string `json:"secrets,omitempty" yaml:"secrets,omitempty"` // secret_name -> env_var_name
 	Script     *ScriptConfig     `json:"script,omitempty" yaml:"script,omitempty"`
+	Retry      *RetryConfig      `json:"retry,omitempty" yaml:"retry,omitempty"`
+	Timeout    string            `json:"timeout,omitempty" yaml:"timeout,omitempty"` // per attempt, e.g. "90s" or "45m"
+	When       string            `json:"when,omitempty" yaml:"when,omitempty"`       // condition over outputs, inputs and env, the task is skipped when false
+	// SkipPropagation decides how dependents see this task when it is skipped:
+	// "skip" (default) reports it as skipped, "none" as if the task had completed
+	SkipPropagation string `json:"skip_propagation,omitempty" yaml:"skip_propagation,omitempty"`
+	// TriggerRule decides, based on the outcome of the upstream tasks, whether the task runs
+	TriggerRule string `json:"trigger_rule,omitempty" yaml:"trigger_rule,omitempty"`
+	// AllowFailure keeps a failure of this task from failing the job run, dependents treat it as a success
+	AllowFailure bool `json:"allow_failure,omitempty" yaml:"allow_failure,omitempty"`
+	// ForEach expands the task at runtime into one parallel instance per item
+	ForEach *ForEachConfig `json:"for_each,omitempty" yaml:"for_each,omitempty"`
+	// Job is the job started and awaited by a task of type "job"
+	Job *SubJobConfig `json:"job,omitempty" yaml:"job,omitempty"`
+	// Approval configures a task of type "approval"
+	Approval *ApprovalConfig `json:"approval,omitempty" yaml:"approval,omitempty"`
+	// OnFailure runs right after the task failed, once its retries are exhausted
+	OnFailure []HandlerTask `json:"on_failure,omitempty" yaml:"on_failure,omitempty"`
+	// Compensate undoes the task once it completed and the job run fails later on
+	Compensate *HandlerTask `json:"compensate,omitempty" yaml:"compensate,omitempty"`
+	// Cache reuses the output of an earlier run of the task instead of executing it again
+	Cache *CacheConfig `json:"cache,omitempty" yaml:"cache,omitempty"`
+	// Artifacts are files a custom task receives from upstream tasks and produces for later ones
+	Artifacts *ArtifactsConfig `json:"artifacts,omitempty" yaml:"artifacts,omitempty"`
+	// MaxOutputSize bounds the output kept on the task run and passed to later tasks, e.g.
+	// "256KB". A larger output is truncated, the complete output is kept in the output store.
+	MaxOutputSize string `json:"max_output_size,omitempty" yaml:"max_output_size,omitempty"`
+	// Resources limits what the script of a custom task may use
+	Resources *ResourcesConfig `json:"resources,omitempty" yaml:"resources,omitempty"`
 }
 
+const (
+	SkipPropagationSkip = "skip"
+	SkipPropagationNone = "none"
+)
+
+const (
+	TriggerAllSuccess = "all_success" // default, every upstream task succeeded
+	TriggerAllDone    = "all_done"    // every upstream task finished, whatever the outcome
+	TriggerOneFailed  = "one_failed"  // at least one upstream task failed
+	TriggerOneSuccess = "one_success" // at least one upstream task succeeded
+	TriggerNoneFailed = "none_failed" // no upstream task failed, skipped ones are fine
+)
+
 type ScriptConfig struct {
 	Language string `json:"language" yaml:"language"`
 	Code     string `json:"code" yaml:"code"`
 }
+
+// ForEachConfig describes the items a fan-out task is expanded over. Either a static
+// matrix, whose combinations become instances, or an upstream task whose output is a JSON array.
+// Each instance receives ITEM (the item, JSON encoded unless it is a string) and ITEM_INDEX
+// as parameters, matrix instances additionally receive one parameter per matrix key.
+type ForEachConfig struct {
+	Matrix      map[string][]string `json:"matrix,omitempty" yaml:"matrix,omitempty"`
+	FromTask    string              `json:"from_task,omitempty" yaml:"from_task,omitempty"`
+	MaxParallel int                 `json:"max_parallel,omitempty" yaml:"max_parallel,omitempty"` // defaults to the level parallelism
+}
+
+// SubJobConfig references an existing job by ID or name. Inputs are passed to the child run
+// and may reference task outputs with ${TASK_OUTPUT.task_name} and run inputs with ${inputs.name}.
+type SubJobConfig struct {
+	JobID   string            `json:"job_id,omitempty" yaml:"job_id,omitempty"`
+	JobName string            `json:"job_name,omitempty" yaml:"job_name,omitempty"`
+	Inputs  map[string]string `json:"inputs,omitempty" yaml:"inputs,omitempty"`
+}
+
+// ApprovalConfig configures a manual approval gate. The run waits until the task run is
+// approved or rejected through the API, or until the expiry elapses, which rejects it.
+type ApprovalConfig struct {
+	Message string `json:"message,omitempty" yaml:"message,omitempty"` // shown to approvers
+	Expiry  string `json:"expiry,omitempty" yaml:"expiry,omitempty"`   // e.g. "24h", no expiry when empty
+}
+
+// CacheConfig opts a task into result caching. A task whose type, configuration, resolved
+// parameters and upstream outputs match an earlier successful run within the TTL is not
+// executed, its task run is marked cached and gets the stored output.
+type CacheConfig struct {
+	TTL string `json:"ttl,omitempty" yaml:"ttl,omitempty"` // e.g. "6h", defaults to 24h
+}
+
+// ArtifactsConfig declares the files a script task exchanges through STRATAL_ARTIFACTS_DIR.
+// Inputs name upstream tasks, all their artifacts are downloaded to <task name>/ within the
+// directory before the script runs. Outputs are paths or glob patterns relative to the
+// directory, the matching files are uploaded to the artifact store once the script succeeded.
+type ArtifactsConfig struct {
+	Inputs  []string `json:"inputs,omitempty" yaml:"inputs,omitempty"`
+	Outputs []string `json:"outputs,omitempty" yaml:"outputs,omitempty"`
+}
+
+// ResourcesConfig limits the script of a custom task, Linux only. Memory, CPU and processes
+// are enforced through a cgroup of the script where the worker has cgroup v2 available.
+// Without it memory is a best effort limit of each process and CPU and processes are not
+// limited. A script killed for breaching its cgroup memory or process limit or its disk
+// quota fails with a failure reason naming the limit.
+type ResourcesConfig struct {
+	Memory       string  `json:"memory,omitempty" yaml:"memory,omitempty"`                 // e.g. "512MB"
+	CPU          float64 `json:"cpu,omitempty" yaml:"cpu,omitempty"`                       // in cores, e.g. 0.5
+	MaxProcesses int     `json:"max_processes,omitempty" yaml:"max_processes,omitempty"`   // processes and threads
+	MaxOpenFiles int     `json:"max_open_files,omitempty" yaml:"max_open_files,omitempty"` // per process
+	Disk         string  `json:"disk,omitempty" yaml:"disk,omitempty"`                     // the script directory, measured while it runs, e.g. "1GB"
+}
+
+// HandlerTask is a builtin, custom or job task run when something fails. Besides its own
+// parameters it receives FAILED_TASK, FAILURE_ERROR and FAILURE_OUTPUTS (the outputs so far
+// as a JSON object), a compensation also COMPENSATED_TASK and COMPENSATED_OUTPUT.
+type HandlerTask struct {
+	Name   string     `json:"name" yaml:"name"`
+	Type   string     `json:"type" yaml:"type"`
+	Config TaskConfig `json:"config" yaml:"config"`
+}
+
+// RetryConfig controls how often a failing task is re-run and how long to wait between attempts.
+// Delays are Go duration strings such as "500ms", "10s" or "1m".
+type RetryConfig struct {
+	MaxAttempts      int      `json:"max_attempts" yaml:"max_attempts"`
+	InitialDelay     string   `json:"initial_delay,omitempty" yaml:"initial_delay,omitempty"`
+	Multiplier       float64  `json:"multiplier,omitempty" yaml:"multiplier,omitempty"`
+	MaxDelay         string   `json:"max_delay,omitempty" yaml:"max_delay,omitempty"`
+	RetryOnExitCodes []int    `json:"retry_on_exit_codes,omitempty" yaml:"retry_on_exit_codes,omitempty"` // only retry scripts exiting with these codes
+	RetryOnErrors    []string `json:"retry_on_errors,omitempty" yaml:"retry_on_errors,omitempty"`         // only retry errors matching these regular expressions
+}
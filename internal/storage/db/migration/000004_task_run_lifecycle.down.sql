@@ -0,0 +1,8 @@
+-- Skipped task runs fall back to pending
+UPDATE task_runs SET status = 'pending' WHERE status = 'skipped';
+
+-- Revert task_runs status constraint
+ALTER TABLE task_runs DROP CONSTRAINT IF EXISTS task_runs_status_check;
+ALTER TABLE task_runs ADD CONSTRAINT task_runs_status_check CHECK (
+    status IN ('pending', 'running', 'paused', 'failed', 'completed')
+);
@@ -0,0 +1,5 @@
+-- Add 'skipped' status to task_runs for tasks that never ran
+ALTER TABLE task_runs DROP CONSTRAINT IF EXISTS task_runs_status_check;
+ALTER TABLE task_runs ADD CONSTRAINT task_runs_status_check CHECK (
+    status IN ('pending', 'running', 'paused', 'failed', 'completed', 'skipped')
+);
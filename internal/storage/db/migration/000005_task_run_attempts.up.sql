@@ -0,0 +1,2 @@
+-- Track which attempt of a task a task run is on
+ALTER TABLE task_runs ADD COLUMN attempt INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,14 @@
+-- Job level settings such as the overall run timeout
+ALTER TABLE jobs ADD COLUMN config JSONB NOT NULL DEFAULT '{}';
+
+-- Add 'timed_out' status to job_runs
+ALTER TABLE job_runs DROP CONSTRAINT IF EXISTS job_runs_status_check;
+ALTER TABLE job_runs ADD CONSTRAINT job_runs_status_check CHECK (
+    status IN ('pending', 'queued', 'running', 'paused', 'failed', 'completed', 'timed_out')
+);
+
+-- Add 'timed_out' status to task_runs
+ALTER TABLE task_runs DROP CONSTRAINT IF EXISTS task_runs_status_check;
+ALTER TABLE task_runs ADD CONSTRAINT task_runs_status_check CHECK (
+    status IN ('pending', 'running', 'paused', 'failed', 'completed', 'skipped', 'timed_out')
+);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_task_runs_parent_task_run_id;
+DELETE FROM task_runs WHERE parent_task_run_id IS NOT NULL;
+ALTER TABLE task_runs DROP COLUMN IF EXISTS instance_index;
+ALTER TABLE task_runs DROP COLUMN IF EXISTS parent_task_run_id;
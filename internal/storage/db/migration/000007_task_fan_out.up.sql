@@ -0,0 +1,5 @@
+-- Fan-out tasks create one child task run per instance under the task run of the task
+ALTER TABLE task_runs ADD COLUMN parent_task_run_id UUID REFERENCES task_runs (id) ON DELETE CASCADE;
+ALTER TABLE task_runs ADD COLUMN instance_index INTEGER;
+
+CREATE INDEX idx_task_runs_parent_task_run_id ON task_runs (parent_task_run_id);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_job_runs_parent_task_run_id;
+DROP INDEX IF EXISTS idx_job_runs_parent_job_run_id;
+ALTER TABLE job_runs DROP COLUMN IF EXISTS parent_task_run_id;
+ALTER TABLE job_runs DROP COLUMN IF EXISTS parent_job_run_id;
@@ -0,0 +1,6 @@
+-- Runs started by a sub-job task link back to the job run and task run that started them
+ALTER TABLE job_runs ADD COLUMN parent_job_run_id UUID REFERENCES job_runs (id) ON DELETE CASCADE;
+ALTER TABLE job_runs ADD COLUMN parent_task_run_id UUID REFERENCES task_runs (id) ON DELETE SET NULL;
+
+CREATE INDEX idx_job_runs_parent_job_run_id ON job_runs (parent_job_run_id);
+CREATE INDEX idx_job_runs_parent_task_run_id ON job_runs (parent_task_run_id);
@@ -0,0 +1,13 @@
+-- Cancelled runs are reported as failed
+UPDATE job_runs SET status = 'failed' WHERE status = 'cancelled';
+UPDATE task_runs SET status = 'failed' WHERE status = 'cancelled';
+
+ALTER TABLE job_runs DROP CONSTRAINT IF EXISTS job_runs_status_check;
+ALTER TABLE job_runs ADD CONSTRAINT job_runs_status_check CHECK (
+    status IN ('pending', 'queued', 'running', 'paused', 'failed', 'completed', 'timed_out')
+);
+
+ALTER TABLE task_runs DROP CONSTRAINT IF EXISTS task_runs_status_check;
+ALTER TABLE task_runs ADD CONSTRAINT task_runs_status_check CHECK (
+    status IN ('pending', 'running', 'paused', 'failed', 'completed', 'skipped', 'timed_out')
+);
@@ -0,0 +1,2 @@
+-- Environment variables a script exported through its STRATAL_ENV file, passed to later tasks of the run
+ALTER TABLE task_runs ADD COLUMN exported_env JSONB;
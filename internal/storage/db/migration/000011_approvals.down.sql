@@ -0,0 +1,19 @@
+DROP INDEX IF EXISTS idx_task_runs_approval_expires_at;
+
+ALTER TABLE task_runs DROP COLUMN IF EXISTS approval_decided_at;
+ALTER TABLE task_runs DROP COLUMN IF EXISTS approval_comment;
+ALTER TABLE task_runs DROP COLUMN IF EXISTS approver;
+ALTER TABLE task_runs DROP COLUMN IF EXISTS approval_expires_at;
+
+UPDATE job_runs SET status = 'paused' WHERE status = 'waiting_approval';
+UPDATE task_runs SET status = 'paused' WHERE status = 'waiting_approval';
+
+ALTER TABLE job_runs DROP CONSTRAINT IF EXISTS job_runs_status_check;
+ALTER TABLE job_runs ADD CONSTRAINT job_runs_status_check CHECK (
+    status IN ('pending', 'queued', 'running', 'paused', 'failed', 'completed', 'timed_out', 'cancelled')
+);
+
+ALTER TABLE task_runs DROP CONSTRAINT IF EXISTS task_runs_status_check;
+ALTER TABLE task_runs ADD CONSTRAINT task_runs_status_check CHECK (
+    status IN ('pending', 'running', 'paused', 'failed', 'completed', 'skipped', 'timed_out', 'cancelled')
+);
@@ -0,0 +1,19 @@
+-- Add 'waiting_approval' status to job_runs
+ALTER TABLE job_runs DROP CONSTRAINT IF EXISTS job_runs_status_check;
+ALTER TABLE job_runs ADD CONSTRAINT job_runs_status_check CHECK (
+    status IN ('pending', 'queued', 'running', 'paused', 'waiting_approval', 'failed', 'completed', 'timed_out', 'cancelled')
+);
+
+-- Add 'waiting_approval' status to task_runs
+ALTER TABLE task_runs DROP CONSTRAINT IF EXISTS task_runs_status_check;
+ALTER TABLE task_runs ADD CONSTRAINT task_runs_status_check CHECK (
+    status IN ('pending', 'running', 'paused', 'waiting_approval', 'failed', 'completed', 'skipped', 'timed_out', 'cancelled')
+);
+
+-- Approval tasks record when they expire and who decided them
+ALTER TABLE task_runs ADD COLUMN approval_expires_at TIMESTAMP;
+ALTER TABLE task_runs ADD COLUMN approver TEXT;
+ALTER TABLE task_runs ADD COLUMN approval_comment TEXT;
+ALTER TABLE task_runs ADD COLUMN approval_decided_at TIMESTAMP;
+
+CREATE INDEX idx_task_runs_approval_expires_at ON task_runs (approval_expires_at) WHERE status = 'waiting_approval';
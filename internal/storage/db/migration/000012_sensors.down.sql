@@ -0,0 +1,18 @@
+DROP INDEX IF EXISTS idx_task_runs_sensor_next_poke_at;
+
+ALTER TABLE task_runs DROP COLUMN IF EXISTS sensor_pokes;
+ALTER TABLE task_runs DROP COLUMN IF EXISTS sensor_next_poke_at;
+ALTER TABLE task_runs DROP COLUMN IF EXISTS sensor_first_poke_at;
+
+UPDATE job_runs SET status = 'queued' WHERE status = 'up_for_reschedule';
+UPDATE task_runs SET status = 'pending' WHERE status = 'up_for_reschedule';
+
+ALTER TABLE job_runs DROP CONSTRAINT IF EXISTS job_runs_status_check;
+ALTER TABLE job_runs ADD CONSTRAINT job_runs_status_check CHECK (
+    status IN ('pending', 'queued', 'running', 'paused', 'waiting_approval', 'failed', 'completed', 'timed_out', 'cancelled')
+);
+
+ALTER TABLE task_runs DROP CONSTRAINT IF EXISTS task_runs_status_check;
+ALTER TABLE task_runs ADD CONSTRAINT task_runs_status_check CHECK (
+    status IN ('pending', 'running', 'paused', 'waiting_approval', 'failed', 'completed', 'skipped', 'timed_out', 'cancelled')
+);
@@ -0,0 +1,19 @@
+-- Add 'up_for_reschedule' status to job_runs
+ALTER TABLE job_runs DROP CONSTRAINT IF EXISTS job_runs_status_check;
+ALTER TABLE job_runs ADD CONSTRAINT job_runs_status_check CHECK (
+    status IN ('pending', 'queued', 'running', 'paused', 'waiting_approval', 'up_for_reschedule', 'failed', 'completed', 'timed_out', 'cancelled')
+);
+
+-- Add 'up_for_reschedule' status to task_runs
+ALTER TABLE task_runs DROP CONSTRAINT IF EXISTS task_runs_status_check;
+ALTER TABLE task_runs ADD CONSTRAINT task_runs_status_check CHECK (
+    status IN ('pending', 'running', 'paused', 'waiting_approval', 'up_for_reschedule', 'failed', 'completed', 'skipped', 'timed_out', 'cancelled')
+);
+
+-- Sensor tasks record when they first checked their condition, when they check it next
+-- and how often they checked it
+ALTER TABLE task_runs ADD COLUMN sensor_first_poke_at TIMESTAMP;
+ALTER TABLE task_runs ADD COLUMN sensor_next_poke_at TIMESTAMP;
+ALTER TABLE task_runs ADD COLUMN sensor_pokes INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX idx_task_runs_sensor_next_poke_at ON task_runs (sensor_next_poke_at) WHERE status = 'up_for_reschedule';
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_task_runs_handler_of_task_run_id;
+
+DELETE FROM task_runs WHERE handler IS NOT NULL;
+
+ALTER TABLE task_runs DROP COLUMN IF EXISTS handler_of_task_run_id;
+ALTER TABLE task_runs DROP COLUMN IF EXISTS handler_name;
+ALTER TABLE task_runs DROP COLUMN IF EXISTS handler;
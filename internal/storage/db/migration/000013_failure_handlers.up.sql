@@ -0,0 +1,7 @@
+-- On-failure handlers and compensations are recorded as task runs without a task. They
+-- name the handler and, for task level handlers and compensations, the task run they handle.
+ALTER TABLE task_runs ADD COLUMN handler TEXT CHECK (handler IN ('on_failure', 'compensate'));
+ALTER TABLE task_runs ADD COLUMN handler_name TEXT;
+ALTER TABLE task_runs ADD COLUMN handler_of_task_run_id UUID REFERENCES task_runs (id) ON DELETE CASCADE;
+
+CREATE INDEX idx_task_runs_handler_of_task_run_id ON task_runs (handler_of_task_run_id);
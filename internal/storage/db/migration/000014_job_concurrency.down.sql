@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_job_runs_job_id_status;
+DROP INDEX IF EXISTS idx_job_runs_deferred_until;
+
+ALTER TABLE job_runs DROP COLUMN IF EXISTS deferred_until;
+
+UPDATE job_runs SET status = 'cancelled' WHERE status = 'skipped';
+
+ALTER TABLE job_runs DROP CONSTRAINT IF EXISTS job_runs_status_check;
+ALTER TABLE job_runs ADD CONSTRAINT job_runs_status_check CHECK (
+    status IN ('pending', 'queued', 'running', 'paused', 'waiting_approval', 'up_for_reschedule', 'failed', 'completed', 'timed_out', 'cancelled')
+);
@@ -0,0 +1,11 @@
+-- Add 'skipped' status to job_runs, used for runs dropped by the concurrency policy of their job
+ALTER TABLE job_runs DROP CONSTRAINT IF EXISTS job_runs_status_check;
+ALTER TABLE job_runs ADD CONSTRAINT job_runs_status_check CHECK (
+    status IN ('pending', 'queued', 'running', 'paused', 'waiting_approval', 'up_for_reschedule', 'failed', 'completed', 'skipped', 'timed_out', 'cancelled')
+);
+
+-- Runs held back by the concurrency policy of their job are re-queued once deferred_until passes
+ALTER TABLE job_runs ADD COLUMN deferred_until TIMESTAMP;
+
+CREATE INDEX idx_job_runs_deferred_until ON job_runs (deferred_until) WHERE deferred_until IS NOT NULL;
+CREATE INDEX idx_job_runs_job_id_status ON job_runs (job_id, status);
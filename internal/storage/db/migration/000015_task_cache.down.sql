@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS task_cache;
+
+UPDATE task_runs SET status = 'completed' WHERE status = 'cached';
+
+ALTER TABLE task_runs DROP CONSTRAINT IF EXISTS task_runs_status_check;
+ALTER TABLE task_runs ADD CONSTRAINT task_runs_status_check CHECK (
+    status IN ('pending', 'running', 'paused', 'waiting_approval', 'up_for_reschedule', 'failed', 'completed', 'skipped', 'timed_out', 'cancelled')
+);
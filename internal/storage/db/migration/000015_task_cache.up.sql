@@ -0,0 +1,21 @@
+-- Add 'cached' status to task_runs, for tasks that reused a cached output instead of running
+ALTER TABLE task_runs DROP CONSTRAINT IF EXISTS task_runs_status_check;
+ALTER TABLE task_runs ADD CONSTRAINT task_runs_status_check CHECK (
+    status IN ('pending', 'running', 'paused', 'waiting_approval', 'up_for_reschedule', 'failed', 'completed', 'cached', 'skipped', 'timed_out', 'cancelled')
+);
+
+-- Outputs of tasks with caching enabled, keyed on a hash of everything that determines them
+CREATE TABLE task_cache (
+    key TEXT PRIMARY KEY,
+    job_id UUID NOT NULL REFERENCES jobs (id) ON DELETE CASCADE,
+    task_name TEXT NOT NULL,
+    output TEXT NOT NULL,
+    task_run_id UUID REFERENCES task_runs (id) ON DELETE SET NULL,
+    hits INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_hit_at TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_task_cache_job_id ON task_cache (job_id, task_name);
+CREATE INDEX idx_task_cache_expires_at ON task_cache (expires_at);
@@ -0,0 +1,15 @@
+-- Files uploaded by task runs to the artifact store, the content lives in the store under key
+CREATE TABLE task_artifacts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    job_run_id UUID NOT NULL REFERENCES job_runs (id) ON DELETE CASCADE,
+    task_run_id UUID NOT NULL REFERENCES task_runs (id) ON DELETE CASCADE,
+    task_name TEXT NOT NULL,
+    name TEXT NOT NULL,
+    key TEXT NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    sha256 TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (task_run_id, name)
+);
+
+CREATE INDEX idx_task_artifacts_job_run_id ON task_artifacts (job_run_id, task_name);
@@ -0,0 +1,2 @@
+ALTER TABLE task_runs DROP COLUMN IF EXISTS output_size;
+ALTER TABLE task_runs DROP COLUMN IF EXISTS output_key;
@@ -0,0 +1,4 @@
+-- Outputs larger than the inline limit are truncated in task_runs.output, the complete
+-- output is stored in the output store under output_key.
+ALTER TABLE task_runs ADD COLUMN output_key TEXT;
+ALTER TABLE task_runs ADD COLUMN output_size BIGINT;
@@ -0,0 +1,3 @@
+-- Why a task run failed when the error alone does not tell, e.g. the script breached one
+-- of its resource limits.
+ALTER TABLE task_runs ADD COLUMN failure_reason TEXT;
@@ -0,0 +1,3 @@
+ALTER TABLE task_cache DROP COLUMN IF EXISTS output_size;
+ALTER TABLE task_cache DROP COLUMN IF EXISTS output_key;
+ALTER TABLE task_cache DROP COLUMN IF EXISTS exported_env;
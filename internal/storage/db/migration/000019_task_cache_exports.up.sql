@@ -0,0 +1,5 @@
+-- A cache hit restores what the task run left besides its output: the variables it exported
+-- through STRATAL_ENV and the reference to its complete output in the output store.
+ALTER TABLE task_cache ADD COLUMN exported_env JSONB;
+ALTER TABLE task_cache ADD COLUMN output_key TEXT;
+ALTER TABLE task_cache ADD COLUMN output_size BIGINT;
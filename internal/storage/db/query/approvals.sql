@@ -0,0 +1,42 @@
+-- name: WaitForApproval :exec
+UPDATE task_runs
+SET status = 'waiting_approval',
+    started_at = COALESCE(started_at, CURRENT_TIMESTAMP),
+    approval_expires_at = $2,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: GetTaskRunApproval :one
+SELECT tr.id, tr.job_run_id, tr.task_id, t.name AS task_name, t.config, tr.status, tr.error_message,
+       tr.approval_expires_at, tr.approver, tr.approval_comment, tr.approval_decided_at
+FROM task_runs tr
+JOIN tasks t ON tr.task_id = t.id
+WHERE tr.id = $1;
+
+-- name: DecideApproval :one
+UPDATE task_runs
+SET status = $2,
+    output = $3,
+    error_message = $4,
+    approver = $5,
+    approval_comment = $6,
+    approval_decided_at = CURRENT_TIMESTAMP,
+    finished_at = CURRENT_TIMESTAMP,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND status = 'waiting_approval'
+RETURNING job_run_id;
+
+-- name: ExpireApprovals :many
+UPDATE task_runs
+SET status = 'failed',
+    error_message = 'Approval expired',
+    approval_decided_at = CURRENT_TIMESTAMP,
+    finished_at = CURRENT_TIMESTAMP,
+    updated_at = CURRENT_TIMESTAMP
+WHERE status = 'waiting_approval' AND approval_expires_at IS NOT NULL AND approval_expires_at <= CURRENT_TIMESTAMP
+RETURNING job_run_id;
+
+-- name: RequeueApprovedJobRun :execrows
+UPDATE job_runs
+SET status = 'queued', updated_at = NOW()
+WHERE id = $1 AND status = 'waiting_approval';
@@ -0,0 +1,30 @@
+-- name: LockJobConcurrency :exec
+SELECT pg_advisory_xact_lock(hashtext(sqlc.arg(job_id)::uuid::text));
+
+-- name: ListActiveJobRuns :many
+SELECT id
+FROM job_runs
+WHERE job_id = $1 AND id <> $2 AND started_at IS NOT NULL
+  AND status IN ('pending', 'queued', 'running', 'paused', 'waiting_approval', 'up_for_reschedule')
+ORDER BY started_at;
+
+-- name: AdmitJobRun :exec
+UPDATE job_runs
+SET started_at = COALESCE(started_at, CURRENT_TIMESTAMP), deferred_until = NULL, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: DeferJobRun :exec
+UPDATE job_runs
+SET deferred_until = CURRENT_TIMESTAMP + make_interval(secs => sqlc.arg(delay_seconds)::float8), updated_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: SkipJobRun :exec
+UPDATE job_runs
+SET status = 'skipped', error_message = $2, finished_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: RequeueDeferredJobRuns :many
+UPDATE job_runs
+SET deferred_until = NULL, updated_at = CURRENT_TIMESTAMP
+WHERE status IN ('pending', 'queued') AND deferred_until <= CURRENT_TIMESTAMP
+RETURNING id;
@@ -0,0 +1,11 @@
+-- name: CreateHandlerTaskRun :one
+INSERT INTO task_runs (job_run_id, status, handler, handler_name, handler_of_task_run_id)
+VALUES ($1, 'pending', $2, $3, $4)
+RETURNING id;
+
+-- name: ListCompensableTaskRuns :many
+SELECT tr.id, tr.task_id, tr.output
+FROM task_runs tr
+JOIN tasks t ON tr.task_id = t.id
+WHERE tr.job_run_id = $1 AND tr.parent_task_run_id IS NULL AND tr.status = 'completed'
+ORDER BY tr.finished_at DESC, t."order" DESC;
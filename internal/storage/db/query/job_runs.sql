@@ -1,6 +1,6 @@
 -- name: CreateJobRun :one
-INSERT INTO job_runs (job_id, status, triggered_by)
-VALUES ($1, $2, $3)
+INSERT INTO job_runs (job_id, status, triggered_by, metadata)
+VALUES ($1, $2, $3, $4)
 RETURNING id, job_id, status, started_at, finished_at, error_message, triggered_by, metadata, created_at;
 
 -- name: GetJobRun :one
@@ -29,6 +29,11 @@ UPDATE job_runs
 SET error_message = $2, updated_at = CURRENT_TIMESTAMP
 WHERE id = $1;
 
+-- name: FinishJobRun :exec
+UPDATE job_runs
+SET status = $2, finished_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
 -- name: DeleteJobRun :exec
 DELETE FROM job_runs
 WHERE id = $1;
@@ -45,7 +50,44 @@ GROUP BY jr.id;
 SELECT id, job_id, status, created_at, finished_at, started_at
 FROM job_runs
 WHERE status = 'pending'
+  AND (deferred_until IS NULL OR deferred_until <= NOW())
   AND (started_at IS NULL OR started_at < NOW() - INTERVAL '1 hour')
   AND (finished_at IS NULL OR finished_at > NOW() - INTERVAL '1 hour')
 ORDER BY created_at DESC LIMIT 30;
 
+-- name: CreateChildJobRun :one
+INSERT INTO job_runs (job_id, status, triggered_by, metadata, parent_job_run_id, parent_task_run_id, started_at)
+VALUES ($1, 'running', $2, $3, $4, $5, CURRENT_TIMESTAMP)
+RETURNING id;
+
+-- name: GetLatestChildJobRunByTaskRun :one
+SELECT id, job_id, status, error_message
+FROM job_runs
+WHERE parent_task_run_id = $1
+ORDER BY created_at DESC LIMIT 1;
+
+-- name: ListChildJobRuns :many
+SELECT id, job_id, status, started_at, finished_at, error_message, parent_task_run_id, created_at
+FROM job_runs
+WHERE parent_job_run_id = $1
+ORDER BY created_at;
+
+-- name: CancelJobRun :exec
+UPDATE job_runs
+SET status = 'cancelled', error_message = $2, finished_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: ParkJobRun :execrows
+UPDATE job_runs
+SET status = CASE
+        WHEN EXISTS (SELECT 1 FROM task_runs WHERE job_run_id = $1 AND status = 'waiting_approval') THEN 'waiting_approval'
+        ELSE 'up_for_reschedule'
+    END,
+    updated_at = NOW()
+WHERE id = $1 AND status = 'running'
+  AND EXISTS (SELECT 1 FROM task_runs WHERE job_run_id = $1 AND status IN ('waiting_approval', 'up_for_reschedule'));
+
+-- name: StartJobRun :execrows
+UPDATE job_runs
+SET status = 'running', started_at = COALESCE(started_at, CURRENT_TIMESTAMP), finished_at = NULL, error_message = NULL, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND status IN ('pending', 'queued');
@@ -0,0 +1,32 @@
+-- name: GetTaskRunSensor :one
+SELECT id, status, sensor_first_poke_at, sensor_next_poke_at, sensor_pokes,
+       (sensor_next_poke_at IS NULL OR sensor_next_poke_at <= CURRENT_TIMESTAMP)::boolean AS due
+FROM task_runs
+WHERE id = $1;
+
+-- name: StartSensorPoke :one
+UPDATE task_runs
+SET status = 'running',
+    started_at = COALESCE(started_at, CURRENT_TIMESTAMP),
+    sensor_first_poke_at = COALESCE(sensor_first_poke_at, CURRENT_TIMESTAMP),
+    sensor_pokes = sensor_pokes + 1,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+RETURNING sensor_pokes, EXTRACT(EPOCH FROM (CURRENT_TIMESTAMP - sensor_first_poke_at))::float8 AS waited_seconds;
+
+-- name: RescheduleSensor :exec
+UPDATE task_runs
+SET status = 'up_for_reschedule',
+    sensor_next_poke_at = CURRENT_TIMESTAMP + make_interval(secs => sqlc.arg(interval_seconds)::float8),
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: RequeueDueSensorJobRuns :many
+UPDATE job_runs
+SET status = 'queued', updated_at = NOW()
+WHERE status IN ('waiting_approval', 'up_for_reschedule')
+  AND id IN (
+    SELECT job_run_id FROM task_runs
+    WHERE status = 'up_for_reschedule' AND sensor_next_poke_at <= CURRENT_TIMESTAMP
+  )
+RETURNING id;
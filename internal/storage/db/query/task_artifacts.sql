@@ -0,0 +1,21 @@
+-- name: CreateTaskArtifact :one
+INSERT INTO task_artifacts (job_run_id, task_run_id, task_name, name, key, size_bytes, sha256)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (task_run_id, name) DO UPDATE
+SET key = EXCLUDED.key,
+    size_bytes = EXCLUDED.size_bytes,
+    sha256 = EXCLUDED.sha256,
+    created_at = CURRENT_TIMESTAMP
+RETURNING id, job_run_id, task_run_id, task_name, name, key, size_bytes, sha256, created_at;
+
+-- name: GetTaskArtifact :one
+SELECT id, job_run_id, task_run_id, task_name, name, key, size_bytes, sha256, created_at
+FROM task_artifacts
+WHERE id = $1 AND job_run_id = $2;
+
+-- name: ListJobRunArtifacts :many
+SELECT id, job_run_id, task_run_id, task_name, name, key, size_bytes, sha256, created_at
+FROM task_artifacts
+WHERE job_run_id = $1
+  AND (sqlc.narg(task_name)::text IS NULL OR task_name = sqlc.narg(task_name))
+ORDER BY created_at ASC, name ASC;
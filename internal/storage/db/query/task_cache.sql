@@ -0,0 +1,46 @@
+-- name: HitTaskCache :one
+UPDATE task_cache
+SET hits = hits + 1, last_hit_at = CURRENT_TIMESTAMP
+WHERE key = $1 AND expires_at > CURRENT_TIMESTAMP
+RETURNING output, task_run_id, exported_env, output_key, output_size;
+
+-- name: PutTaskCacheEntry :exec
+INSERT INTO task_cache (key, job_id, task_name, output, task_run_id, exported_env, output_key, output_size, expires_at)
+SELECT $1, $2, $3, $4, tr.id, tr.exported_env, tr.output_key, tr.output_size, CURRENT_TIMESTAMP + make_interval(secs => sqlc.arg(ttl_seconds)::float8)
+FROM task_runs tr
+WHERE tr.id = sqlc.arg(task_run_id)
+ON CONFLICT (key) DO UPDATE
+SET output = EXCLUDED.output,
+    task_run_id = EXCLUDED.task_run_id,
+    exported_env = EXCLUDED.exported_env,
+    output_key = EXCLUDED.output_key,
+    output_size = EXCLUDED.output_size,
+    hits = 0,
+    created_at = CURRENT_TIMESTAMP,
+    last_hit_at = NULL,
+    expires_at = EXCLUDED.expires_at;
+
+-- name: GetTaskCacheEntry :one
+SELECT key, job_id, task_name, output, task_run_id, hits, created_at, last_hit_at, expires_at, exported_env, output_key, output_size
+FROM task_cache
+WHERE key = $1;
+
+-- name: ListTaskCacheEntries :many
+SELECT key, job_id, task_name, output, task_run_id, hits, created_at, last_hit_at, expires_at, exported_env, output_key, output_size
+FROM task_cache
+WHERE (sqlc.narg(job_id)::uuid IS NULL OR job_id = sqlc.narg(job_id))
+  AND (sqlc.narg(task_name)::text IS NULL OR task_name = sqlc.narg(task_name))
+ORDER BY created_at DESC;
+
+-- name: DeleteTaskCacheEntry :execrows
+DELETE FROM task_cache
+WHERE key = $1;
+
+-- name: DeleteTaskCacheEntries :execrows
+DELETE FROM task_cache
+WHERE job_id = $1
+  AND (sqlc.narg(task_name)::text IS NULL OR task_name = sqlc.narg(task_name));
+
+-- name: DeleteExpiredTaskCacheEntries :execrows
+DELETE FROM task_cache
+WHERE expires_at <= CURRENT_TIMESTAMP;
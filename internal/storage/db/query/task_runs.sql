@@ -11,7 +11,7 @@ WHERE id = $1 LIMIT 1;
 -- name: GetTaskRunByJobRunAndTaskID :one
 SELECT id, job_run_id, task_id, status, started_at, finished_at, exit_code, output, error_message, created_at
 FROM task_runs
-WHERE job_run_id = $1 AND task_id = $2 LIMIT 1;
+WHERE job_run_id = $1 AND task_id = $2 AND parent_task_run_id IS NULL LIMIT 1;
 
 -- name: ListTaskRuns :many
 SELECT id, job_run_id, task_id, status, started_at, finished_at, exit_code, output, error_message, created_at
@@ -49,3 +49,62 @@ WHERE id = $1;
 -- name: DeleteTaskRun :exec
 DELETE FROM task_runs
 WHERE id = $1;
+
+-- name: StartTaskRun :exec
+UPDATE task_runs
+SET status = 'running', attempt = $2, started_at = CURRENT_TIMESTAMP, finished_at = NULL, exit_code = NULL, error_message = NULL, failure_reason = NULL, output_key = NULL, output_size = NULL, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: FinishTaskRun :exec
+UPDATE task_runs
+SET status = $2, exit_code = $3, output = $4, error_message = $5, failure_reason = $6, finished_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: SkipPendingTaskRuns :exec
+UPDATE task_runs
+SET status = 'skipped', finished_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+WHERE job_run_id = $1 AND status = 'pending';
+
+-- name: ListTaskRunsWithTaskName :many
+SELECT tr.id, tr.task_id, t.name AS task_name, tr.status, tr.output
+FROM task_runs tr
+JOIN tasks t ON tr.task_id = t.id
+WHERE tr.job_run_id = $1 AND tr.parent_task_run_id IS NULL
+ORDER BY t."order";
+
+-- name: CreateTaskRunInstance :one
+INSERT INTO task_runs (job_run_id, task_id, status, parent_task_run_id, instance_index)
+VALUES ($1, $2, 'pending', $3, $4)
+RETURNING id;
+
+-- name: ListTaskRunInstances :many
+SELECT id, instance_index, status, output
+FROM task_runs
+WHERE parent_task_run_id = $1
+ORDER BY instance_index;
+
+-- name: CancelUnfinishedTaskRuns :exec
+UPDATE task_runs
+SET status = 'cancelled', finished_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+WHERE job_run_id = $1 AND status IN ('pending', 'running', 'paused', 'waiting_approval', 'up_for_reschedule');
+
+-- name: SetTaskRunExportedEnv :exec
+UPDATE task_runs
+SET exported_env = $2, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: ListTaskRunExportedEnv :many
+SELECT exported_env
+FROM task_runs
+WHERE job_run_id = $1 AND status IN ('completed', 'cached') AND exported_env IS NOT NULL
+ORDER BY finished_at, id;
+
+-- name: SetTaskRunOutputSpill :exec
+UPDATE task_runs
+SET output_key = $2, output_size = $3, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: GetTaskRunOutput :one
+SELECT id, output, output_key, output_size
+FROM task_runs
+WHERE id = $1 LIMIT 1;
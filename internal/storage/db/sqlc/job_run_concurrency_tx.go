@@ -0,0 +1,91 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/b0nbon1/stratal/internal/storage/db/dto"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// AdmitJobRunParams describes a run about to start and the concurrency policy of its job
+type AdmitJobRunParams struct {
+	JobRunID   pgtype.UUID
+	JobID      pgtype.UUID
+	MaxRuns    int
+	OnConflict string
+	RetryAfter time.Duration // how long a queued run waits before it tries again
+}
+
+// AdmitJobRunResult reports whether the run may start. Runs cancelled to make room for it
+// still have to be stopped on the workers executing them.
+type AdmitJobRunResult struct {
+	Admitted        bool
+	ActiveRuns      int
+	CancelledRunIDs []pgtype.UUID
+}
+
+// AdmitJobRunTx marks a run as started if fewer than MaxRuns other runs of its job have
+// started and not finished yet, the worker then moves it to running with StartJobRun.
+// Otherwise the run is deferred, skipped or the oldest runs are cancelled, depending on
+// OnConflict. An advisory lock on the job serializes admissions of its runs across workers,
+// so the limit holds however many workers pick up runs at once.
+func (store *SQLStore) AdmitJobRunTx(ctx context.Context, arg AdmitJobRunParams) (AdmitJobRunResult, error) {
+	var result AdmitJobRunResult
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		result, err = admitRun(ctx, q, arg)
+		return err
+	})
+	return result, err
+}
+
+// admitRun applies the concurrency policy within the transaction of AdmitJobRunTx
+func admitRun(ctx context.Context, q *Queries, arg AdmitJobRunParams) (AdmitJobRunResult, error) {
+	var result AdmitJobRunResult
+	if err := q.LockJobConcurrency(ctx, arg.JobID); err != nil {
+		return result, fmt.Errorf("unable to lock job concurrency: %w", err)
+	}
+
+	active, err := q.ListActiveJobRuns(ctx, ListActiveJobRunsParams{
+		JobID: arg.JobID,
+		ID:    arg.JobRunID,
+	})
+	if err != nil {
+		return result, fmt.Errorf("unable to list active job runs: %w", err)
+	}
+	result.ActiveRuns = len(active)
+
+	if len(active) >= arg.MaxRuns {
+		switch arg.OnConflict {
+		case dto.ConcurrencySkip:
+			return result, q.SkipJobRun(ctx, SkipJobRunParams{
+				ID:           arg.JobRunID,
+				ErrorMessage: pgtype.Text{String: fmt.Sprintf("Skipped, %d run(s) of the job are already active", len(active)), Valid: true},
+			})
+		case dto.ConcurrencyCancelPrevious:
+			// the oldest runs go first, until this run fits
+			for _, jobRunID := range active[:len(active)-arg.MaxRuns+1] {
+				if err := q.CancelJobRun(ctx, CancelJobRunParams{
+					ID:           jobRunID,
+					ErrorMessage: pgtype.Text{String: fmt.Sprintf("Cancelled by newer run %s", arg.JobRunID.String()), Valid: true},
+				}); err != nil {
+					return result, fmt.Errorf("unable to cancel job_run %s: %w", jobRunID.String(), err)
+				}
+				if err := q.CancelUnfinishedTaskRuns(ctx, jobRunID); err != nil {
+					return result, fmt.Errorf("unable to cancel task runs of job_run %s: %w", jobRunID.String(), err)
+				}
+				result.CancelledRunIDs = append(result.CancelledRunIDs, jobRunID)
+			}
+		default:
+			return result, q.DeferJobRun(ctx, DeferJobRunParams{
+				ID:           arg.JobRunID,
+				DelaySeconds: arg.RetryAfter.Seconds(),
+			})
+		}
+	}
+
+	result.Admitted = true
+	return result, q.AdmitJobRun(ctx, arg.JobRunID)
+}
@@ -0,0 +1,165 @@
+package db
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/b0nbon1/stratal/internal/storage/db/dto"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingDB records the queries it executes and lists the given runs as active
+type recordingDB struct {
+	active  []pgtype.UUID
+	queries []string
+	args    [][]interface{}
+}
+
+func (d *recordingDB) record(sql string, args []interface{}) {
+	// the query name is the first line, "-- name: SkipJobRun :exec"
+	name := strings.Fields(strings.SplitN(sql, "\n", 2)[0])[2]
+	d.queries = append(d.queries, name)
+	d.args = append(d.args, args)
+}
+
+func (d *recordingDB) Exec(_ context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	d.record(sql, args)
+	return pgconn.NewCommandTag("UPDATE 1"), nil
+}
+
+func (d *recordingDB) Query(_ context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	d.record(sql, args)
+	return &uuidRows{ids: d.active, pos: -1}, nil
+}
+
+func (d *recordingDB) QueryRow(_ context.Context, sql string, args ...interface{}) pgx.Row {
+	d.record(sql, args)
+	return nil
+}
+
+// uuidRows returns one uuid per row
+type uuidRows struct {
+	pgx.Rows
+	ids []pgtype.UUID
+	pos int
+}
+
+func (r *uuidRows) Next() bool {
+	r.pos++
+	return r.pos < len(r.ids)
+}
+
+func (r *uuidRows) Scan(dest ...interface{}) error {
+	*dest[0].(*pgtype.UUID) = r.ids[r.pos]
+	return nil
+}
+
+func (r *uuidRows) Close()     {}
+func (r *uuidRows) Err() error { return nil }
+
+func testUUID(b byte) pgtype.UUID {
+	return pgtype.UUID{Bytes: [16]byte{b}, Valid: true}
+}
+
+func TestAdmitRun(t *testing.T) {
+	runID := testUUID(9)
+	older, old := testUUID(1), testUUID(2)
+
+	tests := []struct {
+		name       string
+		active     []pgtype.UUID
+		maxRuns    int
+		onConflict string
+		admitted   bool
+		cancelled  []pgtype.UUID
+		queries    []string
+	}{
+		{
+			name:     "below the limit",
+			active:   []pgtype.UUID{older},
+			maxRuns:  2,
+			admitted: true,
+			queries:  []string{"LockJobConcurrency", "ListActiveJobRuns", "AdmitJobRun"},
+		},
+		{
+			name:       "queue defers the run",
+			active:     []pgtype.UUID{older, old},
+			maxRuns:    2,
+			onConflict: dto.ConcurrencyQueue,
+			queries:    []string{"LockJobConcurrency", "ListActiveJobRuns", "DeferJobRun"},
+		},
+		{
+			name:    "queue by default",
+			active:  []pgtype.UUID{older},
+			maxRuns: 1,
+			queries: []string{"LockJobConcurrency", "ListActiveJobRuns", "DeferJobRun"},
+		},
+		{
+			name:       "skip",
+			active:     []pgtype.UUID{older},
+			maxRuns:    1,
+			onConflict: dto.ConcurrencySkip,
+			queries:    []string{"LockJobConcurrency", "ListActiveJobRuns", "SkipJobRun"},
+		},
+		{
+			name:       "cancel_previous cancels the oldest runs until the run fits",
+			active:     []pgtype.UUID{older, old},
+			maxRuns:    2,
+			onConflict: dto.ConcurrencyCancelPrevious,
+			admitted:   true,
+			cancelled:  []pgtype.UUID{older},
+			queries:    []string{"LockJobConcurrency", "ListActiveJobRuns", "CancelJobRun", "CancelUnfinishedTaskRuns", "AdmitJobRun"},
+		},
+		{
+			name:       "cancel_previous cancels every active run with max_runs 1",
+			active:     []pgtype.UUID{older, old},
+			maxRuns:    1,
+			onConflict: dto.ConcurrencyCancelPrevious,
+			admitted:   true,
+			cancelled:  []pgtype.UUID{older, old},
+			queries: []string{"LockJobConcurrency", "ListActiveJobRuns",
+				"CancelJobRun", "CancelUnfinishedTaskRuns", "CancelJobRun", "CancelUnfinishedTaskRuns", "AdmitJobRun"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := &recordingDB{active: tt.active}
+			result, err := admitRun(context.Background(), New(conn), AdmitJobRunParams{
+				JobRunID:   runID,
+				JobID:      testUUID(5),
+				MaxRuns:    tt.maxRuns,
+				OnConflict: tt.onConflict,
+				RetryAfter: 30 * time.Second,
+			})
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.admitted, result.Admitted)
+			assert.Equal(t, len(tt.active), result.ActiveRuns)
+			assert.Equal(t, tt.cancelled, result.CancelledRunIDs)
+			assert.Equal(t, tt.queries, conn.queries)
+			// the lock is taken on the job before anything else
+			assert.Equal(t, testUUID(5), conn.args[0][0])
+		})
+	}
+}
+
+func TestAdmitRun_DeferredByRetryAfter(t *testing.T) {
+	conn := &recordingDB{active: []pgtype.UUID{testUUID(1)}}
+	_, err := admitRun(context.Background(), New(conn), AdmitJobRunParams{
+		JobRunID:   testUUID(9),
+		JobID:      testUUID(5),
+		MaxRuns:    1,
+		RetryAfter: 45 * time.Second,
+	})
+	require.NoError(t, err)
+
+	last := conn.args[len(conn.args)-1]
+	assert.Equal(t, []interface{}{testUUID(9), 45.0}, last)
+}
@@ -12,7 +12,7 @@ type JobRunResult struct {
 	TaskRunIds []string
 }
 
-func (store *SQLStore) CreateJobRunTx(ctx context.Context, jobID pgtype.UUID, triggeredBy string) (JobRunResult, error) {
+func (store *SQLStore) CreateJobRunTx(ctx context.Context, jobID pgtype.UUID, triggeredBy string, metadata []byte) (JobRunResult, error) {
 	var result JobRunResult
 
 	err := store.execTx(ctx, func(q *Queries) error {
@@ -20,35 +20,83 @@ func (store *SQLStore) CreateJobRunTx(ctx context.Context, jobID pgtype.UUID, tr
 			JobID:       jobID,
 			TriggeredBy: pgtype.Text{String: triggeredBy, Valid: true},
 			Status:      pgtype.Text{String: "pending", Valid: true},
+			Metadata:    metadata,
 		})
 		if err != nil {
 			return fmt.Errorf("unable to create job_run %w", err)
 		}
 
-		// Step 2: Fetch all tasks for the job
-		tasks, err := q.GetTasksByJobID(ctx, jobID)
+		// Step 2: Create task_runs for all tasks of the job
+		taskRunIDs, err := createTaskRuns(ctx, q, jobID, jobRun.ID)
 		if err != nil {
-			return fmt.Errorf("failed to get tasks: %w", err)
+			return err
 		}
 
-		// Step 3: Create task_runs
-		var taskRunIDs []string
-		for _, task := range tasks {
-			taskRun, err := q.CreateTaskRun(ctx, CreateTaskRunParams{
-				JobRunID: jobRun.ID,
-				TaskID:   task.ID,
-				Status:   pgtype.Text{String: "pending", Valid: true},
-			})
-			if err != nil {
-				return fmt.Errorf("failed to create task run: %w", err)
-			}
-			taskRunIDs = append(taskRunIDs, taskRun.ID.String())
+		result.JobRunId = jobRun.ID.String() // Fixed: use jobRun.ID instead of jobID
+		result.TaskRunIds = taskRunIDs
+		return nil
+	})
+
+	return result, err
+}
+
+// ChildJobRunParams describes a job run started by a sub-job task of another run
+type ChildJobRunParams struct {
+	JobID           pgtype.UUID
+	ParentJobRunID  pgtype.UUID
+	ParentTaskRunID pgtype.UUID
+	TriggeredBy     string
+	Metadata        []byte
+}
+
+// CreateChildJobRunTx creates a job run linked to the task run that started it. The run
+// is created as running because the parent's worker executes it directly instead of queueing it.
+func (store *SQLStore) CreateChildJobRunTx(ctx context.Context, arg ChildJobRunParams) (JobRunResult, error) {
+	var result JobRunResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		jobRunID, err := q.CreateChildJobRun(ctx, CreateChildJobRunParams{
+			JobID:           arg.JobID,
+			TriggeredBy:     pgtype.Text{String: arg.TriggeredBy, Valid: true},
+			Metadata:        arg.Metadata,
+			ParentJobRunID:  arg.ParentJobRunID,
+			ParentTaskRunID: arg.ParentTaskRunID,
+		})
+		if err != nil {
+			return fmt.Errorf("unable to create child job_run %w", err)
 		}
 
-		result.JobRunId = jobRun.ID.String() // Fixed: use jobRun.ID instead of jobID
+		taskRunIDs, err := createTaskRuns(ctx, q, arg.JobID, jobRunID)
+		if err != nil {
+			return err
+		}
+
+		result.JobRunId = jobRunID.String()
 		result.TaskRunIds = taskRunIDs
 		return nil
 	})
 
 	return result, err
 }
+
+// createTaskRuns creates a pending task run for every task of a job
+func createTaskRuns(ctx context.Context, q *Queries, jobID, jobRunID pgtype.UUID) ([]string, error) {
+	tasks, err := q.GetTasksByJobID(ctx, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tasks: %w", err)
+	}
+
+	var taskRunIDs []string
+	for _, task := range tasks {
+		taskRun, err := q.CreateTaskRun(ctx, CreateTaskRunParams{
+			JobRunID: jobRunID,
+			TaskID:   task.ID,
+			Status:   pgtype.Text{String: "pending", Valid: true},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create task run: %w", err)
+		}
+		taskRunIDs = append(taskRunIDs, taskRun.ID.String())
+	}
+	return taskRunIDs, nil
+}
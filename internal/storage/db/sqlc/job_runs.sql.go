@@ -11,9 +11,52 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const cancelJobRun = `-- name: CancelJobRun :exec
+UPDATE job_runs
+SET status = 'cancelled', error_message = $2, finished_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+`
+
+type CancelJobRunParams struct {
+	ID           pgtype.UUID `json:"id"`
+	ErrorMessage pgtype.Text `json:"error_message"`
+}
+
+func (q *Queries) CancelJobRun(ctx context.Context, arg CancelJobRunParams) error {
+	_, err := q.db.Exec(ctx, cancelJobRun, arg.ID, arg.ErrorMessage)
+	return err
+}
+
+const createChildJobRun = `-- name: CreateChildJobRun :one
+INSERT INTO job_runs (job_id, status, triggered_by, metadata, parent_job_run_id, parent_task_run_id, started_at)
+VALUES ($1, 'running', $2, $3, $4, $5, CURRENT_TIMESTAMP)
+RETURNING id
+`
+
+type CreateChildJobRunParams struct {
+	JobID           pgtype.UUID `json:"job_id"`
+	TriggeredBy     pgtype.Text `json:"triggered_by"`
+	Metadata        []byte      `json:"metadata"`
+	ParentJobRunID  pgtype.UUID `json:"parent_job_run_id"`
+	ParentTaskRunID pgtype.UUID `json:"parent_task_run_id"`
+}
+
+func (q *Queries) CreateChildJobRun(ctx context.Context, arg CreateChildJobRunParams) (pgtype.UUID, error) {
+	row := q.db.QueryRow(ctx, createChildJobRun,
+		arg.JobID,
+		arg.TriggeredBy,
+		arg.Metadata,
+		arg.ParentJobRunID,
+		arg.ParentTaskRunID,
+	)
+	var id pgtype.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
 const createJobRun = `-- name: CreateJobRun :one
-INSERT INTO job_runs (job_id, status, triggered_by)
-VALUES ($1, $2, $3)
+INSERT INTO job_runs (job_id, status, triggered_by, metadata)
+VALUES ($1, $2, $3, $4)
 RETURNING id, job_id, status, started_at, finished_at, error_message, triggered_by, metadata, created_at
 `
 
@@ -21,6 +64,7 @@ type CreateJobRunParams struct {
 	JobID       pgtype.UUID `json:"job_id"`
 	Status      pgtype.Text `json:"status"`
 	TriggeredBy pgtype.Text `json:"triggered_by"`
+	Metadata    []byte      `json:"metadata"`
 }
 
 type CreateJobRunRow struct {
@@ -36,7 +80,12 @@ type CreateJobRunRow struct {
 }
 
 func (q *Queries) CreateJobRun(ctx context.Context, arg CreateJobRunParams) (CreateJobRunRow, error) {
-	row := q.db.QueryRow(ctx, createJobRun, arg.JobID, arg.Status, arg.TriggeredBy)
+	row := q.db.QueryRow(ctx, createJobRun,
+		arg.JobID,
+		arg.Status,
+		arg.TriggeredBy,
+		arg.Metadata,
+	)
 	var i CreateJobRunRow
 	err := row.Scan(
 		&i.ID,
@@ -62,6 +111,22 @@ func (q *Queries) DeleteJobRun(ctx context.Context, id pgtype.UUID) error {
 	return err
 }
 
+const finishJobRun = `-- name: FinishJobRun :exec
+UPDATE job_runs
+SET status = $2, finished_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+`
+
+type FinishJobRunParams struct {
+	ID     pgtype.UUID `json:"id"`
+	Status pgtype.Text `json:"status"`
+}
+
+func (q *Queries) FinishJobRun(ctx context.Context, arg FinishJobRunParams) error {
+	_, err := q.db.Exec(ctx, finishJobRun, arg.ID, arg.Status)
+	return err
+}
+
 const getJobRun = `-- name: GetJobRun :one
 SELECT id, job_id, status, started_at, finished_at, error_message, triggered_by, metadata, created_at
 FROM job_runs
@@ -97,6 +162,32 @@ func (q *Queries) GetJobRun(ctx context.Context, id pgtype.UUID) (GetJobRunRow,
 	return i, err
 }
 
+const getLatestChildJobRunByTaskRun = `-- name: GetLatestChildJobRunByTaskRun :one
+SELECT id, job_id, status, error_message
+FROM job_runs
+WHERE parent_task_run_id = $1
+ORDER BY created_at DESC LIMIT 1
+`
+
+type GetLatestChildJobRunByTaskRunRow struct {
+	ID           pgtype.UUID `json:"id"`
+	JobID        pgtype.UUID `json:"job_id"`
+	Status       pgtype.Text `json:"status"`
+	ErrorMessage pgtype.Text `json:"error_message"`
+}
+
+func (q *Queries) GetLatestChildJobRunByTaskRun(ctx context.Context, parentTaskRunID pgtype.UUID) (GetLatestChildJobRunByTaskRunRow, error) {
+	row := q.db.QueryRow(ctx, getLatestChildJobRunByTaskRun, parentTaskRunID)
+	var i GetLatestChildJobRunByTaskRunRow
+	err := row.Scan(
+		&i.ID,
+		&i.JobID,
+		&i.Status,
+		&i.ErrorMessage,
+	)
+	return i, err
+}
+
 const jobRunsWithTasks = `-- name: JobRunsWithTasks :one
 SELECT jr.id, jr.job_id, jr.status, jr.started_at, jr.finished_at, jr.error_message, jr.triggered_by, jr.metadata, jr.created_at,
        json_agg(tr.*) AS task_runs
@@ -137,6 +228,53 @@ func (q *Queries) JobRunsWithTasks(ctx context.Context, id pgtype.UUID) (JobRuns
 	return i, err
 }
 
+const listChildJobRuns = `-- name: ListChildJobRuns :many
+SELECT id, job_id, status, started_at, finished_at, error_message, parent_task_run_id, created_at
+FROM job_runs
+WHERE parent_job_run_id = $1
+ORDER BY created_at
+`
+
+type ListChildJobRunsRow struct {
+	ID              pgtype.UUID        `json:"id"`
+	JobID           pgtype.UUID        `json:"job_id"`
+	Status          pgtype.Text        `json:"status"`
+	StartedAt       pgtype.Timestamp   `json:"started_at"`
+	FinishedAt      pgtype.Timestamp   `json:"finished_at"`
+	ErrorMessage    pgtype.Text        `json:"error_message"`
+	ParentTaskRunID pgtype.UUID        `json:"parent_task_run_id"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+}
+
+func (q *Queries) ListChildJobRuns(ctx context.Context, parentJobRunID pgtype.UUID) ([]ListChildJobRunsRow, error) {
+	rows, err := q.db.Query(ctx, listChildJobRuns, parentJobRunID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListChildJobRunsRow{}
+	for rows.Next() {
+		var i ListChildJobRunsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.JobID,
+			&i.Status,
+			&i.StartedAt,
+			&i.FinishedAt,
+			&i.ErrorMessage,
+			&i.ParentTaskRunID,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listJobRuns = `-- name: ListJobRuns :many
 SELECT id, job_id, status, started_at, finished_at, error_message, triggered_by, metadata, created_at
 FROM job_runs
@@ -190,6 +328,7 @@ const listPendingJobRuns = `-- name: ListPendingJobRuns :many
 SELECT id, job_id, status, created_at, finished_at, started_at
 FROM job_runs
 WHERE status = 'pending'
+  AND (deferred_until IS NULL OR deferred_until <= NOW())
   AND (started_at IS NULL OR started_at < NOW() - INTERVAL '1 hour')
   AND (finished_at IS NULL OR finished_at > NOW() - INTERVAL '1 hour')
 ORDER BY created_at DESC LIMIT 30
@@ -231,6 +370,39 @@ func (q *Queries) ListPendingJobRuns(ctx context.Context) ([]ListPendingJobRunsR
 	return items, nil
 }
 
+const parkJobRun = `-- name: ParkJobRun :execrows
+UPDATE job_runs
+SET status = CASE
+        WHEN EXISTS (SELECT 1 FROM task_runs WHERE job_run_id = $1 AND status = 'waiting_approval') THEN 'waiting_approval'
+        ELSE 'up_for_reschedule'
+    END,
+    updated_at = NOW()
+WHERE id = $1 AND status = 'running'
+  AND EXISTS (SELECT 1 FROM task_runs WHERE job_run_id = $1 AND status IN ('waiting_approval', 'up_for_reschedule'))
+`
+
+func (q *Queries) ParkJobRun(ctx context.Context, id pgtype.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, parkJobRun, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const startJobRun = `-- name: StartJobRun :execrows
+UPDATE job_runs
+SET status = 'running', started_at = COALESCE(started_at, CURRENT_TIMESTAMP), finished_at = NULL, error_message = NULL, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND status IN ('pending', 'queued')
+`
+
+func (q *Queries) StartJobRun(ctx context.Context, id pgtype.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, startJobRun, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const updateJobRun = `-- name: UpdateJobRun :exec
 UPDATE job_runs
 SET status = $2, started_at = $3, finished_at = $4, error_message = $5, triggered_by = $6, metadata = $7, updated_at = CURRENT_TIMESTAMP
@@ -8,20 +8,22 @@ package db
 import (
 	"context"
 
+	dto "github.com/b0nbon1/stratal/internal/storage/db/dto"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
 const createJob = `-- name: CreateJob :one
-INSERT INTO jobs (name, description, source, raw_payload)
-VALUES ($1, $2, $3, $4)
-RETURNING id, user_id, name, description, source, created_at
+INSERT INTO jobs (name, description, source, raw_payload, config)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, user_id, name, description, source, config, created_at
 `
 
 type CreateJobParams struct {
-	Name        string      `json:"name"`
-	Description pgtype.Text `json:"description"`
-	Source      string      `json:"source"`
-	RawPayload  []byte      `json:"raw_payload"`
+	Name        string        `json:"name"`
+	Description pgtype.Text   `json:"description"`
+	Source      string        `json:"source"`
+	RawPayload  []byte        `json:"raw_payload"`
+	Config      dto.JobConfig `json:"config"`
 }
 
 type CreateJobRow struct {
@@ -30,6 +32,7 @@ type CreateJobRow struct {
 	Name        string             `json:"name"`
 	Description pgtype.Text        `json:"description"`
 	Source      string             `json:"source"`
+	Config      dto.JobConfig      `json:"config"`
 	CreatedAt   pgtype.Timestamptz `json:"created_at"`
 }
 
@@ -39,6 +42,7 @@ func (q *Queries) CreateJob(ctx context.Context, arg CreateJobParams) (CreateJob
 		arg.Description,
 		arg.Source,
 		arg.RawPayload,
+		arg.Config,
 	)
 	var i CreateJobRow
 	err := row.Scan(
@@ -47,6 +51,7 @@ func (q *Queries) CreateJob(ctx context.Context, arg CreateJobParams) (CreateJob
 		&i.Name,
 		&i.Description,
 		&i.Source,
+		&i.Config,
 		&i.CreatedAt,
 	)
 	return i, err
@@ -63,7 +68,7 @@ func (q *Queries) DeleteJob(ctx context.Context, id pgtype.UUID) error {
 }
 
 const getJob = `-- name: GetJob :one
-SELECT id, user_id, name, description, source, created_at FROM jobs
+SELECT id, user_id, name, description, source, config, created_at FROM jobs
 WHERE id = $1 LIMIT 1
 `
 
@@ -73,6 +78,7 @@ type GetJobRow struct {
 	Name        string             `json:"name"`
 	Description pgtype.Text        `json:"description"`
 	Source      string             `json:"source"`
+	Config      dto.JobConfig      `json:"config"`
 	CreatedAt   pgtype.Timestamptz `json:"created_at"`
 }
 
@@ -85,13 +91,45 @@ func (q *Queries) GetJob(ctx context.Context, id pgtype.UUID) (GetJobRow, error)
 		&i.Name,
 		&i.Description,
 		&i.Source,
+		&i.Config,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getJobByName = `-- name: GetJobByName :one
+SELECT id, user_id, name, description, source, config, created_at FROM jobs
+WHERE name = $1
+ORDER BY created_at DESC LIMIT 1
+`
+
+type GetJobByNameRow struct {
+	ID          pgtype.UUID        `json:"id"`
+	UserID      pgtype.UUID        `json:"user_id"`
+	Name        string             `json:"name"`
+	Description pgtype.Text        `json:"description"`
+	Source      string             `json:"source"`
+	Config      dto.JobConfig      `json:"config"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+}
+
+func (q *Queries) GetJobByName(ctx context.Context, name string) (GetJobByNameRow, error) {
+	row := q.db.QueryRow(ctx, getJobByName, name)
+	var i GetJobByNameRow
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.Description,
+		&i.Source,
+		&i.Config,
 		&i.CreatedAt,
 	)
 	return i, err
 }
 
 const getJobWithTasks = `-- name: GetJobWithTasks :one
-SELECT j.id, j.user_id, j.name, j.description, j.source, j.created_at,
+SELECT j.id, j.user_id, j.name, j.description, j.source, j.config, j.created_at,
        json_agg(t.*) AS tasks
 FROM jobs j
 LEFT JOIN tasks t ON j.id = t.job_id
@@ -105,6 +143,7 @@ type GetJobWithTasksRow struct {
 	Name        string             `json:"name"`
 	Description pgtype.Text        `json:"description"`
 	Source      string             `json:"source"`
+	Config      dto.JobConfig      `json:"config"`
 	CreatedAt   pgtype.Timestamptz `json:"created_at"`
 	Tasks       []byte             `json:"tasks"`
 }
@@ -118,6 +157,7 @@ func (q *Queries) GetJobWithTasks(ctx context.Context, id pgtype.UUID) (GetJobWi
 		&i.Name,
 		&i.Description,
 		&i.Source,
+		&i.Config,
 		&i.CreatedAt,
 		&i.Tasks,
 	)
@@ -18,20 +18,24 @@ type Job struct {
 	RawPayload  []byte             `json:"raw_payload"`
 	CreatedAt   pgtype.Timestamptz `json:"created_at"`
 	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
+	Config      dto.JobConfig      `json:"config"`
 }
 
 type JobRun struct {
-	ID           pgtype.UUID        `json:"id"`
-	JobID        pgtype.UUID        `json:"job_id"`
-	Status       pgtype.Text        `json:"status"`
-	StartedAt    pgtype.Timestamp   `json:"started_at"`
-	FinishedAt   pgtype.Timestamp   `json:"finished_at"`
-	ErrorMessage pgtype.Text        `json:"error_message"`
-	TriggeredBy  pgtype.Text        `json:"triggered_by"`
-	Metadata     []byte             `json:"metadata"`
-	CreatedAt    pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt    pgtype.Timestamptz `json:"updated_at"`
-	PausedAt     pgtype.Timestamp   `json:"paused_at"`
+	ID              pgtype.UUID        `json:"id"`
+	JobID           pgtype.UUID        `json:"job_id"`
+	Status          pgtype.Text        `json:"status"`
+	StartedAt       pgtype.Timestamp   `json:"started_at"`
+	FinishedAt      pgtype.Timestamp   `json:"finished_at"`
+	ErrorMessage    pgtype.Text        `json:"error_message"`
+	TriggeredBy     pgtype.Text        `json:"triggered_by"`
+	Metadata        []byte             `json:"metadata"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
+	PausedAt        pgtype.Timestamp   `json:"paused_at"`
+	ParentJobRunID  pgtype.UUID        `json:"parent_job_run_id"`
+	ParentTaskRunID pgtype.UUID        `json:"parent_task_run_id"`
+	DeferredUntil   pgtype.Timestamp   `json:"deferred_until"`
 }
 
 type Log struct {
@@ -67,19 +71,63 @@ type Task struct {
 	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
 }
 
+type TaskArtifact struct {
+	ID        pgtype.UUID      `json:"id"`
+	JobRunID  pgtype.UUID      `json:"job_run_id"`
+	TaskRunID pgtype.UUID      `json:"task_run_id"`
+	TaskName  string           `json:"task_name"`
+	Name      string           `json:"name"`
+	Key       string           `json:"key"`
+	SizeBytes int64            `json:"size_bytes"`
+	Sha256    string           `json:"sha256"`
+	CreatedAt pgtype.Timestamp `json:"created_at"`
+}
+
+type TaskCache struct {
+	Key         string           `json:"key"`
+	JobID       pgtype.UUID      `json:"job_id"`
+	TaskName    string           `json:"task_name"`
+	Output      string           `json:"output"`
+	TaskRunID   pgtype.UUID      `json:"task_run_id"`
+	Hits        int32            `json:"hits"`
+	CreatedAt   pgtype.Timestamp `json:"created_at"`
+	LastHitAt   pgtype.Timestamp `json:"last_hit_at"`
+	ExpiresAt   pgtype.Timestamp `json:"expires_at"`
+	ExportedEnv []byte           `json:"exported_env"`
+	OutputKey   pgtype.Text      `json:"output_key"`
+	OutputSize  pgtype.Int8      `json:"output_size"`
+}
+
 type TaskRun struct {
-	ID           pgtype.UUID        `json:"id"`
-	JobRunID     pgtype.UUID        `json:"job_run_id"`
-	TaskID       pgtype.UUID        `json:"task_id"`
-	Status       pgtype.Text        `json:"status"`
-	StartedAt    pgtype.Timestamp   `json:"started_at"`
-	FinishedAt   pgtype.Timestamp   `json:"finished_at"`
-	ExitCode     pgtype.Int4        `json:"exit_code"`
-	Output       pgtype.Text        `json:"output"`
-	ErrorMessage pgtype.Text        `json:"error_message"`
-	CreatedAt    pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt    pgtype.Timestamptz `json:"updated_at"`
-	PausedAt     pgtype.Timestamp   `json:"paused_at"`
+	ID                 pgtype.UUID        `json:"id"`
+	JobRunID           pgtype.UUID        `json:"job_run_id"`
+	TaskID             pgtype.UUID        `json:"task_id"`
+	Status             pgtype.Text        `json:"status"`
+	StartedAt          pgtype.Timestamp   `json:"started_at"`
+	FinishedAt         pgtype.Timestamp   `json:"finished_at"`
+	ExitCode           pgtype.Int4        `json:"exit_code"`
+	Output             pgtype.Text        `json:"output"`
+	ErrorMessage       pgtype.Text        `json:"error_message"`
+	CreatedAt          pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt          pgtype.Timestamptz `json:"updated_at"`
+	PausedAt           pgtype.Timestamp   `json:"paused_at"`
+	Attempt            int32              `json:"attempt"`
+	ParentTaskRunID    pgtype.UUID        `json:"parent_task_run_id"`
+	InstanceIndex      pgtype.Int4        `json:"instance_index"`
+	ExportedEnv        []byte             `json:"exported_env"`
+	ApprovalExpiresAt  pgtype.Timestamp   `json:"approval_expires_at"`
+	Approver           pgtype.Text        `json:"approver"`
+	ApprovalComment    pgtype.Text        `json:"approval_comment"`
+	ApprovalDecidedAt  pgtype.Timestamp   `json:"approval_decided_at"`
+	SensorFirstPokeAt  pgtype.Timestamp   `json:"sensor_first_poke_at"`
+	SensorNextPokeAt   pgtype.Timestamp   `json:"sensor_next_poke_at"`
+	SensorPokes        int32              `json:"sensor_pokes"`
+	Handler            pgtype.Text        `json:"handler"`
+	HandlerName        pgtype.Text        `json:"handler_name"`
+	HandlerOfTaskRunID pgtype.UUID        `json:"handler_of_task_run_id"`
+	OutputKey          pgtype.Text        `json:"output_key"`
+	OutputSize         pgtype.Int8        `json:"output_size"`
+	FailureReason      pgtype.Text        `json:"failure_reason"`
 }
 
 type User struct {
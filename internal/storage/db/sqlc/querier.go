@@ -11,10 +11,15 @@ import (
 )
 
 type Querier interface {
+	AdmitJobRun(ctx context.Context, id pgtype.UUID) error
+	CancelJobRun(ctx context.Context, arg CancelJobRunParams) error
+	CancelUnfinishedTaskRuns(ctx context.Context, jobRunID pgtype.UUID) error
 	CountLogsByJobRun(ctx context.Context, jobRunID pgtype.UUID) (int64, error)
 	CountLogsByTaskRun(ctx context.Context, taskRunID pgtype.UUID) (int64, error)
 	CountLogsByType(ctx context.Context, type_ string) (int64, error)
 	CreateBulkTasks(ctx context.Context, arg CreateBulkTasksParams) ([]CreateBulkTasksRow, error)
+	CreateChildJobRun(ctx context.Context, arg CreateChildJobRunParams) (pgtype.UUID, error)
+	CreateHandlerTaskRun(ctx context.Context, arg CreateHandlerTaskRunParams) (pgtype.UUID, error)
 	CreateJob(ctx context.Context, arg CreateJobParams) (CreateJobRow, error)
 	CreateJobLog(ctx context.Context, arg CreateJobLogParams) error
 	CreateJobRun(ctx context.Context, arg CreateJobRunParams) (CreateJobRunRow, error)
@@ -22,8 +27,13 @@ type Querier interface {
 	CreateSecret(ctx context.Context, arg CreateSecretParams) (CreateSecretRow, error)
 	CreateSystemLog(ctx context.Context, arg CreateSystemLogParams) error
 	CreateTask(ctx context.Context, arg CreateTaskParams) (CreateTaskRow, error)
+	CreateTaskArtifact(ctx context.Context, arg CreateTaskArtifactParams) (TaskArtifact, error)
 	CreateTaskLog(ctx context.Context, arg CreateTaskLogParams) error
 	CreateTaskRun(ctx context.Context, arg CreateTaskRunParams) (CreateTaskRunRow, error)
+	CreateTaskRunInstance(ctx context.Context, arg CreateTaskRunInstanceParams) (pgtype.UUID, error)
+	DecideApproval(ctx context.Context, arg DecideApprovalParams) (pgtype.UUID, error)
+	DeferJobRun(ctx context.Context, arg DeferJobRunParams) error
+	DeleteExpiredTaskCacheEntries(ctx context.Context) (int64, error)
 	DeleteJob(ctx context.Context, id pgtype.UUID) error
 	DeleteJobRun(ctx context.Context, id pgtype.UUID) error
 	DeleteLog(ctx context.Context, id int64) error
@@ -32,20 +42,37 @@ type Querier interface {
 	DeleteLogsByType(ctx context.Context, type_ string) error
 	DeleteSecret(ctx context.Context, arg DeleteSecretParams) error
 	DeleteTask(ctx context.Context, id pgtype.UUID) error
+	DeleteTaskCacheEntries(ctx context.Context, arg DeleteTaskCacheEntriesParams) (int64, error)
+	DeleteTaskCacheEntry(ctx context.Context, key string) (int64, error)
 	DeleteTaskRun(ctx context.Context, id pgtype.UUID) error
+	ExpireApprovals(ctx context.Context) ([]pgtype.UUID, error)
+	FinishJobRun(ctx context.Context, arg FinishJobRunParams) error
+	FinishTaskRun(ctx context.Context, arg FinishTaskRunParams) error
 	GetJob(ctx context.Context, id pgtype.UUID) (GetJobRow, error)
+	GetJobByName(ctx context.Context, name string) (GetJobByNameRow, error)
 	GetJobRun(ctx context.Context, id pgtype.UUID) (GetJobRunRow, error)
 	GetJobRunWithPauseInfo(ctx context.Context, id pgtype.UUID) (GetJobRunWithPauseInfoRow, error)
 	GetJobWithTasks(ctx context.Context, id pgtype.UUID) (GetJobWithTasksRow, error)
+	GetLatestChildJobRunByTaskRun(ctx context.Context, parentTaskRunID pgtype.UUID) (GetLatestChildJobRunByTaskRunRow, error)
 	GetLog(ctx context.Context, id int64) (Log, error)
 	GetPausedJobRuns(ctx context.Context) ([]JobRun, error)
 	GetSecret(ctx context.Context, arg GetSecretParams) (GetSecretRow, error)
 	GetSecretByName(ctx context.Context, arg GetSecretByNameParams) (GetSecretByNameRow, error)
 	GetTask(ctx context.Context, id pgtype.UUID) (GetTaskRow, error)
+	GetTaskArtifact(ctx context.Context, arg GetTaskArtifactParams) (TaskArtifact, error)
+	GetTaskCacheEntry(ctx context.Context, key string) (TaskCache, error)
 	GetTaskRun(ctx context.Context, id pgtype.UUID) (GetTaskRunRow, error)
+	GetTaskRunApproval(ctx context.Context, id pgtype.UUID) (GetTaskRunApprovalRow, error)
 	GetTaskRunByJobRunAndTaskID(ctx context.Context, arg GetTaskRunByJobRunAndTaskIDParams) (GetTaskRunByJobRunAndTaskIDRow, error)
+	GetTaskRunOutput(ctx context.Context, id pgtype.UUID) (GetTaskRunOutputRow, error)
+	GetTaskRunSensor(ctx context.Context, id pgtype.UUID) (GetTaskRunSensorRow, error)
 	GetTasksByJobID(ctx context.Context, jobID pgtype.UUID) ([]GetTasksByJobIDRow, error)
+	HitTaskCache(ctx context.Context, key string) (HitTaskCacheRow, error)
 	JobRunsWithTasks(ctx context.Context, id pgtype.UUID) (JobRunsWithTasksRow, error)
+	ListActiveJobRuns(ctx context.Context, arg ListActiveJobRunsParams) ([]pgtype.UUID, error)
+	ListChildJobRuns(ctx context.Context, parentJobRunID pgtype.UUID) ([]ListChildJobRunsRow, error)
+	ListCompensableTaskRuns(ctx context.Context, jobRunID pgtype.UUID) ([]ListCompensableTaskRunsRow, error)
+	ListJobRunArtifacts(ctx context.Context, arg ListJobRunArtifactsParams) ([]TaskArtifact, error)
 	ListJobRuns(ctx context.Context, jobID pgtype.UUID) ([]ListJobRunsRow, error)
 	ListJobs(ctx context.Context, arg ListJobsParams) ([]ListJobsRow, error)
 	ListLogs(ctx context.Context, arg ListLogsParams) ([]Log, error)
@@ -58,13 +85,31 @@ type Querier interface {
 	ListPendingJobRuns(ctx context.Context) ([]ListPendingJobRunsRow, error)
 	ListSecrets(ctx context.Context, userID pgtype.UUID) ([]ListSecretsRow, error)
 	ListSystemLogs(ctx context.Context, arg ListSystemLogsParams) ([]Log, error)
+	ListTaskCacheEntries(ctx context.Context, arg ListTaskCacheEntriesParams) ([]TaskCache, error)
+	ListTaskRunExportedEnv(ctx context.Context, jobRunID pgtype.UUID) ([][]byte, error)
+	ListTaskRunInstances(ctx context.Context, parentTaskRunID pgtype.UUID) ([]ListTaskRunInstancesRow, error)
 	ListTaskRuns(ctx context.Context, jobRunID pgtype.UUID) ([]ListTaskRunsRow, error)
 	ListTaskRunsByJob(ctx context.Context, id pgtype.UUID) ([]ListTaskRunsByJobRow, error)
+	ListTaskRunsWithTaskName(ctx context.Context, jobRunID pgtype.UUID) ([]ListTaskRunsWithTaskNameRow, error)
 	ListTasks(ctx context.Context, jobID pgtype.UUID) ([]ListTasksRow, error)
+	LockJobConcurrency(ctx context.Context, jobID pgtype.UUID) error
+	ParkJobRun(ctx context.Context, id pgtype.UUID) (int64, error)
 	PauseJobRun(ctx context.Context, id pgtype.UUID) error
 	PauseTaskRun(ctx context.Context, id pgtype.UUID) error
+	PutTaskCacheEntry(ctx context.Context, arg PutTaskCacheEntryParams) error
+	RequeueApprovedJobRun(ctx context.Context, id pgtype.UUID) (int64, error)
+	RequeueDeferredJobRuns(ctx context.Context) ([]pgtype.UUID, error)
+	RequeueDueSensorJobRuns(ctx context.Context) ([]pgtype.UUID, error)
+	RescheduleSensor(ctx context.Context, arg RescheduleSensorParams) error
 	ResumeJobRun(ctx context.Context, id pgtype.UUID) error
 	ResumeTaskRun(ctx context.Context, id pgtype.UUID) error
+	SetTaskRunExportedEnv(ctx context.Context, arg SetTaskRunExportedEnvParams) error
+	SetTaskRunOutputSpill(ctx context.Context, arg SetTaskRunOutputSpillParams) error
+	SkipJobRun(ctx context.Context, arg SkipJobRunParams) error
+	SkipPendingTaskRuns(ctx context.Context, jobRunID pgtype.UUID) error
+	StartJobRun(ctx context.Context, id pgtype.UUID) (int64, error)
+	StartSensorPoke(ctx context.Context, id pgtype.UUID) (StartSensorPokeRow, error)
+	StartTaskRun(ctx context.Context, arg StartTaskRunParams) error
 	UpdateJob(ctx context.Context, arg UpdateJobParams) error
 	UpdateJobRun(ctx context.Context, arg UpdateJobRunParams) error
 	UpdateJobRunError(ctx context.Context, arg UpdateJobRunErrorParams) error
@@ -75,6 +120,7 @@ type Querier interface {
 	UpdateTaskRunError(ctx context.Context, arg UpdateTaskRunErrorParams) error
 	UpdateTaskRunOutput(ctx context.Context, arg UpdateTaskRunOutputParams) error
 	UpdateTaskRunStatus(ctx context.Context, arg UpdateTaskRunStatusParams) error
+	WaitForApproval(ctx context.Context, arg WaitForApprovalParams) error
 }
 
 var _ Querier = (*Queries)(nil)
@@ -11,7 +11,9 @@ import (
 type Store interface {
 	Querier
 	CreateJobWithTasksTx(ctx context.Context, jobParams CreateJobParams, taskInputs []CreateTaskParams) (JobWithTaskResult, error)
-	CreateJobRunTx(ctx context.Context, jobID pgtype.UUID, triggeredBy string) (JobRunResult, error)
+	CreateJobRunTx(ctx context.Context, jobID pgtype.UUID, triggeredBy string, metadata []byte) (JobRunResult, error)
+	CreateChildJobRunTx(ctx context.Context, arg ChildJobRunParams) (JobRunResult, error)
+	AdmitJobRunTx(ctx context.Context, arg AdmitJobRunParams) (AdmitJobRunResult, error)
 }
 
 // SQLStore provides all functions to execute SQL queries and transactions
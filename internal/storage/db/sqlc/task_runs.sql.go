@@ -11,6 +11,17 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const cancelUnfinishedTaskRuns = `-- name: CancelUnfinishedTaskRuns :exec
+UPDATE task_runs
+SET status = 'cancelled', finished_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+WHERE job_run_id = $1 AND status IN ('pending', 'running', 'paused', 'waiting_approval', 'up_for_reschedule')
+`
+
+func (q *Queries) CancelUnfinishedTaskRuns(ctx context.Context, jobRunID pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, cancelUnfinishedTaskRuns, jobRunID)
+	return err
+}
+
 const createTaskRun = `-- name: CreateTaskRun :one
 INSERT INTO task_runs (job_run_id, task_id, status)
 VALUES ($1, $2, $3)
@@ -54,6 +65,31 @@ func (q *Queries) CreateTaskRun(ctx context.Context, arg CreateTaskRunParams) (C
 	return i, err
 }
 
+const createTaskRunInstance = `-- name: CreateTaskRunInstance :one
+INSERT INTO task_runs (job_run_id, task_id, status, parent_task_run_id, instance_index)
+VALUES ($1, $2, 'pending', $3, $4)
+RETURNING id
+`
+
+type CreateTaskRunInstanceParams struct {
+	JobRunID        pgtype.UUID `json:"job_run_id"`
+	TaskID          pgtype.UUID `json:"task_id"`
+	ParentTaskRunID pgtype.UUID `json:"parent_task_run_id"`
+	InstanceIndex   pgtype.Int4 `json:"instance_index"`
+}
+
+func (q *Queries) CreateTaskRunInstance(ctx context.Context, arg CreateTaskRunInstanceParams) (pgtype.UUID, error) {
+	row := q.db.QueryRow(ctx, createTaskRunInstance,
+		arg.JobRunID,
+		arg.TaskID,
+		arg.ParentTaskRunID,
+		arg.InstanceIndex,
+	)
+	var id pgtype.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
 const deleteTaskRun = `-- name: DeleteTaskRun :exec
 DELETE FROM task_runs
 WHERE id = $1
@@ -64,6 +100,33 @@ func (q *Queries) DeleteTaskRun(ctx context.Context, id pgtype.UUID) error {
 	return err
 }
 
+const finishTaskRun = `-- name: FinishTaskRun :exec
+UPDATE task_runs
+SET status = $2, exit_code = $3, output = $4, error_message = $5, failure_reason = $6, finished_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+`
+
+type FinishTaskRunParams struct {
+	ID            pgtype.UUID `json:"id"`
+	Status        pgtype.Text `json:"status"`
+	ExitCode      pgtype.Int4 `json:"exit_code"`
+	Output        pgtype.Text `json:"output"`
+	ErrorMessage  pgtype.Text `json:"error_message"`
+	FailureReason pgtype.Text `json:"failure_reason"`
+}
+
+func (q *Queries) FinishTaskRun(ctx context.Context, arg FinishTaskRunParams) error {
+	_, err := q.db.Exec(ctx, finishTaskRun,
+		arg.ID,
+		arg.Status,
+		arg.ExitCode,
+		arg.Output,
+		arg.ErrorMessage,
+		arg.FailureReason,
+	)
+	return err
+}
+
 const getTaskRun = `-- name: GetTaskRun :one
 SELECT id, job_run_id, task_id, status, started_at, finished_at, exit_code, output, error_message, created_at
 FROM task_runs
@@ -104,7 +167,7 @@ func (q *Queries) GetTaskRun(ctx context.Context, id pgtype.UUID) (GetTaskRunRow
 const getTaskRunByJobRunAndTaskID = `-- name: GetTaskRunByJobRunAndTaskID :one
 SELECT id, job_run_id, task_id, status, started_at, finished_at, exit_code, output, error_message, created_at
 FROM task_runs
-WHERE job_run_id = $1 AND task_id = $2 LIMIT 1
+WHERE job_run_id = $1 AND task_id = $2 AND parent_task_run_id IS NULL LIMIT 1
 `
 
 type GetTaskRunByJobRunAndTaskIDParams struct {
@@ -143,6 +206,97 @@ func (q *Queries) GetTaskRunByJobRunAndTaskID(ctx context.Context, arg GetTaskRu
 	return i, err
 }
 
+const getTaskRunOutput = `-- name: GetTaskRunOutput :one
+SELECT id, output, output_key, output_size
+FROM task_runs
+WHERE id = $1 LIMIT 1
+`
+
+type GetTaskRunOutputRow struct {
+	ID         pgtype.UUID `json:"id"`
+	Output     pgtype.Text `json:"output"`
+	OutputKey  pgtype.Text `json:"output_key"`
+	OutputSize pgtype.Int8 `json:"output_size"`
+}
+
+func (q *Queries) GetTaskRunOutput(ctx context.Context, id pgtype.UUID) (GetTaskRunOutputRow, error) {
+	row := q.db.QueryRow(ctx, getTaskRunOutput, id)
+	var i GetTaskRunOutputRow
+	err := row.Scan(
+		&i.ID,
+		&i.Output,
+		&i.OutputKey,
+		&i.OutputSize,
+	)
+	return i, err
+}
+
+const listTaskRunExportedEnv = `-- name: ListTaskRunExportedEnv :many
+SELECT exported_env
+FROM task_runs
+WHERE job_run_id = $1 AND status IN ('completed', 'cached') AND exported_env IS NOT NULL
+ORDER BY finished_at, id
+`
+
+func (q *Queries) ListTaskRunExportedEnv(ctx context.Context, jobRunID pgtype.UUID) ([][]byte, error) {
+	rows, err := q.db.Query(ctx, listTaskRunExportedEnv, jobRunID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items [][]byte
+	for rows.Next() {
+		var exported_env []byte
+		if err := rows.Scan(&exported_env); err != nil {
+			return nil, err
+		}
+		items = append(items, exported_env)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTaskRunInstances = `-- name: ListTaskRunInstances :many
+SELECT id, instance_index, status, output
+FROM task_runs
+WHERE parent_task_run_id = $1
+ORDER BY instance_index
+`
+
+type ListTaskRunInstancesRow struct {
+	ID            pgtype.UUID `json:"id"`
+	InstanceIndex pgtype.Int4 `json:"instance_index"`
+	Status        pgtype.Text `json:"status"`
+	Output        pgtype.Text `json:"output"`
+}
+
+func (q *Queries) ListTaskRunInstances(ctx context.Context, parentTaskRunID pgtype.UUID) ([]ListTaskRunInstancesRow, error) {
+	rows, err := q.db.Query(ctx, listTaskRunInstances, parentTaskRunID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListTaskRunInstancesRow{}
+	for rows.Next() {
+		var i ListTaskRunInstancesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.InstanceIndex,
+			&i.Status,
+			&i.Output,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listTaskRuns = `-- name: ListTaskRuns :many
 SELECT id, job_run_id, task_id, status, started_at, finished_at, exit_code, output, error_message, created_at
 FROM task_runs
@@ -246,6 +400,108 @@ func (q *Queries) ListTaskRunsByJob(ctx context.Context, id pgtype.UUID) ([]List
 	return items, nil
 }
 
+const listTaskRunsWithTaskName = `-- name: ListTaskRunsWithTaskName :many
+SELECT tr.id, tr.task_id, t.name AS task_name, tr.status, tr.output
+FROM task_runs tr
+JOIN tasks t ON tr.task_id = t.id
+WHERE tr.job_run_id = $1 AND tr.parent_task_run_id IS NULL
+ORDER BY t."order"
+`
+
+type ListTaskRunsWithTaskNameRow struct {
+	ID       pgtype.UUID `json:"id"`
+	TaskID   pgtype.UUID `json:"task_id"`
+	TaskName string      `json:"task_name"`
+	Status   pgtype.Text `json:"status"`
+	Output   pgtype.Text `json:"output"`
+}
+
+func (q *Queries) ListTaskRunsWithTaskName(ctx context.Context, jobRunID pgtype.UUID) ([]ListTaskRunsWithTaskNameRow, error) {
+	rows, err := q.db.Query(ctx, listTaskRunsWithTaskName, jobRunID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListTaskRunsWithTaskNameRow{}
+	for rows.Next() {
+		var i ListTaskRunsWithTaskNameRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.TaskID,
+			&i.TaskName,
+			&i.Status,
+			&i.Output,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setTaskRunExportedEnv = `-- name: SetTaskRunExportedEnv :exec
+UPDATE task_runs
+SET exported_env = $2, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+`
+
+type SetTaskRunExportedEnvParams struct {
+	ID          pgtype.UUID `json:"id"`
+	ExportedEnv []byte      `json:"exported_env"`
+}
+
+func (q *Queries) SetTaskRunExportedEnv(ctx context.Context, arg SetTaskRunExportedEnvParams) error {
+	_, err := q.db.Exec(ctx, setTaskRunExportedEnv, arg.ID, arg.ExportedEnv)
+	return err
+}
+
+const setTaskRunOutputSpill = `-- name: SetTaskRunOutputSpill :exec
+UPDATE task_runs
+SET output_key = $2, output_size = $3, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+`
+
+type SetTaskRunOutputSpillParams struct {
+	ID         pgtype.UUID `json:"id"`
+	OutputKey  pgtype.Text `json:"output_key"`
+	OutputSize pgtype.Int8 `json:"output_size"`
+}
+
+func (q *Queries) SetTaskRunOutputSpill(ctx context.Context, arg SetTaskRunOutputSpillParams) error {
+	_, err := q.db.Exec(ctx, setTaskRunOutputSpill, arg.ID, arg.OutputKey, arg.OutputSize)
+	return err
+}
+
+const skipPendingTaskRuns = `-- name: SkipPendingTaskRuns :exec
+UPDATE task_runs
+SET status = 'skipped', finished_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+WHERE job_run_id = $1 AND status = 'pending'
+`
+
+func (q *Queries) SkipPendingTaskRuns(ctx context.Context, jobRunID pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, skipPendingTaskRuns, jobRunID)
+	return err
+}
+
+const startTaskRun = `-- name: StartTaskRun :exec
+UPDATE task_runs
+SET status = 'running', attempt = $2, started_at = CURRENT_TIMESTAMP, finished_at = NULL, exit_code = NULL, error_message = NULL, failure_reason = NULL, output_key = NULL, output_size = NULL, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+`
+
+type StartTaskRunParams struct {
+	ID      pgtype.UUID `json:"id"`
+	Attempt int32       `json:"attempt"`
+}
+
+func (q *Queries) StartTaskRun(ctx context.Context, arg StartTaskRunParams) error {
+	_, err := q.db.Exec(ctx, startTaskRun, arg.ID, arg.Attempt)
+	return err
+}
+
 const updateTaskRun = `-- name: UpdateTaskRun :exec
 UPDATE task_runs
 SET status = $2, started_at = $3, finished_at = $4, exit_code = $5, output = $6, error_message = $7, updated_at = CURRENT_TIMESTAMP
@@ -0,0 +1,61 @@
+package worker
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/b0nbon1/stratal/internal/logger"
+	"github.com/b0nbon1/stratal/internal/processor"
+	"github.com/b0nbon1/stratal/internal/storage/db/dto"
+	db "github.com/b0nbon1/stratal/internal/storage/db/sqlc"
+)
+
+// concurrencyRetryAfter is how long a run held back by the concurrency policy of its job
+// waits before the scheduler queues it again
+const concurrencyRetryAfter = 15 * time.Second
+
+// admitJobRun enforces the concurrency policy of a job before one of its runs starts and
+// reports whether the run may start now. Runs that already started before, e.g. parked
+// ones that were re-queued, hold their slot and are always admitted.
+func (w *Worker) admitJobRun(jobRun db.GetJobRunRow, job db.GetJobWithTasksRow, jobLogger *logger.JobRunLogger) (bool, error) {
+	cfg := job.Config.Concurrency
+	if cfg == nil || jobRun.StartedAt.Valid {
+		return true, nil
+	}
+	if err := processor.ValidateConcurrency(cfg); err != nil {
+		return false, err
+	}
+
+	result, err := w.store.AdmitJobRunTx(w.ctx, db.AdmitJobRunParams{
+		JobRunID:   jobRun.ID,
+		JobID:      job.ID,
+		MaxRuns:    cfg.MaxRuns,
+		OnConflict: cfg.OnConflict,
+		RetryAfter: concurrencyRetryAfter,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to enforce concurrency policy: %w", err)
+	}
+
+	for _, cancelledID := range result.CancelledRunIDs {
+		fmt.Printf("Cancelled job run %s to make room for job run %s\n", cancelledID.String(), jobRun.ID.String())
+		if jobLogger != nil {
+			jobLogger.Info(fmt.Sprintf("Cancelled previous job run %s, the job allows %d concurrent run(s)", cancelledID.String(), cfg.MaxRuns))
+		}
+		if err := w.q.PublishCancel(cancelledID.String()); err != nil {
+			fmt.Printf("Error publishing cancellation of job run %s: %v\n", cancelledID.String(), err)
+		}
+	}
+
+	if !result.Admitted {
+		message := fmt.Sprintf("Job run %s skipped, %d run(s) of the job are already active", jobRun.ID.String(), result.ActiveRuns)
+		if cfg.OnConflict != dto.ConcurrencySkip {
+			message = fmt.Sprintf("Job run %s waits for one of %d active run(s) of the job to finish", jobRun.ID.String(), result.ActiveRuns)
+		}
+		fmt.Println(message)
+		if jobLogger != nil {
+			jobLogger.Info(message)
+		}
+	}
+	return result.Admitted, nil
+}
@@ -1,6 +1,7 @@
 package worker
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -11,9 +12,21 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const (
+	// stuckJobIdleTimeout is how long a job can go without a heartbeat before another worker reclaims it
+	stuckJobIdleTimeout = 10 * time.Minute
+	heartbeatInterval   = time.Minute
+)
+
 func (w *Worker) ProcessNextJob() {
 	fmt.Println("Worker polling for jobs...")
 
+	// Take over jobs of workers that died mid-run, they are resumed from their last completed task
+	if time.Since(w.lastReclaim) > heartbeatInterval {
+		w.q.ReclaimStuckJobs(stuckJobIdleTimeout)
+		w.lastReclaim = time.Now()
+	}
+
 	msgID, values, err := w.q.Dequeue(5 * time.Second)
 	if err != nil {
 		fmt.Println("Error dequeue:", err)
@@ -23,7 +36,14 @@ func (w *Worker) ProcessNextJob() {
 		return
 	}
 
-	jobRunId := values["job_run_id"].(string)
+	// the message is acknowledged once processing ends, a worker crash leaves it pending for reclaiming
+	defer func() {
+		if err := w.q.Ack(msgID); err != nil {
+			fmt.Printf("Error acknowledging message %s: %v\n", msgID, err)
+		}
+	}()
+
+	jobRunId, _ := values["job_run_id"].(string)
 	fmt.Println("Processing job:", jobRunId)
 
 	if jobRunId == "" {
@@ -38,6 +58,9 @@ func (w *Worker) ProcessNextJob() {
 		return
 	}
 
+	stopHeartbeat := w.startHeartbeat(msgID)
+	defer stopHeartbeat()
+
 	var jobLogger *logger.JobRunLogger
 	if w.logSystem != nil {
 		jobLogger, err = w.logSystem.GetJobRunLogger(jobRunId)
@@ -74,39 +97,92 @@ func (w *Worker) ProcessJobRun(jobRunID pgtype.UUID, jobLogger *logger.JobRunLog
 		return fmt.Errorf("failed to get job_run: %w", err)
 	}
 
-	if jobRun.Status.String != "queued" && jobRun.Status.String != "pending" {
-		if jobRun.Status.String == "paused" {
-			fmt.Printf("Job run %s is paused, skipping processing\n", jobRunID.String())
-			return nil
+	switch jobRun.Status.String {
+	case "queued", "pending":
+	case "running":
+		// a resumed run, or one reclaimed from a worker that died while running it
+		fmt.Printf("Resuming job run %s\n", jobRunID.String())
+		if jobLogger != nil {
+			jobLogger.Info("Resuming job run from its last completed task")
 		}
-		return fmt.Errorf("job run %s is not in queued/pending/paused state, current state: %s",
+	case "paused":
+		fmt.Printf("Job run %s is paused, skipping processing\n", jobRunID.String())
+		return nil
+	case "waiting_approval":
+		fmt.Printf("Job run %s is waiting for approval, skipping processing\n", jobRunID.String())
+		return nil
+	case "up_for_reschedule":
+		fmt.Printf("Job run %s is waiting for its next sensor check, skipping processing\n", jobRunID.String())
+		return nil
+	case "completed", "failed", "skipped", "timed_out", "cancelled":
+		fmt.Printf("Job run %s already finished with status %s, skipping processing\n", jobRunID.String(), jobRun.Status.String)
+		return nil
+	default:
+		return fmt.Errorf("job run %s is not in queued/pending/running/paused state, current state: %s",
 			jobRunID.String(), jobRun.Status.String)
 	}
 
-	startTime := pgtype.Timestamp{Time: time.Now(), Valid: true}
-	err = w.store.UpdateJobRun(w.ctx, db.UpdateJobRunParams{
-		ID:          jobRunID,
-		Status:      pgtype.Text{String: "running", Valid: true},
-		StartedAt:   startTime,
-		TriggeredBy: jobRun.TriggeredBy,
-		Metadata:    jobRun.Metadata,
-	})
-	if err != nil {
-		return fmt.Errorf("failed to update job_run status to running: %w", err)
-	}
-
-	fmt.Printf("Job run %s status updated to running\n", jobRunID.String())
-
 	job, err := w.store.GetJobWithTasks(w.ctx, jobRun.JobID)
 	if err != nil {
 		w.UpdateJobRunError(jobRunID, "Failed to fetch job details", err)
 		return fmt.Errorf("failed to get job with tasks: %w", err)
 	}
 
-	if w.secretManager != nil {
-		return processor.ProcessJob(w.ctx, w.store, w.secretManager, jobRunID, job, jobLogger)
-	} else {
-		return processor.ProcessJob(w.ctx, w.store, nil, jobRunID, job, jobLogger)
+	admitted, err := w.admitJobRun(jobRun, job, jobLogger)
+	if err != nil {
+		return err
+	}
+	if !admitted {
+		return nil
+	}
+
+	if jobRun.Status.String != "running" {
+		// a run queued twice is picked up by two workers, only the one moving it to running executes it
+		started, err := w.store.StartJobRun(w.ctx, jobRunID)
+		if err != nil {
+			return fmt.Errorf("failed to update job_run status to running: %w", err)
+		}
+		if started == 0 {
+			fmt.Printf("Job run %s was started by another worker, skipping processing\n", jobRunID.String())
+			return nil
+		}
+		fmt.Printf("Job run %s status updated to running\n", jobRunID.String())
+	}
+
+	// the run gets its own context so a cancellation request can stop it and its processes
+	runCtx, cancel := context.WithCancelCause(w.ctx)
+	defer cancel(nil)
+	w.registerRun(jobRunID.String(), cancel)
+	defer w.unregisterRun(jobRunID.String())
+
+	return processor.ProcessJob(runCtx, w.deps, jobRunID, job, jobLogger)
+}
+
+func (w *Worker) registerRun(jobRunID string, cancel context.CancelCauseFunc) {
+	w.runsMu.Lock()
+	defer w.runsMu.Unlock()
+	w.runCancels[jobRunID] = cancel
+}
+
+func (w *Worker) unregisterRun(jobRunID string) {
+	w.runsMu.Lock()
+	defer w.runsMu.Unlock()
+	delete(w.runCancels, jobRunID)
+}
+
+// listenForCancellations cancels the context of job runs owned by this worker when
+// their cancellation is requested. Requests for runs of other workers are ignored.
+func (w *Worker) listenForCancellations() {
+	for jobRunID := range w.q.SubscribeCancel(w.ctx) {
+		w.runsMu.Lock()
+		cancel, ok := w.runCancels[jobRunID]
+		w.runsMu.Unlock()
+		if !ok {
+			continue
+		}
+
+		fmt.Printf("Cancelling job run %s\n", jobRunID)
+		cancel(processor.ErrJobRunCancelled)
 	}
 }
 
@@ -120,3 +196,23 @@ func (w *Worker) UpdateJobRunError(jobRunID pgtype.UUID, message string, err err
 		fmt.Printf("Failed to update job run error: %v\n", updateErr)
 	}
 }
+
+// startHeartbeat keeps the queue message of a running job claimed until the returned func is called
+func (w *Worker) startHeartbeat(msgID string) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(heartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := w.q.Heartbeat(msgID); err != nil {
+					fmt.Printf("Error sending heartbeat for message %s: %v\n", msgID, err)
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
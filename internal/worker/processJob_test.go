@@ -0,0 +1,50 @@
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/b0nbon1/stratal/internal/processor"
+	"github.com/b0nbon1/stratal/internal/queue"
+	"github.com/stretchr/testify/assert"
+)
+
+// cancelQueue delivers cancellation requests from a channel, the other methods are unused
+type cancelQueue struct {
+	queue.TaskQueue
+	cancels chan string
+}
+
+func (q *cancelQueue) SubscribeCancel(ctx context.Context) <-chan string {
+	return q.cancels
+}
+
+func TestListenForCancellations(t *testing.T) {
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	q := &cancelQueue{cancels: make(chan string)}
+	w := &Worker{ctx: ctx, q: q, runCancels: make(map[string]context.CancelCauseFunc)}
+	go w.listenForCancellations()
+
+	owned, cancelOwned := context.WithCancelCause(ctx)
+	defer cancelOwned(nil)
+	w.registerRun("owned", cancelOwned)
+	finished, cancelFinished := context.WithCancelCause(ctx)
+	defer cancelFinished(nil)
+	w.registerRun("finished", cancelFinished)
+	w.unregisterRun("finished")
+
+	q.cancels <- "other-worker"
+	q.cancels <- "finished"
+	q.cancels <- "owned"
+
+	select {
+	case <-owned.Done():
+		assert.ErrorIs(t, context.Cause(owned), processor.ErrJobRunCancelled)
+	case <-time.After(time.Second):
+		t.Fatal("the run of this worker was not cancelled")
+	}
+	assert.NoError(t, finished.Err(), "a run no longer processed by this worker was cancelled")
+	close(q.cancels)
+}
@@ -3,33 +3,41 @@ package worker
 import (
 	"context"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/b0nbon1/stratal/internal/logger"
+	"github.com/b0nbon1/stratal/internal/processor"
 	"github.com/b0nbon1/stratal/internal/queue"
-	"github.com/b0nbon1/stratal/internal/security"
 	db "github.com/b0nbon1/stratal/internal/storage/db/sqlc"
 )
 
 type Worker struct {
-	ctx           context.Context
-	q             queue.TaskQueue
-	store         *db.SQLStore
-	secretManager *security.SecretManager
-	logSystem     *logger.Logger
+	ctx         context.Context
+	q           queue.TaskQueue
+	store       *db.SQLStore
+	deps        processor.Dependencies // what the job runs of this worker are processed with
+	logSystem   *logger.Logger
+	lastReclaim time.Time
+
+	runsMu     sync.Mutex
+	runCancels map[string]context.CancelCauseFunc // job run ID -> cancel func of the run processed by this worker
 }
 
-func StartWorker(ctx context.Context, q queue.TaskQueue, store *db.SQLStore, secretManager *security.SecretManager) {
-	logSystem := logger.NewLogger(store, "internal/storage/files/logs")
+func StartWorker(ctx context.Context, q queue.TaskQueue, deps processor.Dependencies) {
+	logSystem := logger.NewLogger(deps.Store, "internal/storage/files/logs")
 	defer logSystem.Close()
 
 	worker := &Worker{
-		ctx:           ctx,
-		q:             q,
-		store:         store,
-		secretManager: secretManager,
-		logSystem:     logSystem,
+		ctx:        ctx,
+		q:          q,
+		store:      deps.Store,
+		deps:       deps,
+		logSystem:  logSystem,
+		runCancels: make(map[string]context.CancelCauseFunc),
 	}
 
+	go worker.listenForCancellations()
 	go worker.Start()
 }
 
@@ -0,0 +1,299 @@
+// Package expr implements a small, side effect free expression language used for
+// task conditions. It supports comparisons (== != < <= > >=), boolean logic
+// (&& || ! and, or, not), substring and membership checks with contains, and
+// field access into JSON values such as outputs.fetch.items[0].status.
+package expr
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Expression is a parsed expression that can be evaluated repeatedly
+type Expression struct {
+	source string
+	root   node
+}
+
+// Compile parses an expression so syntax errors surface before evaluation
+func Compile(source string) (*Expression, error) {
+	root, err := parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression '%s': %w", source, err)
+	}
+	return &Expression{source: source, root: root}, nil
+}
+
+// String returns the source of the expression
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Eval evaluates the expression and returns its raw value
+func (e *Expression) Eval(vars map[string]interface{}) (interface{}, error) {
+	value, err := e.root.eval(vars)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate '%s': %w", e.source, err)
+	}
+	return value, nil
+}
+
+// EvalBool evaluates the expression and converts the result to a boolean
+func (e *Expression) EvalBool(vars map[string]interface{}) (bool, error) {
+	value, err := e.Eval(vars)
+	if err != nil {
+		return false, err
+	}
+	return truthy(value), nil
+}
+
+// EvalBool compiles and evaluates an expression in one step
+func EvalBool(source string, vars map[string]interface{}) (bool, error) {
+	e, err := Compile(source)
+	if err != nil {
+		return false, err
+	}
+	return e.EvalBool(vars)
+}
+
+func (n *literalNode) eval(map[string]interface{}) (interface{}, error) {
+	return n.value, nil
+}
+
+// eval resolves the path, unknown variables and missing fields evaluate to null
+func (n *pathNode) eval(vars map[string]interface{}) (interface{}, error) {
+	var current interface{} = vars
+	for _, segment := range n.segments {
+		current = decodeJSONString(current)
+		switch key := segment.(type) {
+		case string:
+			m, ok := current.(map[string]interface{})
+			if !ok {
+				if sm, isStringMap := current.(map[string]string); isStringMap {
+					value, found := sm[key]
+					if !found {
+						return nil, nil
+					}
+					current = value
+					continue
+				}
+				return nil, nil
+			}
+			current = m[key]
+		case int:
+			list, ok := current.([]interface{})
+			if !ok || key < 0 || key >= len(list) {
+				return nil, nil
+			}
+			current = list[key]
+		}
+	}
+	return current, nil
+}
+
+func (n *notNode) eval(vars map[string]interface{}) (interface{}, error) {
+	value, err := n.operand.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	return !truthy(value), nil
+}
+
+func (n *binaryNode) eval(vars map[string]interface{}) (interface{}, error) {
+	left, err := n.left.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	// short circuit boolean operators
+	switch n.op {
+	case "&&":
+		if !truthy(left) {
+			return false, nil
+		}
+		right, err := n.right.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		return truthy(right), nil
+	case "||":
+		if truthy(left) {
+			return true, nil
+		}
+		right, err := n.right.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		return truthy(right), nil
+	}
+
+	right, err := n.right.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	case "contains":
+		return contains(left, right), nil
+	case "<", "<=", ">", ">=":
+		cmp, err := compare(left, right)
+		if err != nil {
+			return nil, err
+		}
+		switch n.op {
+		case "<":
+			return cmp < 0, nil
+		case "<=":
+			return cmp <= 0, nil
+		case ">":
+			return cmp > 0, nil
+		default:
+			return cmp >= 0, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown operator '%s'", n.op)
+}
+
+// decodeJSONString parses string values holding a JSON object or array, which is
+// how task outputs are stored, so that their fields can be accessed
+func decodeJSONString(value interface{}) interface{} {
+	s, ok := value.(string)
+	if !ok {
+		return value
+	}
+	trimmed := strings.TrimSpace(s)
+	if !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[") {
+		return value
+	}
+	var decoded interface{}
+	if err := json.Unmarshal([]byte(trimmed), &decoded); err != nil {
+		return value
+	}
+	return decoded
+}
+
+// truthy converts a value to a boolean. Empty strings, "false", "0", zero, null
+// and empty collections are false.
+func truthy(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case float64:
+		return v != 0
+	case string:
+		s := strings.TrimSpace(v)
+		return s != "" && !strings.EqualFold(s, "false") && s != "0"
+	case []interface{}:
+		return len(v) > 0
+	case map[string]interface{}:
+		return len(v) > 0
+	}
+	return true
+}
+
+// toNumber converts numbers and numeric strings to float64
+func toNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// toString renders a value the way it would appear in a task output
+func toString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(b)
+}
+
+// equal compares values loosely: task outputs are strings, so "42" == 42 and "true" == true
+func equal(left, right interface{}) bool {
+	if left == nil || right == nil {
+		return left == nil && right == nil
+	}
+	if lb, ok := left.(bool); ok {
+		return lb == truthy(right)
+	}
+	if rb, ok := right.(bool); ok {
+		return rb == truthy(left)
+	}
+	if ln, ok := toNumber(left); ok {
+		if rn, ok := toNumber(right); ok {
+			return ln == rn
+		}
+	}
+	if _, ok := left.(string); !ok {
+		if _, ok := right.(string); !ok {
+			return reflect.DeepEqual(left, right)
+		}
+	}
+	return strings.TrimSpace(toString(left)) == strings.TrimSpace(toString(right))
+}
+
+// compare orders two values numerically when both are numbers, otherwise as strings
+func compare(left, right interface{}) (int, error) {
+	ln, lok := toNumber(left)
+	rn, rok := toNumber(right)
+	if lok && rok {
+		switch {
+		case ln < rn:
+			return -1, nil
+		case ln > rn:
+			return 1, nil
+		}
+		return 0, nil
+	}
+
+	ls, lIsString := left.(string)
+	rs, rIsString := right.(string)
+	if !lIsString || !rIsString {
+		return 0, fmt.Errorf("cannot compare %s with %s", toString(left), toString(right))
+	}
+	return strings.Compare(ls, rs), nil
+}
+
+// contains checks list membership, object keys or substrings
+func contains(container, item interface{}) bool {
+	switch c := decodeJSONString(container).(type) {
+	case []interface{}:
+		for _, element := range c {
+			if equal(element, item) {
+				return true
+			}
+		}
+		return false
+	case map[string]interface{}:
+		_, ok := c[toString(item)]
+		return ok
+	case nil:
+		return false
+	}
+	return strings.Contains(toString(container), toString(item))
+}
@@ -0,0 +1,145 @@
+package expr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testVars() map[string]interface{} {
+	return map[string]interface{}{
+		"outputs": map[string]string{
+			"check":  "ready\n",
+			"count":  "42",
+			"fetch":  `{"status": "ok", "items": [{"id": 7, "tags": ["a", "b"]}], "enabled": true}`,
+			"broken": `{"status": `,
+		},
+		"inputs": map[string]interface{}{
+			"environment": "production",
+			"dry_run":     false,
+			"replicas":    3.0,
+		},
+		"env": map[string]string{
+			"REGION": "eu-west-1",
+		},
+	}
+}
+
+func TestEvalBool_Comparisons(t *testing.T) {
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`outputs.check == "ready"`, true},
+		{`outputs.check != "ready"`, false},
+		{`outputs.count == 42`, true},
+		{`outputs.count > 40`, true},
+		{`outputs.count <= 41`, false},
+		{`inputs.replicas >= 3`, true},
+		{`inputs.environment == 'production'`, true},
+		{`env.REGION < "us"`, true},
+		{`-1 < 0`, true},
+		{`1.5 > 1`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := EvalBool(tt.expr, testVars())
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestEvalBool_BooleanLogic(t *testing.T) {
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`inputs.environment == "production" && !inputs.dry_run`, true},
+		{`inputs.environment == "staging" || outputs.count == 42`, true},
+		{`not (outputs.count == 42 and inputs.dry_run)`, true},
+		{`inputs.dry_run`, false},
+		{`outputs.check`, true},
+		{`outputs.missing`, false},
+		{`!outputs.missing && true`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := EvalBool(tt.expr, testVars())
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestEvalBool_JSONFieldAccess(t *testing.T) {
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`outputs.fetch.status == "ok"`, true},
+		{`outputs.fetch.items[0].id == 7`, true},
+		{`outputs.fetch.items.0.id == 7`, true},
+		{`outputs.fetch["enabled"] == true`, true},
+		{`outputs.fetch.items[3].id == null`, true},
+		{`outputs.broken.status == null`, true},
+		{`outputs["count"] == "42"`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := EvalBool(tt.expr, testVars())
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestEvalBool_Contains(t *testing.T) {
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`outputs.check contains "read"`, true},
+		{`outputs.fetch.items[0].tags contains "b"`, true},
+		{`outputs.fetch.items[0].tags contains "c"`, false},
+		{`outputs.fetch contains "status"`, true},
+		{`env.REGION contains "eu"`, true},
+		{`outputs.missing contains "x"`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := EvalBool(tt.expr, testVars())
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCompile_SyntaxErrors(t *testing.T) {
+	invalid := []string{
+		``,
+		`outputs.check ==`,
+		`(outputs.check == "ready"`,
+		`outputs.check == "ready`,
+		`outputs.check = "ready"`,
+		`outputs.items[`,
+		`a == b c`,
+	}
+
+	for _, source := range invalid {
+		t.Run(source, func(t *testing.T) {
+			_, err := Compile(source)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestEvalBool_CompareTypeMismatch(t *testing.T) {
+	_, err := EvalBool(`outputs.fetch.items > 1`, testVars())
+	assert.Error(t, err)
+}
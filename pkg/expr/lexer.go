@@ -0,0 +1,140 @@
+package expr
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOperator
+	tokenLParen
+	tokenRParen
+	tokenLBracket
+	tokenRBracket
+	tokenDot
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+// keywords that are read as operators instead of identifiers
+var keywordOperators = map[string]string{
+	"and":      "&&",
+	"or":       "||",
+	"not":      "!",
+	"contains": "contains",
+}
+
+// tokenize splits an expression into tokens
+func tokenize(input string) ([]token, error) {
+	var tokens []token
+	runes := []rune(input)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case r == '(':
+			tokens = append(tokens, token{tokenLParen, "(", i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{tokenRParen, ")", i})
+			i++
+		case r == '[':
+			tokens = append(tokens, token{tokenLBracket, "[", i})
+			i++
+		case r == ']':
+			tokens = append(tokens, token{tokenRBracket, "]", i})
+			i++
+		case r == '.' && (lastIsValue(tokens) || i+1 >= len(runes) || !unicode.IsDigit(runes[i+1])):
+			tokens = append(tokens, token{tokenDot, ".", i})
+			i++
+
+		case r == '\'' || r == '"':
+			start := i
+			var sb strings.Builder
+			i++
+			for ; i < len(runes) && runes[i] != r; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				sb.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unterminated string starting at position %d", start)
+			}
+			i++
+			tokens = append(tokens, token{tokenString, sb.String(), start})
+
+		case unicode.IsDigit(r) || r == '.' || (r == '-' && i+1 < len(runes) && unicode.IsDigit(runes[i+1]) && !lastIsValue(tokens)):
+			start := i
+			// an index in a path such as items.0.id never contains a decimal point
+			afterDot := len(tokens) > 0 && tokens[len(tokens)-1].kind == tokenDot
+			i++
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || (runes[i] == '.' && !afterDot)) {
+				i++
+			}
+			tokens = append(tokens, token{tokenNumber, string(runes[start:i]), start})
+
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_' || runes[i] == '-') {
+				i++
+			}
+			word := string(runes[start:i])
+			if op, ok := keywordOperators[strings.ToLower(word)]; ok {
+				tokens = append(tokens, token{tokenOperator, op, start})
+			} else {
+				tokens = append(tokens, token{tokenIdent, word, start})
+			}
+
+		default:
+			start := i
+			two := ""
+			if i+1 < len(runes) {
+				two = string(runes[i : i+2])
+			}
+			switch two {
+			case "==", "!=", "<=", ">=", "&&", "||":
+				tokens = append(tokens, token{tokenOperator, two, start})
+				i += 2
+				continue
+			}
+			switch r {
+			case '<', '>', '!':
+				tokens = append(tokens, token{tokenOperator, string(r), start})
+				i++
+			default:
+				return nil, fmt.Errorf("unexpected character '%c' at position %d", r, start)
+			}
+		}
+	}
+
+	tokens = append(tokens, token{tokenEOF, "", len(runes)})
+	return tokens, nil
+}
+
+// lastIsValue reports whether the previous token ends a value, in which case a '-' is not a sign
+func lastIsValue(tokens []token) bool {
+	if len(tokens) == 0 {
+		return false
+	}
+	switch tokens[len(tokens)-1].kind {
+	case tokenIdent, tokenString, tokenNumber, tokenRParen, tokenRBracket:
+		return true
+	}
+	return false
+}
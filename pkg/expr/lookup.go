@@ -0,0 +1,99 @@
+package expr
+
+import (
+	"fmt"
+	"strings"
+)
+
+// lookupRoot names the value a lookup path is resolved against in error messages
+const lookupRoot = "$"
+
+// lookupVar is the placeholder variable lookup paths are parsed against
+const lookupVar = "value"
+
+// Lookup resolves a field path such as .data[0].id or ["user name"] against a value.
+// String values holding a JSON object or array are decoded on the way. Unlike paths
+// inside expressions, a missing field or index is reported as an error.
+func Lookup(value interface{}, path string) (interface{}, error) {
+	segments, err := parseLookupPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	current := value
+	walked := lookupRoot
+	for _, segment := range segments {
+		current = decodeJSONString(current)
+		switch key := segment.(type) {
+		case string:
+			m, ok := current.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("cannot read field '%s' of %s at '%s'", key, kindOf(current), walked)
+			}
+			next, found := m[key]
+			if !found {
+				return nil, fmt.Errorf("field '%s' not found at '%s'", key, walked)
+			}
+			current = next
+			walked += "." + key
+		case int:
+			list, ok := current.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("cannot index %s at '%s'", kindOf(current), walked)
+			}
+			if key < 0 || key >= len(list) {
+				return nil, fmt.Errorf("index %d out of range at '%s' (length %d)", key, walked, len(list))
+			}
+			current = list[key]
+			walked += fmt.Sprintf("[%d]", key)
+		}
+	}
+	return current, nil
+}
+
+// Format renders a value the way it is passed to tasks: strings as they are,
+// numbers and booleans in their plain form and objects and arrays as JSON
+func Format(value interface{}) string {
+	return toString(value)
+}
+
+// parseLookupPath splits a path made of .field, [index] and ["key"] accessors
+func parseLookupPath(path string) ([]interface{}, error) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(path, ".") && !strings.HasPrefix(path, "[") {
+		path = "." + path
+	}
+
+	// reuse the expression parser by resolving the path against a placeholder variable
+	root, err := parse(lookupVar + path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid path '%s': %w", path, err)
+	}
+	node, ok := root.(*pathNode)
+	if !ok {
+		return nil, fmt.Errorf("invalid path '%s'", path)
+	}
+	return node.segments[1:], nil
+}
+
+// kindOf describes the JSON type of a value for error messages
+func kindOf(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "an object"
+	case []interface{}:
+		return "an array"
+	case string:
+		return "a string"
+	case float64:
+		return "a number"
+	case bool:
+		return "a boolean"
+	}
+	return fmt.Sprintf("%T", value)
+}
@@ -0,0 +1,67 @@
+package expr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const lookupOutput = `{"data": [{"id": 7, "name": "ada", "tags": ["a", "b"]}], "count": 1, "meta": {"user name": "bob", "active": true, "next": null}}`
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"", lookupOutput},
+		{".count", "1"},
+		{"count", "1"},
+		{".data[0].id", "7"},
+		{".data[0].name", "ada"},
+		{".data.0.tags[1]", "b"},
+		{`.meta["user name"]`, "bob"},
+		{".meta.active", "true"},
+		{".meta.next", ""},
+		{".data[0].tags", `["a","b"]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			value, err := Lookup(lookupOutput, tt.path)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, Format(value))
+		})
+	}
+}
+
+func TestLookup_Errors(t *testing.T) {
+	tests := []struct {
+		path    string
+		wantErr string
+	}{
+		{".missing", "field 'missing' not found at '$'"},
+		{".data[3]", "index 3 out of range at '$.data' (length 1)"},
+		{".count.value", "cannot read field 'value' of a number at '$.count'"},
+		{".meta[0]", "cannot index an object at '$.meta'"},
+		{".data[", "invalid path"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			_, err := Lookup(lookupOutput, tt.path)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestLookup_PlainText(t *testing.T) {
+	value, err := Lookup("done\n", "")
+	require.NoError(t, err)
+	assert.Equal(t, "done\n", Format(value))
+
+	_, err = Lookup("done\n", ".status")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "of a string")
+}
@@ -0,0 +1,233 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// node is a parsed expression that can be evaluated against a set of variables
+type node interface {
+	eval(vars map[string]interface{}) (interface{}, error)
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+type pathNode struct {
+	segments []interface{} // string keys and int indexes
+	text     string
+}
+
+type notNode struct {
+	operand node
+}
+
+type binaryNode struct {
+	op          string
+	left, right node
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// parse builds a syntax tree from an expression
+//
+//	or      := and (("||" | "or") and)*
+//	and     := unary (("&&" | "and") unary)*
+//	unary   := ("!" | "not") unary | compare
+//	compare := primary (("==" | "!=" | "<" | "<=" | ">" | ">=" | "contains") primary)?
+//	primary := literal | path | "(" or ")"
+func parse(input string) (node, error) {
+	tokens, err := tokenize(input)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected '%s' at position %d", tok.value, tok.pos)
+	}
+	return n, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) isOperator(ops ...string) bool {
+	tok := p.peek()
+	if tok.kind != tokenOperator {
+		return false
+	}
+	for _, op := range ops {
+		if tok.value == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("||") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("&&") {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.isOperator("!") {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand: operand}, nil
+	}
+	return p.parseCompare()
+}
+
+func (p *parser) parseCompare() (node, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	if p.isOperator("==", "!=", "<", "<=", ">", ">=", "contains") {
+		op := p.next().value
+		right, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		return &binaryNode{op: op, left: left, right: right}, nil
+	}
+	return left, nil
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenLParen:
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRParen {
+			return nil, fmt.Errorf("expected ')' at position %d", closing.pos)
+		}
+		return n, nil
+
+	case tokenString:
+		return &literalNode{value: tok.value}, nil
+
+	case tokenNumber:
+		f, err := strconv.ParseFloat(tok.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number '%s' at position %d", tok.value, tok.pos)
+		}
+		return &literalNode{value: f}, nil
+
+	case tokenIdent:
+		switch tok.value {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		case "null", "nil":
+			return &literalNode{value: nil}, nil
+		}
+		return p.parsePath(tok)
+
+	case tokenEOF:
+		return nil, fmt.Errorf("unexpected end of expression")
+	default:
+		return nil, fmt.Errorf("unexpected '%s' at position %d", tok.value, tok.pos)
+	}
+}
+
+// parsePath reads a variable reference such as outputs.fetch.items[0].id or inputs["env"]
+func (p *parser) parsePath(first token) (node, error) {
+	path := &pathNode{segments: []interface{}{first.value}, text: first.value}
+
+	for {
+		switch p.peek().kind {
+		case tokenDot:
+			p.next()
+			tok := p.next()
+			switch tok.kind {
+			case tokenIdent:
+				path.segments = append(path.segments, tok.value)
+			case tokenNumber:
+				index, err := strconv.Atoi(tok.value)
+				if err != nil {
+					return nil, fmt.Errorf("invalid index '%s' at position %d", tok.value, tok.pos)
+				}
+				path.segments = append(path.segments, index)
+			default:
+				return nil, fmt.Errorf("expected field name after '.' at position %d", tok.pos)
+			}
+			path.text += "." + tok.value
+
+		case tokenLBracket:
+			p.next()
+			tok := p.next()
+			switch tok.kind {
+			case tokenString:
+				path.segments = append(path.segments, tok.value)
+			case tokenNumber:
+				index, err := strconv.Atoi(tok.value)
+				if err != nil {
+					return nil, fmt.Errorf("invalid index '%s' at position %d", tok.value, tok.pos)
+				}
+				path.segments = append(path.segments, index)
+			default:
+				return nil, fmt.Errorf("expected index or quoted key at position %d", tok.pos)
+			}
+			if closing := p.next(); closing.kind != tokenRBracket {
+				return nil, fmt.Errorf("expected ']' at position %d", closing.pos)
+			}
+			path.text += "[" + tok.value + "]"
+
+		default:
+			return path, nil
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package tmpl
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// eval runs the commands of a pipeline, each result is passed as the last argument of the next
+func (pl *pipeline) eval(roots map[string]interface{}) (interface{}, error) {
+	var value interface{}
+	for i, cmd := range pl.commands {
+		args := make([]interface{}, 0, len(cmd.args)+1)
+		for _, arg := range cmd.args {
+			v, err := evalOperand(arg, roots)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, v)
+		}
+		if i > 0 {
+			args = append(args, value)
+		}
+
+		// only the first command can be a plain operand, the parser requires functions after '|'
+		if cmd.function == "" {
+			value = args[0]
+			continue
+		}
+
+		var err error
+		value, err = call(cmd.function, args)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if missing, ok := value.(*MissingError); ok {
+		return nil, missing
+	}
+	return value, nil
+}
+
+// evalOperand evaluates an argument, a reference that cannot be resolved evaluates to its
+// *MissingError so that default can replace it
+func evalOperand(arg operand, roots map[string]interface{}) (interface{}, error) {
+	switch a := arg.(type) {
+	case literalOperand:
+		return a.value, nil
+	case *pathOperand:
+		return missingAsValue(resolve(a.ref, roots[a.ref.Root]))
+	case *pipeline:
+		return missingAsValue(a.eval(roots))
+	}
+	return nil, fmt.Errorf("unknown operand %T", arg)
+}
+
+// missingAsValue turns a *MissingError into the value of an operand
+func missingAsValue(value interface{}, err error) (interface{}, error) {
+	var missing *MissingError
+	if errors.As(err, &missing) {
+		return missing, nil
+	}
+	return value, err
+}
+
+// resolve walks the path of a reference from the value of its root. String values holding a
+// JSON object or array, which is how task outputs are stored, are decoded on the way.
+func resolve(ref Reference, value interface{}) (interface{}, error) {
+	current := value
+	walked := ref.Root
+	for _, segment := range ref.Path {
+		current = decodeJSONString(current)
+		switch key := segment.(type) {
+		case string:
+			next, err := field(current, key, walked)
+			if err != nil {
+				return nil, err
+			}
+			current = next
+			walked += "." + key
+		case int:
+			list, ok := current.([]interface{})
+			if !ok {
+				return nil, Missing("cannot index %s at '%s'", kindOf(current), walked)
+			}
+			if key < 0 || key >= len(list) {
+				return nil, Missing("index %d out of range at '%s' (length %d)", key, walked, len(list))
+			}
+			current = list[key]
+			walked += fmt.Sprintf("[%d]", key)
+		}
+	}
+	if _, ok := current.(LookupFunc); ok {
+		return nil, fmt.Errorf("'%s' is a namespace, reference one of its fields", walked)
+	}
+	return current, nil
+}
+
+// field reads a field of a map or a LookupFunc
+func field(value interface{}, key, walked string) (interface{}, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if next, ok := v[key]; ok {
+			return next, nil
+		}
+	case map[string]string:
+		if next, ok := v[key]; ok {
+			return next, nil
+		}
+	case LookupFunc:
+		return v(key)
+	default:
+		return nil, Missing("cannot read field '%s' of %s at '%s'", key, kindOf(value), walked)
+	}
+	return nil, Missing("field '%s' not found at '%s'", key, walked)
+}
+
+// decodeJSONString parses string values holding a JSON object or array
+func decodeJSONString(value interface{}) interface{} {
+	s, ok := value.(string)
+	if !ok {
+		return value
+	}
+	trimmed := strings.TrimSpace(s)
+	if !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[") {
+		return value
+	}
+	var decoded interface{}
+	if err := json.Unmarshal([]byte(trimmed), &decoded); err != nil {
+		return value
+	}
+	return decoded
+}
+
+// kindOf describes the JSON type of a value for error messages
+func kindOf(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}, map[string]string:
+		return "an object"
+	case []interface{}:
+		return "an array"
+	case string:
+		return "a string"
+	case float64:
+		return "a number"
+	case bool:
+		return "a boolean"
+	}
+	return fmt.Sprintf("%T", value)
+}
@@ -0,0 +1,93 @@
+package tmpl
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/b0nbon1/stratal/pkg/expr"
+)
+
+// function is a template function, args holds the piped value last
+type function struct {
+	minArgs, maxArgs int
+	call             func(args []interface{}) (interface{}, error)
+}
+
+// now is replaced in tests
+var now = time.Now
+
+var functions = map[string]function{
+	// default returns its last argument unless it is missing, null or empty, otherwise the first
+	"default": {2, 2, func(args []interface{}) (interface{}, error) {
+		switch v := args[1].(type) {
+		case *MissingError, nil:
+			return args[0], nil
+		case string:
+			if v == "" {
+				return args[0], nil
+			}
+		}
+		return args[1], nil
+	}},
+	"upper": {1, 1, func(args []interface{}) (interface{}, error) {
+		return strings.ToUpper(expr.Format(args[0])), nil
+	}},
+	"trim": {1, 1, func(args []interface{}) (interface{}, error) {
+		return strings.TrimSpace(expr.Format(args[0])), nil
+	}},
+	"base64": {1, 1, func(args []interface{}) (interface{}, error) {
+		return base64.StdEncoding.EncodeToString([]byte(expr.Format(args[0]))), nil
+	}},
+	// json parses a JSON document so that it is rendered compactly
+	"json": {1, 1, func(args []interface{}) (interface{}, error) {
+		var decoded interface{}
+		if err := json.Unmarshal([]byte(expr.Format(args[0])), &decoded); err != nil {
+			return nil, fmt.Errorf("json: %w", err)
+		}
+		return decoded, nil
+	}},
+	// toJSON encodes a value as JSON, strings become quoted JSON strings
+	"toJSON": {1, 1, func(args []interface{}) (interface{}, error) {
+		encoded, err := json.Marshal(args[0])
+		if err != nil {
+			return nil, fmt.Errorf("toJSON: %w", err)
+		}
+		return string(encoded), nil
+	}},
+	// now returns the current UTC time as RFC 3339, or in the Go layout given
+	"now": {0, 1, func(args []interface{}) (interface{}, error) {
+		layout := time.RFC3339
+		if len(args) == 1 {
+			layout = expr.Format(args[0])
+		}
+		return now().UTC().Format(layout), nil
+	}},
+}
+
+// isFunc reports whether name is a template function
+func isFunc(name string) bool {
+	_, ok := functions[name]
+	return ok
+}
+
+// call invokes a function, only default accepts missing arguments
+func call(name string, args []interface{}) (interface{}, error) {
+	fn := functions[name]
+	if len(args) < fn.minArgs || len(args) > fn.maxArgs {
+		if fn.minArgs == fn.maxArgs {
+			return nil, fmt.Errorf("%s takes %d argument(s), got %d", name, fn.minArgs, len(args))
+		}
+		return nil, fmt.Errorf("%s takes %d to %d argument(s), got %d", name, fn.minArgs, fn.maxArgs, len(args))
+	}
+	if name != "default" {
+		for _, arg := range args {
+			if missing, ok := arg.(*MissingError); ok {
+				return nil, missing
+			}
+		}
+	}
+	return fn.call(args)
+}
@@ -0,0 +1,320 @@
+package tmpl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenDot
+	tokenLBracket
+	tokenRBracket
+	tokenLParen
+	tokenRParen
+	tokenPipe
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+// pipeline is the body of a placeholder: commands whose results feed the next one
+//
+//	pipeline := command ("|" command)*
+//	command  := function operand* | operand
+//	operand  := path | string | number | true | false | null | "(" pipeline ")"
+//	path     := ident ("." ident | "." number | "[" (number | string) "]")*
+type pipeline struct {
+	commands []command
+}
+
+type command struct {
+	function string // empty for a single operand
+	args     []operand
+}
+
+// operand is a *pathOperand, a literalOperand or a *pipeline
+type operand interface{}
+
+type pathOperand struct {
+	ref Reference
+}
+
+type literalOperand struct {
+	value interface{}
+}
+
+// tokenize splits a placeholder body into tokens
+func tokenize(input string) ([]token, error) {
+	var tokens []token
+	runes := []rune(input)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '.':
+			tokens = append(tokens, token{tokenDot, ".", i})
+			i++
+		case r == '[':
+			tokens = append(tokens, token{tokenLBracket, "[", i})
+			i++
+		case r == ']':
+			tokens = append(tokens, token{tokenRBracket, "]", i})
+			i++
+		case r == '(':
+			tokens = append(tokens, token{tokenLParen, "(", i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{tokenRParen, ")", i})
+			i++
+		case r == '|':
+			tokens = append(tokens, token{tokenPipe, "|", i})
+			i++
+		case r == '"' || r == '\'':
+			start := i
+			var sb strings.Builder
+			i++
+			for ; i < len(runes) && runes[i] != r; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				sb.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unterminated string at position %d", start)
+			}
+			i++
+			tokens = append(tokens, token{tokenString, sb.String(), start})
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			start := i
+			// an index in a path such as items.0.id never contains a decimal point
+			afterDot := len(tokens) > 0 && tokens[len(tokens)-1].kind == tokenDot
+			i++
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || (runes[i] == '.' && !afterDot)) {
+				i++
+			}
+			tokens = append(tokens, token{tokenNumber, string(runes[start:i]), start})
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_' || runes[i] == '-') {
+				i++
+			}
+			tokens = append(tokens, token{tokenIdent, string(runes[start:i]), start})
+		default:
+			return nil, fmt.Errorf("unexpected character '%c' at position %d", r, i)
+		}
+	}
+	return append(tokens, token{tokenEOF, "", len(runes)}), nil
+}
+
+// leadingIdent returns the identifier a placeholder body starts with
+func leadingIdent(body string) string {
+	body = strings.TrimLeftFunc(body, unicode.IsSpace)
+	end := strings.IndexFunc(body, func(r rune) bool {
+		return !(unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-')
+	})
+	if end < 0 {
+		return body
+	}
+	return body[:end]
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func parsePlaceholder(body string) (*pipeline, error) {
+	tokens, err := tokenize(body)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	if p.peek().kind == tokenEOF {
+		return nil, fmt.Errorf("empty placeholder")
+	}
+	pl, err := p.parsePipeline()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected '%s' at position %d", tok.value, tok.pos)
+	}
+	return pl, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) parsePipeline() (*pipeline, error) {
+	pl := &pipeline{}
+	for {
+		cmd, err := p.parseCommand()
+		if err != nil {
+			return nil, err
+		}
+		pl.commands = append(pl.commands, cmd)
+		if p.peek().kind != tokenPipe {
+			return pl, nil
+		}
+		p.next()
+		if tok := p.peek(); tok.kind != tokenIdent || !isFunc(tok.value) {
+			return nil, fmt.Errorf("expected a function after '|' at position %d, got '%s'", tok.pos, tok.value)
+		}
+	}
+}
+
+func (p *parser) parseCommand() (command, error) {
+	tok := p.peek()
+	if tok.kind == tokenIdent && isFunc(tok.value) {
+		p.next()
+		cmd := command{function: tok.value}
+		for p.startsOperand() {
+			arg, err := p.parseOperand()
+			if err != nil {
+				return command{}, err
+			}
+			cmd.args = append(cmd.args, arg)
+		}
+		return cmd, nil
+	}
+
+	arg, err := p.parseOperand()
+	if err != nil {
+		return command{}, err
+	}
+	if p.startsOperand() {
+		tok := p.peek()
+		return command{}, fmt.Errorf("unexpected '%s' at position %d, only functions take arguments", tok.value, tok.pos)
+	}
+	return command{args: []operand{arg}}, nil
+}
+
+func (p *parser) startsOperand() bool {
+	switch p.peek().kind {
+	case tokenIdent, tokenString, tokenNumber, tokenLParen:
+		return true
+	}
+	return false
+}
+
+func (p *parser) parseOperand() (operand, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenString:
+		return literalOperand{value: tok.value}, nil
+	case tokenNumber:
+		f, err := strconv.ParseFloat(tok.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number '%s' at position %d", tok.value, tok.pos)
+		}
+		return literalOperand{value: f}, nil
+	case tokenLParen:
+		pl, err := p.parsePipeline()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRParen {
+			return nil, fmt.Errorf("expected ')' at position %d", closing.pos)
+		}
+		return pl, nil
+	case tokenIdent:
+		switch tok.value {
+		case "true":
+			return literalOperand{value: true}, nil
+		case "false":
+			return literalOperand{value: false}, nil
+		case "null", "nil":
+			return literalOperand{value: nil}, nil
+		}
+		if isFunc(tok.value) {
+			return nil, fmt.Errorf("function %s used as a value at position %d, wrap it in parentheses", tok.value, tok.pos)
+		}
+		return p.parsePath(tok)
+	case tokenEOF:
+		return nil, fmt.Errorf("unexpected end of placeholder")
+	}
+	return nil, fmt.Errorf("unexpected '%s' at position %d", tok.value, tok.pos)
+}
+
+// parsePath reads a reference such as outputs.fetch.items[0].id or inputs["env"]
+func (p *parser) parsePath(first token) (operand, error) {
+	ref := Reference{Root: first.value, Text: first.value}
+	for {
+		switch p.peek().kind {
+		case tokenDot:
+			p.next()
+			tok := p.next()
+			switch tok.kind {
+			case tokenIdent:
+				ref.Path = append(ref.Path, tok.value)
+			case tokenNumber:
+				index, err := strconv.Atoi(tok.value)
+				if err != nil {
+					return nil, fmt.Errorf("invalid index '%s' at position %d", tok.value, tok.pos)
+				}
+				ref.Path = append(ref.Path, index)
+			default:
+				return nil, fmt.Errorf("expected field name after '.' at position %d", tok.pos)
+			}
+			ref.Text += "." + tok.value
+		case tokenLBracket:
+			p.next()
+			tok := p.next()
+			switch tok.kind {
+			case tokenString:
+				ref.Path = append(ref.Path, tok.value)
+				ref.Text += "[" + strconv.Quote(tok.value) + "]"
+			case tokenNumber:
+				index, err := strconv.Atoi(tok.value)
+				if err != nil {
+					return nil, fmt.Errorf("invalid index '%s' at position %d", tok.value, tok.pos)
+				}
+				ref.Path = append(ref.Path, index)
+				ref.Text += "[" + tok.value + "]"
+			default:
+				return nil, fmt.Errorf("expected index or quoted key at position %d", tok.pos)
+			}
+			if closing := p.next(); closing.kind != tokenRBracket {
+				return nil, fmt.Errorf("expected ']' at position %d", closing.pos)
+			}
+		default:
+			return &pathOperand{ref: ref}, nil
+		}
+	}
+}
+
+// references appends the references of the pipeline to refs
+func (pl *pipeline) references(refs []Reference) []Reference {
+	for _, cmd := range pl.commands {
+		for _, arg := range cmd.args {
+			switch a := arg.(type) {
+			case *pathOperand:
+				refs = append(refs, a.ref)
+			case *pipeline:
+				refs = a.references(refs)
+			}
+		}
+	}
+	return refs
+}
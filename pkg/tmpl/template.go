@@ -0,0 +1,226 @@
+// Package tmpl implements the ${...} templates used in task parameters. A placeholder holds
+// a reference such as ${outputs.fetch.data[0].id} or ${inputs.env}, optionally piped
+// through functions: ${inputs.env | default "dev" | upper}. Functions can also be called
+// directly, ${default "dev" inputs.env}, the piped value is passed as the last argument.
+// $${ renders a literal ${.
+//
+// Placeholders whose references start at a name missing from the data are kept as they are,
+// so text meant for a shell or a later stage passes through untouched.
+package tmpl
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/b0nbon1/stratal/pkg/expr"
+)
+
+// LookupFunc resolves the fields of a value on demand, e.g. secrets that are only fetched
+// when referenced. A field that does not exist is reported with Missing.
+type LookupFunc func(key string) (interface{}, error)
+
+// MissingError reports a reference that cannot be resolved. default replaces missing values,
+// anywhere else they fail the render in strict mode.
+type MissingError struct {
+	Reason string
+}
+
+func (e *MissingError) Error() string {
+	return e.Reason
+}
+
+// Missing creates a MissingError
+func Missing(format string, args ...interface{}) error {
+	return &MissingError{Reason: fmt.Sprintf(format, args...)}
+}
+
+// Reference is a variable referenced by a placeholder, Path holds string keys and int indexes
+type Reference struct {
+	Root string
+	Path []interface{}
+	Text string
+}
+
+// Options control how a template is rendered
+type Options struct {
+	// Strict fails the render on references that cannot be resolved, otherwise their
+	// placeholder is kept as it is
+	Strict bool
+	// Partial renders a template in stages: escapes are kept and ${ in rendered values is
+	// escaped, so a later render with more data only expands the placeholders left over
+	Partial bool
+	// Unknown resolves the root of a reference missing from the data, it reports false to
+	// keep the placeholder
+	Unknown func(ref Reference) (interface{}, bool)
+}
+
+// Template is a parsed template
+type Template struct {
+	source string
+	parts  []part
+}
+
+// part is either literal text or a placeholder
+type part struct {
+	text        string // literal text, or the source of a placeholder
+	escape      bool   // a $${ escape
+	placeholder *pipeline
+	invalid     error  // why the placeholder could not be parsed
+	leading     string // identifier the placeholder starts with
+}
+
+// Parse splits a template into text and placeholders. Placeholders that cannot be parsed are
+// only reported when rendered, since ${...} may also be shell syntax.
+func Parse(source string) *Template {
+	t := &Template{source: source}
+	rest := source
+	for rest != "" {
+		start := strings.Index(rest, "${")
+		if start < 0 {
+			t.parts = append(t.parts, part{text: rest})
+			break
+		}
+		if start > 0 && rest[start-1] == '$' {
+			if start > 1 {
+				t.parts = append(t.parts, part{text: rest[:start-1]})
+			}
+			t.parts = append(t.parts, part{text: "$${", escape: true})
+			rest = rest[start+2:]
+			continue
+		}
+		if start > 0 {
+			t.parts = append(t.parts, part{text: rest[:start]})
+		}
+
+		end := placeholderEnd(rest[start+2:])
+		if end < 0 {
+			t.parts = append(t.parts, part{
+				text:    rest[start:],
+				invalid: fmt.Errorf("unterminated placeholder"),
+				leading: leadingIdent(rest[start+2:]),
+			})
+			break
+		}
+		body := rest[start+2 : start+2+end]
+		p := part{text: rest[start : start+3+end], leading: leadingIdent(body)}
+		p.placeholder, p.invalid = parsePlaceholder(body)
+		t.parts = append(t.parts, p)
+		rest = rest[start+3+end:]
+	}
+	return t
+}
+
+// placeholderEnd finds the closing brace of a placeholder body, skipping quoted strings
+func placeholderEnd(body string) int {
+	var quote byte
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '}':
+			return i
+		}
+	}
+	return -1
+}
+
+// String returns the source of the template
+func (t *Template) String() string {
+	return t.source
+}
+
+// References lists the variables referenced by the placeholders of the template
+func (t *Template) References() []Reference {
+	var refs []Reference
+	for _, p := range t.parts {
+		if p.placeholder != nil {
+			refs = p.placeholder.references(refs)
+		}
+	}
+	return refs
+}
+
+// Execute renders the template against data, a map of names to values such as
+// map[string]string, map[string]interface{} or LookupFunc
+func (t *Template) Execute(data map[string]interface{}, opts Options) (string, error) {
+	var sb strings.Builder
+	for _, p := range t.parts {
+		switch {
+		case p.escape:
+			if opts.Partial {
+				sb.WriteString("$${")
+			} else {
+				sb.WriteString("${")
+			}
+		case p.invalid != nil:
+			_, known := data[p.leading]
+			if opts.Strict && (known || isFunc(p.leading)) {
+				return "", fmt.Errorf("invalid placeholder %s: %w", p.text, p.invalid)
+			}
+			sb.WriteString(p.text)
+		case p.placeholder != nil:
+			rendered, err := renderPlaceholder(p, data, opts)
+			if err != nil {
+				return "", err
+			}
+			sb.WriteString(rendered)
+		default:
+			sb.WriteString(p.text)
+		}
+	}
+	return sb.String(), nil
+}
+
+// renderPlaceholder evaluates a placeholder, or keeps it when its references are unknown
+func renderPlaceholder(p part, data map[string]interface{}, opts Options) (string, error) {
+	roots := make(map[string]interface{})
+	for _, ref := range p.placeholder.references(nil) {
+		if _, done := roots[ref.Root]; done {
+			continue
+		}
+		if value, ok := data[ref.Root]; ok {
+			roots[ref.Root] = value
+			continue
+		}
+		if opts.Unknown != nil {
+			if value, ok := opts.Unknown(ref); ok {
+				roots[ref.Root] = value
+				continue
+			}
+		}
+		return p.text, nil
+	}
+
+	value, err := p.placeholder.eval(roots)
+	if err != nil {
+		var missing *MissingError
+		if errors.As(err, &missing) && !opts.Strict {
+			return p.text, nil
+		}
+		return "", fmt.Errorf("cannot resolve %s: %w", p.text, err)
+	}
+
+	rendered := expr.Format(value)
+	if opts.Partial {
+		rendered = Escape(rendered)
+	}
+	return rendered, nil
+}
+
+// Escape protects a value that is not a template, rendering it returns it unchanged
+func Escape(value string) string {
+	return strings.ReplaceAll(value, "${", "$${")
+}
+
+// Render parses and executes a template in one step
+func Render(source string, data map[string]interface{}, opts Options) (string, error) {
+	return Parse(source).Execute(data, opts)
+}
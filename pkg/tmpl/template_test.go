@@ -0,0 +1,164 @@
+package tmpl
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testData() map[string]interface{} {
+	return map[string]interface{}{
+		"outputs": map[string]string{
+			"fetch": `{"status": "ok", "items": [{"id": 7, "name": "a"}]}`,
+			"count": "42",
+			"raw":   "  padded  ",
+		},
+		"inputs": map[string]interface{}{
+			"env":      "production",
+			"replicas": 3.0,
+			"empty":    "",
+		},
+		"secrets": LookupFunc(func(key string) (interface{}, error) {
+			if key == "token" {
+				return "s3cret", nil
+			}
+			return nil, Missing("secret '%s' not found", key)
+		}),
+	}
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{`plain text`, `plain text`},
+		{`${inputs.env}`, `production`},
+		{`replicas=${inputs.replicas}`, `replicas=3`},
+		{`${outputs.fetch.items[0].id}`, `7`},
+		{`${outputs.fetch.items.0.name}`, `a`},
+		{`${outputs.fetch["status"]}`, `ok`},
+		{`${outputs.fetch.items[0]}`, `{"id":7,"name":"a"}`},
+		{`${secrets.token}`, `s3cret`},
+		{`${ inputs.env | upper }`, `PRODUCTION`},
+		{`${upper inputs.env}`, `PRODUCTION`},
+		{`${inputs.missing | default "dev"}`, `dev`},
+		{`${inputs.empty | default "dev"}`, `dev`},
+		{`${default "dev" inputs.env}`, `production`},
+		{`${outputs.raw | trim}`, `padded`},
+		{`${inputs.env | base64}`, `cHJvZHVjdGlvbg==`},
+		{`${inputs.env | toJSON}`, `"production"`},
+		{`${outputs.fetch | json}`, `{"items":[{"id":7,"name":"a"}],"status":"ok"}`},
+		{`${(inputs.missing | default "x") | upper}`, `X`},
+		{`${"}" | upper}`, `}`},
+		{`$${inputs.env}`, `${inputs.env}`},
+		{`$$${inputs.env}`, `$${inputs.env}`},
+		{`${HOME} ${VAR:-default}`, `${HOME} ${VAR:-default}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			got, err := Render(tt.template, testData(), Options{Strict: true})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRender_Now(t *testing.T) {
+	defer func(original func() time.Time) { now = original }(now)
+	now = func() time.Time { return time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC) }
+
+	got, err := Render(`${now} ${now "2006-01-02"}`, nil, Options{Strict: true})
+	require.NoError(t, err)
+	assert.Equal(t, "2024-03-01T12:30:00Z 2024-03-01", got)
+}
+
+func TestRender_StrictErrors(t *testing.T) {
+	tests := []struct {
+		template string
+		errMsg   string
+	}{
+		{`${inputs.missing}`, "field 'missing' not found at 'inputs'"},
+		{`${outputs.fetch.items[3].id}`, "index 3 out of range"},
+		{`${outputs.count.value}`, "cannot read field 'value' of a string"},
+		{`${secrets.other}`, "secret 'other' not found"},
+		{`${inputs.missing | upper}`, "field 'missing' not found"},
+		{`${secrets}`, "'secrets' is a namespace"},
+		{`${inputs.env | nope}`, "invalid placeholder"},
+		{`${upper}`, "upper takes 1 argument(s), got 0"},
+		{`${outputs.count | json | default}`, "default takes 2 argument(s), got 1"},
+		{`${inputs.env | json}`, "json:"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			_, err := Render(tt.template, testData(), Options{Strict: true})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errMsg)
+		})
+	}
+}
+
+func TestRender_Lenient(t *testing.T) {
+	got, err := Render(`${inputs.missing} ${inputs.env}`, testData(), Options{})
+	require.NoError(t, err)
+	assert.Equal(t, "${inputs.missing} production", got)
+}
+
+func TestRender_Partial(t *testing.T) {
+	data := map[string]interface{}{
+		"inputs": map[string]interface{}{"query": "${outputs.fetch}"},
+	}
+	first, err := Render(`${inputs.query} ${outputs.count} $${literal}`, data, Options{Strict: true, Partial: true})
+	require.NoError(t, err)
+	assert.Equal(t, "$${outputs.fetch} ${outputs.count} $${literal}", first)
+
+	second, err := Render(first, testData(), Options{Strict: true})
+	require.NoError(t, err)
+	assert.Equal(t, "${outputs.fetch} 42 ${literal}", second)
+}
+
+func TestEscape(t *testing.T) {
+	value := "echo ${HOME} $${x} ${inputs.env}"
+	got, err := Render(Escape(value), testData(), Options{Strict: true})
+	require.NoError(t, err)
+	assert.Equal(t, value, got)
+}
+
+func TestRender_Unknown(t *testing.T) {
+	opts := Options{
+		Strict: true,
+		Unknown: func(ref Reference) (interface{}, bool) {
+			if len(ref.Path) > 0 && ref.Path[0] == "output" {
+				return map[string]interface{}{"output": "from " + ref.Root}, true
+			}
+			return nil, false
+		},
+	}
+	got, err := Render(`${fetch.output} ${timestamp}`, nil, opts)
+	require.NoError(t, err)
+	assert.Equal(t, "from fetch ${timestamp}", got)
+}
+
+func TestRender_Unterminated(t *testing.T) {
+	got, err := Render(`echo ${HOME`, testData(), Options{Strict: true})
+	require.NoError(t, err)
+	assert.Equal(t, "echo ${HOME", got)
+
+	_, err = Render(`${inputs.env`, testData(), Options{Strict: true})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unterminated placeholder")
+}
+
+func TestTemplate_References(t *testing.T) {
+	tpl := Parse(`${outputs.fetch.items[0].id | default inputs.env} ${(secrets.token | upper)} ${VAR:-x}`)
+
+	var refs []string
+	for _, ref := range tpl.References() {
+		refs = append(refs, ref.Text)
+	}
+	assert.Equal(t, []string{"outputs.fetch.items[0].id", "inputs.env", "secrets.token"}, refs)
+}
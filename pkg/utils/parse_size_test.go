@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"0", 0},
+		{"512", 512},
+		{"512B", 512},
+		{"64KB", 64 << 10},
+		{"64k", 64 << 10},
+		{"1.5MB", 3 << 19},
+		{"10 MiB", 10 << 20},
+		{"2GiB", 2 << 30},
+		{"1g", 1 << 30},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			size, err := ParseSize(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, size)
+		})
+	}
+}
+
+func TestParseSize_Invalid(t *testing.T) {
+	for _, input := range []string{"", "MB", "-1KB", "ten", "1XB", "1e30TB"} {
+		t.Run(input, func(t *testing.T) {
+			_, err := ParseSize(input)
+			assert.Error(t, err)
+		})
+	}
+}
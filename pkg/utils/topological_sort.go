@@ -2,6 +2,7 @@ package utils
 
 import (
 	"fmt"
+	"sort"
 
 	db "github.com/b0nbon1/stratal/internal/storage/db/sqlc"
 )
@@ -68,3 +69,42 @@ func TopoSort(tasks []db.Task) ([]db.Task, error) {
 
 	return sorted, nil
 }
+
+// TopoLevels groups the tasks into execution levels. Every task in a level only
+// depends on tasks from earlier levels, so tasks within a level can run in parallel.
+func TopoLevels(tasks []db.Task) ([][]db.Task, error) {
+	sorted, err := TopoSort(tasks)
+	if err != nil {
+		return nil, err
+	}
+
+	levelOf := make(map[string]int)
+	var levels [][]db.Task
+
+	for _, task := range sorted {
+		level := 0
+		for _, depName := range task.Config.DependsOn {
+			if depLevel := levelOf[depName] + 1; depLevel > level {
+				level = depLevel
+			}
+		}
+		levelOf[task.Name] = level
+
+		for len(levels) <= level {
+			levels = append(levels, nil)
+		}
+		levels[level] = append(levels[level], task)
+	}
+
+	// keep the execution order within a level stable
+	for _, level := range levels {
+		sort.SliceStable(level, func(i, j int) bool {
+			if level[i].Order != level[j].Order {
+				return level[i].Order < level[j].Order
+			}
+			return level[i].Name < level[j].Name
+		})
+	}
+
+	return levels, nil
+}
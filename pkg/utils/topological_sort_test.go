@@ -273,3 +273,68 @@ func TestTopoSort_LongChain(t *testing.T) {
 	assert.Equal(t, "task4", sorted[3].Name)
 	assert.Equal(t, "task5", sorted[4].Name)
 }
+
+func TestTopoLevels_GroupsIndependentTasks(t *testing.T) {
+	// task1, task2 and task3 are independent, task4 waits for all of them
+	// and task5 only waits for task4
+	tasks := []db.Task{
+		createTask("00000000-0000-0000-0000-000000000005", "task5", []string{"task4"}),
+		createTask("00000000-0000-0000-0000-000000000004", "task4", []string{"task1", "task2", "task3"}),
+		createTask("00000000-0000-0000-0000-000000000003", "task3", nil),
+		createTask("00000000-0000-0000-0000-000000000001", "task1", nil),
+		createTask("00000000-0000-0000-0000-000000000002", "task2", nil),
+	}
+
+	levels, err := TopoLevels(tasks)
+
+	require.NoError(t, err)
+	require.Len(t, levels, 3)
+
+	names := func(level []db.Task) []string {
+		var result []string
+		for _, task := range level {
+			result = append(result, task.Name)
+		}
+		return result
+	}
+
+	assert.Equal(t, []string{"task1", "task2", "task3"}, names(levels[0]))
+	assert.Equal(t, []string{"task4"}, names(levels[1]))
+	assert.Equal(t, []string{"task5"}, names(levels[2]))
+}
+
+func TestTopoLevels_UsesLongestDependencyPath(t *testing.T) {
+	// task3 depends on task1 directly and through task2, so it must wait for task2
+	tasks := []db.Task{
+		createTask("00000000-0000-0000-0000-000000000001", "task1", nil),
+		createTask("00000000-0000-0000-0000-000000000002", "task2", []string{"task1"}),
+		createTask("00000000-0000-0000-0000-000000000003", "task3", []string{"task1", "task2"}),
+	}
+
+	levels, err := TopoLevels(tasks)
+
+	require.NoError(t, err)
+	require.Len(t, levels, 3)
+	assert.Equal(t, "task1", levels[0][0].Name)
+	assert.Equal(t, "task2", levels[1][0].Name)
+	assert.Equal(t, "task3", levels[2][0].Name)
+}
+
+func TestTopoLevels_CyclicDependency(t *testing.T) {
+	tasks := []db.Task{
+		createTask("00000000-0000-0000-0000-000000000001", "task1", []string{"task2"}),
+		createTask("00000000-0000-0000-0000-000000000002", "task2", []string{"task1"}),
+	}
+
+	levels, err := TopoLevels(tasks)
+
+	require.Error(t, err)
+	assert.Nil(t, levels)
+}
+
+func TestTopoLevels_EmptyTaskList(t *testing.T) {
+	levels, err := TopoLevels([]db.Task{})
+
+	require.NoError(t, err)
+	assert.Empty(t, levels)
+}